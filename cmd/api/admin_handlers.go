@@ -19,6 +19,7 @@ import (
 	"golang.org/x/image/draw"
 
 	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
 )
 
 // handleAdminDashboard affiche le dashboard admin
@@ -34,11 +35,7 @@ func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 
 	settings, err := s.store.GetSettings(ctx)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Failed to get settings: " + err.Error(),
-		})
+		s.respondError(w, http.StatusInternalServerError, "Failed to get settings", err)
 		return
 	}
 
@@ -53,22 +50,14 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 	// Récupérer les settings existants pour obtenir l'ID
 	existingSettings, err := s.store.GetSettings(ctx)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Failed to get existing settings: " + err.Error(),
-		})
+		s.respondError(w, http.StatusInternalServerError, "Failed to get existing settings", err)
 		return
 	}
 
 	// Décoder les nouveaux paramètres partiels
 	var partialSettings map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&partialSettings); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Invalid request body: " + err.Error(),
-		})
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
@@ -139,13 +128,16 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 	if val, ok := partialSettings["session_timeout"].(float64); ok {
 		existingSettings.SessionTimeout = int(val)
 	}
+	if val, ok := partialSettings["trash_retention_days"].(float64); ok {
+		if val < 1 {
+			s.respondError(w, http.StatusBadRequest, "trash_retention_days must be at least 1", nil)
+			return
+		}
+		existingSettings.TrashRetentionDays = int(val)
+	}
 
 	if err := s.store.UpdateSettings(ctx, existingSettings); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Failed to update settings: " + err.Error(),
-		})
+		s.respondError(w, http.StatusInternalServerError, "Failed to update settings", err)
 		return
 	}
 
@@ -159,6 +151,71 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
+// ValidateSettings valide les paramètres avant enregistrement
+func ValidateSettings(settings *domain.Settings) error {
+	verr := &store.ValidationError{}
+	if strings.TrimSpace(settings.AppTitle) == "" {
+		verr.Add("app_title", "app title is required")
+	} else if len(settings.AppTitle) > 255 {
+		verr.Add("app_title", "app title too long (max 255 characters)")
+	}
+	if settings.PublicProtocol != "" && settings.PublicProtocol != "http" && settings.PublicProtocol != "https" {
+		verr.Add("public_protocol", "public protocol must be http or https")
+	}
+	if settings.RowsPerPage < 1 || settings.RowsPerPage > maxPageSize {
+		verr.Add("rows_per_page", fmt.Sprintf("rows per page must be between 1 and %d", maxPageSize))
+	}
+	if settings.MaxRequestBodySize < 0 {
+		verr.Add("max_request_body_size", "max request body size cannot be negative")
+	}
+	if settings.SessionTimeout < 0 {
+		verr.Add("session_timeout", "session timeout cannot be negative")
+	}
+	if settings.TrashRetentionDays < 1 {
+		verr.Add("trash_retention_days", "trash_retention_days must be at least 1")
+	}
+	return verr.Err()
+}
+
+// handlePatchSettings applique un JSON Merge Patch (RFC 7396) aux paramètres
+func (s *Server) handlePatchSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	patch, err := decodeMergePatch(r)
+	if err != nil {
+		s.respondPatchError(w, "Invalid merge patch", err)
+		return
+	}
+
+	settings, err := s.store.GetSettings(ctx)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to get existing settings", err)
+		return
+	}
+
+	changes, err := applyMergePatch(settings, patch, "id", "smtp_configured", "created_at", "updated_at")
+	if err == nil {
+		err = patchedFieldsOnly(ValidateSettings(settings), patch)
+	}
+	if err != nil {
+		s.respondStoreError(w, "Invalid settings", err)
+		return
+	}
+
+	if len(changes) > 0 {
+		if err := s.store.UpdateSettings(ctx, settings); err != nil {
+			s.respondError(w, http.StatusInternalServerError, "Failed to update settings", err)
+			return
+		}
+
+		// Audit
+		s.store.LogActivity(ctx, "settings", settings.ID, "settings_patched", map[string]interface{}{"fields": changedFields(changes), "changes": changes, "by": ctx.Value(SessionUserKey)}, s.getClientIP(r))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
 // handleUploadLogo gère l'upload du logo
 func (s *Server) handleUploadLogo(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[UPLOAD] Starting logo upload handler")
@@ -169,33 +226,21 @@ func (s *Server) handleUploadLogo(w http.ResponseWriter, r *http.Request) {
 	// Parser le formulaire multipart avec limite de 10MB
 	if err := r.ParseMultipartForm(multipartParseLimit); err != nil {
 		log.Printf("[UPLOAD] Failed to parse form: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Failed to parse form: " + err.Error(),
-		})
+		s.respondError(w, http.StatusBadRequest, "Failed to parse form", err)
 		return
 	}
 
 	file, handler, err := r.FormFile("logo")
 	if err != nil {
 		log.Printf("[UPLOAD] Failed to get file: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Failed to get file: " + err.Error(),
-		})
+		s.respondError(w, http.StatusBadRequest, "Failed to get file", err)
 		return
 	}
 	defer file.Close()
 
 	if handler.Size > maxUploadSize {
 		log.Printf("[UPLOAD] File too large: %d bytes", handler.Size)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "File too large. Maximum 5MB. Recommended: 512x512 under 500KB.",
-		})
+		s.respondError(w, http.StatusBadRequest, "File too large. Maximum 5MB. Recommended: 512x512 under 500KB.", nil)
 		return
 	}
 
@@ -204,20 +249,12 @@ func (s *Server) handleUploadLogo(w http.ResponseWriter, r *http.Request) {
 	bytesRead, err := io.Copy(dataBuf, io.LimitReader(file, maxUploadSize+1))
 	if err != nil {
 		log.Printf("[UPLOAD] Failed to read file: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Failed to read file: " + err.Error(),
-		})
+		s.respondError(w, http.StatusInternalServerError, "Failed to read file", err)
 		return
 	}
 	if bytesRead > maxUploadSize {
 		log.Printf("[UPLOAD] File exceeded limit after read: %d bytes", bytesRead)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "File too large. Maximum 5MB. Recommended: 512x512 under 500KB.",
-		})
+		s.respondError(w, http.StatusBadRequest, "File too large. Maximum 5MB. Recommended: 512x512 under 500KB.", nil)
 		return
 	}
 
@@ -236,11 +273,7 @@ func (s *Server) handleUploadLogo(w http.ResponseWriter, r *http.Request) {
 	ext := strings.ToLower(filepath.Ext(handler.Filename))
 	if !validExtensions[ext] {
 		log.Printf("[UPLOAD] Invalid extension: %s", ext)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Invalid file type. Allowed: png, jpg, jpeg, gif, svg, webp",
-		})
+		s.respondError(w, http.StatusBadRequest, "Invalid file type. Allowed: png, jpg, jpeg, gif, svg, webp", nil)
 		return
 	}
 
@@ -248,11 +281,7 @@ func (s *Server) handleUploadLogo(w http.ResponseWriter, r *http.Request) {
 	uploadsDir := "assets/uploads"
 	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
 		log.Printf("[UPLOAD] Failed to create directory: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Failed to create uploads directory: " + err.Error(),
-		})
+		s.respondError(w, http.StatusInternalServerError, "Failed to create uploads directory", err)
 		return
 	}
 
@@ -266,11 +295,7 @@ func (s *Server) handleUploadLogo(w http.ResponseWriter, r *http.Request) {
 	dst, err := os.Create(filePath)
 	if err != nil {
 		log.Printf("[UPLOAD] Failed to create destination file: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Failed to create file: " + err.Error(),
-		})
+		s.respondError(w, http.StatusInternalServerError, "Failed to create file", err)
 		return
 	}
 	defer dst.Close()
@@ -325,11 +350,7 @@ func (s *Server) handleUploadLogo(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Printf("[UPLOAD] Failed to write file (wrote %d bytes): %v", bytesWritten, err)
 		os.Remove(filePath)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Failed to save file: " + err.Error(),
-		})
+		s.respondError(w, http.StatusInternalServerError, "Failed to save file", err)
 		return
 	}
 
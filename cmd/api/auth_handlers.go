@@ -183,7 +183,7 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	// Créer l'utilisateur (role = "user" par défaut)
 	userID, err := s.store.CreateUser(ctx, req.Username, req.Email, req.Password, "user")
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "Failed to create user", err)
+		s.respondStoreError(w, "Failed to create user", err)
 		return
 	}
 
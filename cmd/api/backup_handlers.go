@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// backupsAvailable répond 503 si le sous-système de sauvegarde n'est pas configuré
+func (s *Server) backupsAvailable(w http.ResponseWriter) bool {
+	if s.backups == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Backups are not configured", nil)
+		return false
+	}
+	return true
+}
+
+// handleListBackups liste les sauvegardes disponibles, de la plus récente à la plus ancienne
+func (s *Server) handleListBackups(w http.ResponseWriter, r *http.Request) {
+	if !s.backupsAvailable(w) {
+		return
+	}
+
+	backups, err := s.backups.List()
+	if err != nil {
+		s.respondStoreError(w, "Failed to list backups", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(backups)
+}
+
+// handleCreateBackup déclenche une sauvegarde immédiate (puis la rotation)
+func (s *Server) handleCreateBackup(w http.ResponseWriter, r *http.Request) {
+	if !s.backupsAvailable(w) {
+		return
+	}
+
+	backup, err := s.backups.Create(r.Context())
+	if backup == nil {
+		s.respondStoreError(w, "Failed to create backup", err)
+		return
+	}
+	if err != nil {
+		// La sauvegarde existe : seule la rotation a échoué
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Backup %s created but rotation failed", backup.Name), err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "backup", 0, "backup_created", map[string]interface{}{"name": backup.Name, "size": backup.Size}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(backup)
+}
+
+// handleDownloadBackup télécharge un fichier de sauvegarde
+func (s *Server) handleDownloadBackup(w http.ResponseWriter, r *http.Request) {
+	if !s.backupsAvailable(w) {
+		return
+	}
+
+	name := r.PathValue("name")
+	path, err := s.backups.Path(name)
+	if err != nil {
+		s.respondStoreError(w, "Failed to find backup", err)
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to open backup", err)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to read backup", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "backup", 0, "backup_downloaded", map[string]string{"name": name}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name))
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
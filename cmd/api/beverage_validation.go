@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// Bornes des attributs des bières
+const (
+	maxBeerStyleLength = 100
+	maxIBU             = 200
+	maxEBC             = 300
+)
+
+// Bornes des attributs des spiritueux
+const (
+	maxAgeStatement        = 100
+	maxSpiritTextLength    = 255
+	maxCaskNumberLength    = 50
+	maxSpiritAlcoholLevel  = 100
+	maxDefaultAlcoholLevel = 20
+	minSpiritDistilledYear = 1800
+)
+
+// maxAlcoholLevel donne le degré maximal accepté : un spiritueux brut de fût dépasse largement 20 %
+func maxAlcoholLevel(bottleType string) float32 {
+	if bottleType == domain.BottleTypeSpirit {
+		return maxSpiritAlcoholLevel
+	}
+	return maxDefaultAlcoholLevel
+}
+
+// validateVintage vérifie le millésime : facultatif (0) pour une bière, qui n'en a pas,
+// et pour un spiritueux sans compte d'âge (NAS)
+func validateVintage(verr *store.ValidationError, wine *domain.Wine) {
+	if wine.Vintage == 0 && (wine.BottleType == domain.BottleTypeBeer || wine.BottleType == domain.BottleTypeSpirit) {
+		return
+	}
+	if wine.Vintage < 1900 || wine.Vintage > time.Now().Year() {
+		verr.Add("vintage", fmt.Sprintf("invalid vintage: must be between 1900 and %d", time.Now().Year()))
+	}
+}
+
+// validateBeer valide les attributs propres aux bières, refusés pour les autres types.
+// Le style sert aussi de catégorie aux spiritueux.
+func validateBeer(verr *store.ValidationError, wine *domain.Wine) {
+	if wine.BottleType != domain.BottleTypeBeer {
+		if wine.BottleType != domain.BottleTypeSpirit && wine.Style != "" {
+			verr.Add("style", "style only applies to beer and spirits")
+		}
+		for _, attr := range []struct {
+			field string
+			set   bool
+		}{
+			{"ibu", wine.IBU != nil},
+			{"ebc", wine.EBC != nil},
+			{"packaged_at", wine.PackagedAt != nil},
+			{"best_before", wine.BestBefore != nil},
+		} {
+			if attr.set {
+				verr.Add(attr.field, attr.field+" only applies to beer")
+			}
+		}
+		return
+	}
+
+	if len(wine.Style) > maxBeerStyleLength {
+		verr.Add("style", fmt.Sprintf("style too long (max %d characters)", maxBeerStyleLength))
+	}
+	if wine.IBU != nil && (*wine.IBU < 0 || *wine.IBU > maxIBU) {
+		verr.Add("ibu", fmt.Sprintf("ibu must be between 0 and %d", maxIBU))
+	}
+	if wine.EBC != nil && (*wine.EBC < 0 || *wine.EBC > maxEBC) {
+		verr.Add("ebc", fmt.Sprintf("ebc must be between 0 and %d", maxEBC))
+	}
+	if wine.PackagedAt != nil && wine.PackagedAt.After(time.Now()) {
+		verr.Add("packaged_at", "packaged_at cannot be in the future")
+	}
+	if wine.PackagedAt != nil && wine.BestBefore != nil && wine.BestBefore.Before(*wine.PackagedAt) {
+		verr.Add("best_before", "best_before must be after packaged_at")
+	}
+}
+
+// validateSpirit valide les attributs propres aux spiritueux, refusés pour les autres types
+func validateSpirit(verr *store.ValidationError, wine *domain.Wine) {
+	if wine.BottleType != domain.BottleTypeSpirit {
+		for _, attr := range []struct {
+			field string
+			set   bool
+		}{
+			{"age_statement", wine.AgeStatement != nil},
+			{"distillery", wine.Distillery != ""},
+			{"cask_type", wine.CaskType != ""},
+			{"cask_number", wine.CaskNumber != ""},
+			{"distilled_year", wine.DistilledYear != nil},
+			{"bottled_year", wine.BottledYear != nil},
+			{"cask_strength", wine.CaskStrength},
+			{"independent_bottler", wine.IndependentBottler != ""},
+		} {
+			if attr.set {
+				verr.Add(attr.field, attr.field+" only applies to spirits")
+			}
+		}
+		return
+	}
+
+	if len(wine.Style) > maxSpiritTextLength {
+		verr.Add("style", fmt.Sprintf("style too long (max %d characters)", maxSpiritTextLength))
+	}
+	for _, attr := range []struct {
+		field, value string
+		max          int
+	}{
+		{"distillery", wine.Distillery, maxSpiritTextLength},
+		{"cask_type", wine.CaskType, maxSpiritTextLength},
+		{"cask_number", wine.CaskNumber, maxCaskNumberLength},
+		{"independent_bottler", wine.IndependentBottler, maxSpiritTextLength},
+	} {
+		if len(attr.value) > attr.max {
+			verr.Add(attr.field, fmt.Sprintf("%s too long (max %d characters)", attr.field, attr.max))
+		}
+	}
+
+	if wine.AgeStatement != nil && (*wine.AgeStatement < 0 || *wine.AgeStatement > maxAgeStatement) {
+		verr.Add("age_statement", fmt.Sprintf("age_statement must be between 0 and %d", maxAgeStatement))
+	}
+	year := time.Now().Year()
+	if wine.DistilledYear != nil && (*wine.DistilledYear < minSpiritDistilledYear || *wine.DistilledYear > year) {
+		verr.Add("distilled_year", fmt.Sprintf("distilled_year must be between %d and %d", minSpiritDistilledYear, year))
+	}
+	if wine.BottledYear != nil && (*wine.BottledYear < minSpiritDistilledYear || *wine.BottledYear > year) {
+		verr.Add("bottled_year", fmt.Sprintf("bottled_year must be between %d and %d", minSpiritDistilledYear, year))
+	}
+	if wine.DistilledYear != nil && wine.BottledYear != nil {
+		// Le compte d'âge est celui de l'eau-de-vie la plus jeune : il ne peut dépasser
+		// le temps passé en fût
+		switch aged := *wine.BottledYear - *wine.DistilledYear; {
+		case aged < 0:
+			verr.Add("bottled_year", "bottled_year cannot be before distilled_year")
+		case wine.AgeStatement != nil && *wine.AgeStatement > aged:
+			verr.Add("age_statement", fmt.Sprintf("age_statement exceeds the %d years between distillation and bottling", aged))
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// handleGetAllBottles retourne toutes les bouteilles, tous types confondus
+// (filtres, tri et pagination par curseur)
+func (s *Server) handleGetAllBottles(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid list parameters", err)
+		return
+	}
+
+	bottles, page, err := s.store.ListWines(r.Context(), opts)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch bottles", err)
+		return
+	}
+
+	writePageHeaders(w, r, page, opts.Limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bottles)
+}
+
+// handleGetCaveBottles retourne les bouteilles d'une cave
+func (s *Server) handleGetCaveBottles(w http.ResponseWriter, r *http.Request) {
+	caveID, err := strconv.ParseInt(r.PathValue("caveID"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cave ID", err)
+		return
+	}
+
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid list parameters", err)
+		return
+	}
+	opts.Filters.CaveID = &caveID
+
+	bottles, page, err := s.store.ListWines(r.Context(), opts)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch bottles", err)
+		return
+	}
+
+	writePageHeaders(w, r, page, opts.Limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bottles)
+}
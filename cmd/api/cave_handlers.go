@@ -0,0 +1,421 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// ValidateCave valide une cave avant enregistrement
+func ValidateCave(c *domain.Cave) error {
+	verr := &store.ValidationError{}
+	if c.Name == "" {
+		verr.Add("name", "cave name is required")
+	} else if len(c.Name) > 255 {
+		verr.Add("name", "cave name too long (max 255 characters)")
+	}
+	if c.Capacity < 0 {
+		verr.Add("capacity", "capacity cannot be negative")
+	}
+	if c.Layout != nil {
+		validateLayout(verr, c.Layout)
+	}
+	return verr.Err()
+}
+
+// Bornes d'un agencement de cave
+const (
+	maxLayoutDimension = 100
+	maxLayoutSlots     = 2000
+)
+
+// validateLayout valide l'agencement d'une cave (zones, clayettes, rangées, colonnes, profondeur)
+func validateLayout(verr *store.ValidationError, layout *domain.CaveLayout) {
+	if len(layout.Zones) == 0 {
+		verr.Add("layout.zones", "layout must have at least one zone")
+		return
+	}
+	names := make(map[string]bool)
+	for i, zone := range layout.Zones {
+		field := fmt.Sprintf("layout.zones[%d]", i)
+		switch {
+		case strings.TrimSpace(zone.Name) == "":
+			verr.Add(field+".name", "zone name is required")
+		case len(zone.Name) > 64:
+			verr.Add(field+".name", "zone name too long (max 64 characters)")
+		case names[zone.Name]:
+			verr.Add(field+".name", fmt.Sprintf("duplicate zone name %q", zone.Name))
+		}
+		names[zone.Name] = true
+
+		for _, dim := range []struct {
+			name  string
+			value int
+		}{{"racks", zone.Racks}, {"rows", zone.Rows}, {"columns", zone.Columns}, {"depth", zone.Depth}} {
+			if dim.value < 1 || dim.value > maxLayoutDimension {
+				verr.Add(field+"."+dim.name, fmt.Sprintf("%s must be between 1 and %d", dim.name, maxLayoutDimension))
+			}
+		}
+		for _, t := range []struct {
+			name  string
+			value *float64
+		}{{"min_temperature", zone.MinTemperature}, {"max_temperature", zone.MaxTemperature}} {
+			if t.value != nil && (*t.value < -10 || *t.value > 30) {
+				verr.Add(field+"."+t.name, "temperature must be between -10 and 30 °C")
+			}
+		}
+		if zone.MinTemperature != nil && zone.MaxTemperature != nil && *zone.MinTemperature > *zone.MaxTemperature {
+			verr.Add(field+".min_temperature", "min_temperature cannot exceed max_temperature")
+		}
+	}
+	if slots := layout.Slots(); slots > maxLayoutSlots {
+		verr.Add("layout", fmt.Sprintf("layout has %d slots (max %d)", slots, maxLayoutSlots))
+	}
+}
+
+// ValidateCell valide un emplacement avant enregistrement
+func ValidateCell(c *domain.Cell) error {
+	verr := &store.ValidationError{}
+	if c.CaveID <= 0 {
+		verr.Add("cave_id", "cave_id is required")
+	}
+	if c.Location == "" {
+		verr.Add("location", "cell location is required")
+	} else if len(c.Location) > 255 {
+		verr.Add("location", "cell location too long (max 255 characters)")
+	}
+	if c.Capacity < 0 {
+		verr.Add("capacity", "capacity cannot be negative")
+	}
+	return verr.Err()
+}
+
+// overflowAllowed indique si la requête autorise à dépasser la capacité
+// d'un emplacement ou d'une cave (?overflow=true)
+func overflowAllowed(r *http.Request) bool {
+	overflow, _ := strconv.ParseBool(r.URL.Query().Get("overflow"))
+	return overflow
+}
+
+// handleGetCaves retourne la liste des caves
+func (s *Server) handleGetCaves(w http.ResponseWriter, r *http.Request) {
+	caves, err := s.store.GetCaves(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch caves", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(caves)
+}
+
+// handleGetCave retourne une cave par son ID, avec son ETag
+func (s *Server) handleGetCave(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cave ID", err)
+		return
+	}
+
+	cave, err := s.store.GetCaveByID(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch cave", err)
+		return
+	}
+
+	setETag(w, cave.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cave)
+}
+
+// handleCreateCave crée une cave
+func (s *Server) handleCreateCave(w http.ResponseWriter, r *http.Request) {
+	var cave domain.Cave
+	if err := json.NewDecoder(r.Body).Decode(&cave); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Modèle du catalogue : agencement (et donc capacité et emplacements) prérempli
+	if err := s.applyCaveModel(r, &cave); err != nil {
+		s.respondStoreError(w, "Failed to load cave model", err)
+		return
+	}
+
+	if err := ValidateCave(&cave); err != nil {
+		s.respondStoreError(w, "Invalid cave", err)
+		return
+	}
+
+	id, err := s.store.CreateCave(r.Context(), &cave)
+	if err != nil {
+		s.respondStoreError(w, "Failed to create cave", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "cave", id, "cave_created", map[string]interface{}{"name": cave.Name}, s.getClientIP(r))
+
+	// Relire la cave : capacité déduite de l'agencement, version, date de création
+	created, err := s.store.GetCaveByID(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch cave", err)
+		return
+	}
+
+	setETag(w, created.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleUpdateCave met à jour une cave (If-Match optionnel : 412 si la version a changé)
+func (s *Server) handleUpdateCave(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cave ID", err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
+	var cave domain.Cave
+	if err := json.NewDecoder(r.Body).Decode(&cave); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	cave.ID = id
+	cave.Version = version
+
+	if err := ValidateCave(&cave); err != nil {
+		s.respondStoreError(w, "Invalid cave", err)
+		return
+	}
+
+	if err := s.store.UpdateCave(r.Context(), &cave); err != nil {
+		s.respondWriteError(w, "Failed to update cave", err, s.currentCave(r, id))
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "cave", cave.ID, "cave_updated", map[string]interface{}{"name": cave.Name}, s.getClientIP(r))
+
+	updated, err := s.store.GetCaveByID(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch cave", err)
+		return
+	}
+
+	setETag(w, updated.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// handlePatchCave applique un JSON Merge Patch (RFC 7396) à une cave
+func (s *Server) handlePatchCave(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cave ID", err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
+	patch, err := decodeMergePatch(r)
+	if err != nil {
+		s.respondPatchError(w, "Invalid merge patch", err)
+		return
+	}
+
+	var cave *domain.Cave
+	var changes map[string]fieldChange
+	for attempt := 1; ; attempt++ {
+		cave, err = s.store.GetCaveByID(r.Context(), id)
+		if err != nil {
+			s.respondStoreError(w, "Failed to fetch cave", err)
+			return
+		}
+		expected := cave.Version
+		if version != 0 {
+			expected = version
+		}
+
+		// current est tenu à jour par le stock, pas par le client
+		changes, err = applyMergePatch(cave, patch, "id", "created_at", "version", "current")
+		if err == nil {
+			err = patchedFieldsOnly(ValidateCave(cave), patch)
+		}
+		if err != nil {
+			s.respondStoreError(w, "Invalid cave", err)
+			return
+		}
+		if len(changes) == 0 {
+			// Rien à écrire : seule la précondition est vérifiée
+			if version != 0 && cave.Version != version {
+				s.respondPreconditionFailed(w, store.StaleVersion("cave", id, cave.Version), cave, cave.Version)
+				return
+			}
+			break
+		}
+
+		cave.ID = id
+		cave.Version = expected
+		err = s.store.UpdateCave(r.Context(), cave)
+		if errors.Is(err, store.ErrPreconditionFailed) && version == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		if err != nil {
+			s.respondWriteError(w, "Failed to update cave", err, s.currentCave(r, id))
+			return
+		}
+		break
+	}
+
+	// Audit
+	if len(changes) > 0 {
+		s.store.LogActivity(r.Context(), "cave", id, "cave_patched", map[string]interface{}{"fields": changedFields(changes), "changes": changes}, s.getClientIP(r))
+	}
+
+	setETag(w, cave.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cave)
+}
+
+// handleDeleteCave met une cave vide à la corbeille, avec ses emplacements.
+// If-Match optionnel : 412 si la version a changé.
+func (s *Server) handleDeleteCave(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cave ID", err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
+	if err := s.store.SoftDelete(r.Context(), store.TrashCave, id, version); err != nil {
+		s.respondWriteError(w, "Failed to delete cave", err, s.currentCave(r, id))
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "cave", id, "cave_trashed", map[string]string{"id": idStr}, s.getClientIP(r))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetCaveLayout retourne la grille d'une cave (zones, clayettes, rangées, emplacements)
+// avec l'occupation et le contenu de chaque emplacement
+func (s *Server) handleGetCaveLayout(w http.ResponseWriter, r *http.Request) {
+	caveID, err := strconv.ParseInt(r.PathValue("caveID"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cave ID", err)
+		return
+	}
+
+	grid, err := s.store.GetCaveLayout(r.Context(), caveID)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch cave layout", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grid)
+}
+
+// handleGetCells retourne les emplacements d'une cave
+func (s *Server) handleGetCells(w http.ResponseWriter, r *http.Request) {
+	caveID, err := strconv.ParseInt(r.PathValue("caveID"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cave ID", err)
+		return
+	}
+
+	if _, err := s.store.GetCaveByID(r.Context(), caveID); err != nil {
+		s.respondStoreError(w, "Failed to fetch cave", err)
+		return
+	}
+
+	cells, err := s.store.GetCellsByCave(r.Context(), caveID)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch cells", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cells)
+}
+
+// handleCreateCell crée un emplacement dans une cave existante
+func (s *Server) handleCreateCell(w http.ResponseWriter, r *http.Request) {
+	var cell domain.Cell
+	if err := json.NewDecoder(r.Body).Decode(&cell); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := ValidateCell(&cell); err != nil {
+		s.respondStoreError(w, "Invalid cell", err)
+		return
+	}
+
+	if _, err := s.store.GetCaveByID(r.Context(), cell.CaveID); err != nil {
+		s.respondStoreError(w, "Failed to fetch cave", err)
+		return
+	}
+
+	id, err := s.store.CreateCell(r.Context(), &cell)
+	if err != nil {
+		s.respondStoreError(w, "Failed to create cell", err)
+		return
+	}
+
+	cell.ID = id
+	cell.CreatedAt = time.Now()
+
+	// Audit
+	s.store.LogActivity(r.Context(), "cell", cell.ID, "cell_created", map[string]interface{}{"cave_id": cell.CaveID, "location": cell.Location}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cell)
+}
+
+// handleDeleteCell met un emplacement vide à la corbeille
+func (s *Server) handleDeleteCell(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cell ID", err)
+		return
+	}
+
+	if err := s.store.SoftDelete(r.Context(), store.TrashCell, id, 0); err != nil {
+		s.respondStoreError(w, "Failed to delete cell", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "cell", id, "cell_trashed", map[string]string{"id": idStr}, s.getClientIP(r))
+
+	w.WriteHeader(http.StatusNoContent)
+}
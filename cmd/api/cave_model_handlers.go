@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// caveModelIDPattern : identifiant en minuscules, chiffres et tirets (ex. "eurocave-pure-l")
+var caveModelIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,63}$`)
+
+// ValidateCaveModel valide un modèle de cave personnalisé
+func ValidateCaveModel(m *domain.CaveModel) error {
+	verr := &store.ValidationError{}
+	if !caveModelIDPattern.MatchString(m.ID) {
+		verr.Add("id", "id must be 2-64 lowercase letters, digits or dashes")
+	}
+	if m.Brand == "" {
+		verr.Add("brand", "brand is required")
+	} else if len(m.Brand) > 255 {
+		verr.Add("brand", "brand too long (max 255 characters)")
+	}
+	if m.Name == "" {
+		verr.Add("name", "model name is required")
+	} else if len(m.Name) > 255 {
+		verr.Add("name", "model name too long (max 255 characters)")
+	}
+	validateLayout(verr, &m.Layout)
+	return verr.Err()
+}
+
+// applyCaveModel préremplit l'agencement d'une cave depuis le catalogue si son modèle
+// y figure et qu'aucun agencement n'est fourni. Un modèle inconnu reste un texte libre.
+func (s *Server) applyCaveModel(r *http.Request, cave *domain.Cave) error {
+	if cave.Layout != nil || cave.Model == nil || *cave.Model == "" {
+		return nil
+	}
+	model, err := s.store.GetCaveModel(r.Context(), *cave.Model)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	cave.Layout = &model.Layout
+	return nil
+}
+
+// handleGetCaveModels liste le catalogue de modèles de caves (intégrés et personnalisés)
+func (s *Server) handleGetCaveModels(w http.ResponseWriter, r *http.Request) {
+	models, err := s.store.ListCaveModels(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to list cave models", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models)
+}
+
+// handleGetCaveModel retourne un modèle de cave
+func (s *Server) handleGetCaveModel(w http.ResponseWriter, r *http.Request) {
+	model, err := s.store.GetCaveModel(r.Context(), r.PathValue("id"))
+	if err != nil {
+		s.respondStoreError(w, "Failed to get cave model", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model)
+}
+
+// handleCreateCaveModel ajoute un modèle personnalisé au catalogue
+func (s *Server) handleCreateCaveModel(w http.ResponseWriter, r *http.Request) {
+	var model domain.CaveModel
+	if err := json.NewDecoder(r.Body).Decode(&model); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := ValidateCaveModel(&model); err != nil {
+		s.respondStoreError(w, "Invalid cave model", err)
+		return
+	}
+
+	if err := s.store.CreateCaveModel(r.Context(), &model); err != nil {
+		s.respondStoreError(w, "Failed to create cave model", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "cave_model", 0, "cave_model_created", map[string]interface{}{"id": model.ID, "brand": model.Brand, "name": model.Name}, s.getClientIP(r))
+
+	created, err := s.store.GetCaveModel(r.Context(), model.ID)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch cave model", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleUpdateCaveModel remplace un modèle personnalisé (les modèles intégrés sont en lecture seule)
+func (s *Server) handleUpdateCaveModel(w http.ResponseWriter, r *http.Request) {
+	var model domain.CaveModel
+	if err := json.NewDecoder(r.Body).Decode(&model); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	model.ID = r.PathValue("id")
+
+	if err := ValidateCaveModel(&model); err != nil {
+		s.respondStoreError(w, "Invalid cave model", err)
+		return
+	}
+
+	if err := s.store.UpdateCaveModel(r.Context(), &model); err != nil {
+		s.respondStoreError(w, "Failed to update cave model", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "cave_model", 0, "cave_model_updated", map[string]interface{}{"id": model.ID}, s.getClientIP(r))
+
+	updated, err := s.store.GetCaveModel(r.Context(), model.ID)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch cave model", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// handleDeleteCaveModel supprime un modèle personnalisé
+func (s *Server) handleDeleteCaveModel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.store.DeleteCaveModel(r.Context(), id); err != nil {
+		s.respondStoreError(w, "Failed to delete cave model", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "cave_model", 0, "cave_model_deleted", map[string]interface{}{"id": id}, s.getClientIP(r))
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/romain/glou-server/internal/store"
+)
+
+// runCommand exécute une sous-commande CLI et retourne le code de sortie
+func runCommand(config *Config, args []string) int {
+	switch args[0] {
+	case "migrate":
+		return runMigrateCommand(config, args[1:])
+	case "backup":
+		return runBackupCommand(config, args[1:])
+	case "restore":
+		return runRestoreCommand(config, args[1:])
+	case "repair":
+		return runRepairCommand(config, args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+// printUsage affiche l'aide des sous-commandes
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage: glou-server [command]
+
+Without command, starts the HTTP server.
+
+Commands:
+  migrate status        Show applied and pending schema migrations
+  migrate up            Apply all pending migrations
+  migrate down [-steps N]
+                        Revert the last N applied migrations (default 1)
+  backup create         Snapshot the database into BACKUP_DIR (with rotation)
+  backup list           List backups in BACKUP_DIR
+  backup restore <name|path>
+                        Replace the database with a backup (stop the server first);
+                        the current database is kept as <DB_PATH>.pre-restore-<date>
+  restore [--at TIMESTAMP] [--from DIR]
+                        Rebuild the database from the WAL replica (REPLICA_DIR by default)
+                        as it was at TIMESTAMP (RFC 3339 or "2006-01-02 15:04:05" local time;
+                        default: latest); stop the server first
+  repair                Recompute cave and cell occupancy counters`)
+}
+
+// runMigrateCommand gère "migrate status|up|down"
+func runMigrateCommand(config *Config, args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return 2
+	}
+
+	s, err := store.Open(config.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "status":
+		statuses, err := s.MigrationStatus(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range statuses {
+			state, appliedAt := "pending", ""
+			if st.Applied {
+				state = "applied"
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+		}
+		tw.Flush()
+		return 0
+
+	case "up":
+		applied, err := s.Migrate(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database schema is up to date")
+		}
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		return 0
+
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := fs.Int("steps", 1, "number of migrations to revert")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if *steps < 1 {
+			fmt.Fprintln(os.Stderr, "Error: -steps must be at least 1")
+			return 2
+		}
+		reverted, err := s.MigrateDown(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migration to revert")
+		}
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+// runBackupCommand gère "backup create|list|restore"
+func runBackupCommand(config *Config, args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return 2
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		s, err := store.Open(config.DBPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer s.Close()
+
+		backup, err := store.NewBackupManager(s, config.BackupDir, config.backupPolicy()).Create(ctx)
+		if backup != nil {
+			fmt.Printf("Created %s (%d bytes)\n", filepath.Join(config.BackupDir, backup.Name), backup.Size)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+
+	case "list":
+		backups, err := store.NewBackupManager(nil, config.BackupDir, config.backupPolicy()).List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(backups) == 0 {
+			fmt.Printf("No backup in %s\n", config.BackupDir)
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSIZE\tCREATED AT (UTC)")
+		for _, b := range backups {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", b.Name, b.Size, b.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		tw.Flush()
+		return 0
+
+	case "restore":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "Usage: glou-server backup restore <name|path>")
+			return 2
+		}
+		// Un nom seul désigne une sauvegarde de BACKUP_DIR
+		source := args[1]
+		if filepath.Base(source) == source {
+			if _, err := os.Stat(source); os.IsNotExist(err) {
+				source = filepath.Join(config.BackupDir, source)
+			}
+		}
+
+		version, err := store.InspectBackup(ctx, source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		safetyCopy, err := store.RestoreBackup(ctx, source, config.DBPath)
+		if safetyCopy != "" {
+			fmt.Printf("Previous database kept as %s\n", safetyCopy)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Restored %s (schema version %04d) into %s\n", source, version, config.DBPath)
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown backup command %q\n\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+// runRestoreCommand gère "restore [--at TIMESTAMP] [--from DIR]" (restauration depuis la réplique)
+func runRestoreCommand(config *Config, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	atFlag := fs.String("at", "", "point in time to restore (default: latest)")
+	from := fs.String("from", config.ReplicaDir, "replica directory")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" {
+		fmt.Fprintln(os.Stderr, "Error: no replica directory (set REPLICA_DIR or use --from)")
+		return 2
+	}
+
+	var at time.Time
+	if *atFlag != "" {
+		var err error
+		at, err = parseRestoreTime(*atFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	result, err := store.RestoreReplica(context.Background(), *from, config.DBPath, at)
+	if result != nil && result.SafetyCopy != "" {
+		fmt.Printf("Previous database kept as %s\n", result.SafetyCopy)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Restored generation %s up to %s (%d WAL segment(s), schema version %04d) into %s\n",
+		result.Generation, result.RestoredTo.Format(time.RFC3339), result.Segments, result.SchemaVersion, config.DBPath)
+	return 0
+}
+
+// parseRestoreTime accepte une date RFC 3339 ou une date locale "2006-01-02 15:04:05"
+func parseRestoreTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --at %q: use RFC 3339 (2006-01-02T15:04:05Z) or \"2006-01-02 15:04:05\"", value)
+}
+
+// runRepairCommand recalcule les compteurs d'occupation des caves et emplacements
+func runRepairCommand(config *Config, args []string) int {
+	if len(args) != 0 {
+		printUsage()
+		return 2
+	}
+
+	s, err := store.Open(config.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer s.Close()
+
+	fixed, err := s.RecomputeOccupancy(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Occupancy counters recomputed (%d cave(s) or cell(s) corrected)\n", fixed)
+	return 0
+}
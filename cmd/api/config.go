@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/romain/glou-server/internal/store"
 )
 
 // Config encapsule la configuration de sécurité et générale
@@ -26,6 +28,19 @@ type Config struct {
 	// Logging
 	LogLevel string
 
+	// Backups
+	BackupDir        string
+	BackupInterval   time.Duration // 0 = pas de sauvegarde planifiée
+	BackupKeepLast   int
+	BackupKeepDaily  int
+	BackupKeepWeekly int
+
+	// Replication (WAL shipping)
+	ReplicaDir              string // vide = réplication désactivée
+	ReplicaSyncInterval     time.Duration
+	ReplicaSnapshotInterval time.Duration
+	ReplicaRetention        time.Duration
+
 	// Encryption (Recommandations ANSSI)
 	EncryptionPassphrase string // Phrase de chiffrement (min 32 caractères)
 	EncryptionSalt       string // Salt pour dérivation de clé
@@ -43,6 +58,10 @@ type Config struct {
 	SMTPFrom     string
 	SMTPTo       string
 	SMTPUseTLS   bool
+
+	// Enrichment
+	UntappdClientID     string
+	UntappdClientSecret string
 }
 
 // LoadConfig charge la configuration depuis les variables d'environnement
@@ -63,6 +82,17 @@ func LoadConfig() *Config {
 		LogLevel:           getEnv("LOG_LEVEL", "info"),
 		Environment:        getEnv("ENVIRONMENT", "development"),
 		TrustProxyHeaders:  strings.EqualFold(getEnv("TRUST_PROXY_HEADERS", "false"), "true"),
+		// Backups
+		BackupDir:        getEnv("BACKUP_DIR", "./backups"),
+		BackupInterval:   time.Duration(getEnvInt("BACKUP_INTERVAL_HOURS", 24)) * time.Hour,
+		BackupKeepLast:   getEnvInt("BACKUP_KEEP_LAST", 7),
+		BackupKeepDaily:  getEnvInt("BACKUP_KEEP_DAILY", 7),
+		BackupKeepWeekly: getEnvInt("BACKUP_KEEP_WEEKLY", 4),
+		// Replication
+		ReplicaDir:              getEnv("REPLICA_DIR", ""),
+		ReplicaSyncInterval:     time.Duration(getEnvInt("REPLICA_SYNC_INTERVAL_SECONDS", 1)) * time.Second,
+		ReplicaSnapshotInterval: time.Duration(getEnvInt("REPLICA_SNAPSHOT_INTERVAL_HOURS", 24)) * time.Hour,
+		ReplicaRetention:        time.Duration(getEnvInt("REPLICA_RETENTION_HOURS", 72)) * time.Hour,
 		// Notifications
 		GotifyURL:    getEnv("GOTIFY_URL", ""),
 		GotifyToken:  getEnv("GOTIFY_TOKEN", ""),
@@ -73,6 +103,9 @@ func LoadConfig() *Config {
 		SMTPFrom:     getEnv("SMTP_FROM", ""),
 		SMTPTo:       getEnv("SMTP_TO", ""),
 		SMTPUseTLS:   getEnv("SMTP_USE_TLS", "true") == "true",
+		// Enrichment
+		UntappdClientID:     getEnv("UNTAPPD_CLIENT_ID", ""),
+		UntappdClientSecret: getEnv("UNTAPPD_CLIENT_SECRET", ""),
 	}
 
 	// Sessions: default to encryption passphrase if SESSION_SECRET missing (dev only)
@@ -85,6 +118,23 @@ func LoadConfig() *Config {
 	return config
 }
 
+// backupPolicy retourne la politique de rotation des sauvegardes
+func (c *Config) backupPolicy() store.RetentionPolicy {
+	return store.RetentionPolicy{
+		KeepLast:   c.BackupKeepLast,
+		KeepDaily:  c.BackupKeepDaily,
+		KeepWeekly: c.BackupKeepWeekly,
+	}
+}
+
+// replicaPolicy retourne la politique d'instantanés et de rétention de la réplique
+func (c *Config) replicaPolicy() store.ReplicaPolicy {
+	return store.ReplicaPolicy{
+		SnapshotInterval: c.ReplicaSnapshotInterval,
+		Retention:        c.ReplicaRetention,
+	}
+}
+
 // getEnv récupère une variable d'environnement avec une valeur par défaut
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -153,6 +203,15 @@ func (c *Config) Validate() error {
 	if c.EncryptionPassphrase != "" && len(c.EncryptionPassphrase) < 32 {
 		return fmt.Errorf("ENCRYPTION_PASSPHRASE must be at least 32 characters (ANSSI requirement)")
 	}
+	if c.BackupInterval < 0 || c.BackupKeepLast < 0 || c.BackupKeepDaily < 0 || c.BackupKeepWeekly < 0 {
+		return fmt.Errorf("BACKUP_INTERVAL_HOURS and BACKUP_KEEP_* cannot be negative")
+	}
+	if c.BackupKeepLast+c.BackupKeepDaily+c.BackupKeepWeekly == 0 {
+		return fmt.Errorf("backup rotation would delete every backup: set BACKUP_KEEP_LAST, BACKUP_KEEP_DAILY or BACKUP_KEEP_WEEKLY")
+	}
+	if c.ReplicaDir != "" && (c.ReplicaSyncInterval <= 0 || c.ReplicaSnapshotInterval <= 0 || c.ReplicaRetention <= 0) {
+		return fmt.Errorf("REPLICA_SYNC_INTERVAL_SECONDS, REPLICA_SNAPSHOT_INTERVAL_HOURS and REPLICA_RETENTION_HOURS must be positive")
+	}
 	// Session secret required and strong in production
 	if c.Environment == "production" {
 		if c.SessionSecret == "" {
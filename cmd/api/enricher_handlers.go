@@ -28,7 +28,7 @@ type EnrichSpiritRequest struct {
 // handleEnrichByBarcode enriches wine by barcode (EAN)
 func handleEnrichByBarcode(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		respondProblem(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -36,12 +36,12 @@ func handleEnrichByBarcode(w http.ResponseWriter, r *http.Request) {
 	var req EnrichByBarcodeRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Barcode == "" {
-		http.Error(w, "Barcode is required", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Barcode is required")
 		return
 	}
 
@@ -54,7 +54,7 @@ func handleEnrichByBarcode(w http.ResponseWriter, r *http.Request) {
 		// If barcode lookup fails, try as a search query
 		data, err = we.EnrichByName(ctx, req.Barcode, "", "")
 		if err != nil {
-			http.Error(w, "No data found for this barcode", http.StatusNotFound)
+			respondProblem(w, http.StatusNotFound, "No data found for this barcode")
 			return
 		}
 	}
@@ -66,7 +66,7 @@ func handleEnrichByBarcode(w http.ResponseWriter, r *http.Request) {
 // handleEnrichByName enriches wine by name
 func handleEnrichByName(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		respondProblem(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -74,12 +74,12 @@ func handleEnrichByName(w http.ResponseWriter, r *http.Request) {
 	var req EnrichByNameRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Name == "" {
-		http.Error(w, "Wine name is required", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Wine name is required")
 		return
 	}
 
@@ -89,7 +89,7 @@ func handleEnrichByName(w http.ResponseWriter, r *http.Request) {
 	// Enrich by name
 	data, err := we.EnrichByName(ctx, req.Name, req.Producer, req.Vintage)
 	if err != nil {
-		http.Error(w, "No data found for this wine", http.StatusNotFound)
+		respondProblem(w, http.StatusNotFound, "No data found for this wine")
 		return
 	}
 
@@ -100,7 +100,7 @@ func handleEnrichByName(w http.ResponseWriter, r *http.Request) {
 // handleEnrichSpirit enriches spirits and cocktails
 func handleEnrichSpirit(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		respondProblem(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -108,12 +108,12 @@ func handleEnrichSpirit(w http.ResponseWriter, r *http.Request) {
 	var req EnrichSpiritRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Name == "" {
-		http.Error(w, "Spirit/cocktail name is required", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Spirit/cocktail name is required")
 		return
 	}
 
@@ -123,7 +123,35 @@ func handleEnrichSpirit(w http.ResponseWriter, r *http.Request) {
 	// Enrich spirit
 	data, err := we.EnrichSpirit(ctx, req.Name)
 	if err != nil {
-		http.Error(w, "No data found for this spirit", http.StatusNotFound)
+		respondProblem(w, http.StatusNotFound, "No data found for this spirit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// handleEnrichBeer enriches a beer from Untappd (style, IBU, brewery)
+func (s *Server) handleEnrichBeer(w http.ResponseWriter, r *http.Request) {
+	var req EnrichByNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondProblem(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Name == "" {
+		respondProblem(w, http.StatusBadRequest, "Beer name is required")
+		return
+	}
+	if s.config.UntappdClientID == "" || s.config.UntappdClientSecret == "" {
+		respondProblem(w, http.StatusServiceUnavailable, "Untappd enrichment is not configured")
+		return
+	}
+
+	we := enricher.NewWineEnricher()
+	data, err := we.EnrichBeer(r.Context(), req.Name, s.config.UntappdClientID, s.config.UntappdClientSecret)
+	if err != nil {
+		respondProblem(w, http.StatusNotFound, "No data found for this beer")
 		return
 	}
 
@@ -134,7 +162,7 @@ func handleEnrichSpirit(w http.ResponseWriter, r *http.Request) {
 // handleBulkEnrich enriches multiple wines at once
 func handleBulkEnrich(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		respondProblem(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -142,7 +170,7 @@ func handleBulkEnrich(w http.ResponseWriter, r *http.Request) {
 	var requests []EnrichByNameRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -168,7 +196,7 @@ func handleBulkEnrich(w http.ResponseWriter, r *http.Request) {
 // handleEnrichImageBarcode detects and reads barcode from image
 func handleEnrichImageBarcode(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		respondProblem(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -176,12 +204,12 @@ func handleEnrichImageBarcode(w http.ResponseWriter, r *http.Request) {
 	var req EnrichByBarcodeRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Barcode == "" {
-		http.Error(w, "Image data is required", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Image data is required")
 		return
 	}
 
@@ -191,14 +219,14 @@ func handleEnrichImageBarcode(w http.ResponseWriter, r *http.Request) {
 	// Detect barcode in image
 	barcodeResult, err := we.DetectBarcodeInImage(ctx, req.Barcode)
 	if err != nil || barcodeResult == nil || barcodeResult.Barcode == "" {
-		http.Error(w, "No barcode detected in image", http.StatusNotFound)
+		respondProblem(w, http.StatusNotFound, "No barcode detected in image")
 		return
 	}
 
 	// Now enrich using the detected barcode
 	data, err := we.EnrichByBarcode(ctx, barcodeResult.Barcode)
 	if err != nil {
-		http.Error(w, "Could not enrich from detected barcode", http.StatusNotFound)
+		respondProblem(w, http.StatusNotFound, "Could not enrich from detected barcode")
 		return
 	}
 
@@ -209,7 +237,7 @@ func handleEnrichImageBarcode(w http.ResponseWriter, r *http.Request) {
 // handleEnrichImageRecognize recognizes wine from bottle label image
 func handleEnrichImageRecognize(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		respondProblem(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -219,12 +247,12 @@ func handleEnrichImageRecognize(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Image == "" {
-		http.Error(w, "Image data is required", http.StatusBadRequest)
+		respondProblem(w, http.StatusBadRequest, "Image data is required")
 		return
 	}
 
@@ -234,7 +262,7 @@ func handleEnrichImageRecognize(w http.ResponseWriter, r *http.Request) {
 	// Recognize wine from image
 	data, err := we.RecognizeWineLabel(ctx, req.Image)
 	if err != nil {
-		http.Error(w, "Could not recognize wine from image", http.StatusNotFound)
+		respondProblem(w, http.StatusNotFound, "Could not recognize wine from image")
 		return
 	}
 
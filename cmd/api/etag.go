@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/romain/glou-server/internal/store"
+)
+
+// etag formate une version de ligne en ETag fort, ex: "3"
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// setETag expose la version courante d'une ressource
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// ifMatchVersion lit l'en-tête If-Match et retourne la version attendue.
+// Sans en-tête, ou avec "*", la version vaut 0 (écriture sans condition).
+// Seul un ETag fort unique est accepté.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" || raw == "*" {
+		return 0, nil
+	}
+
+	invalid := store.NewValidationError("If-Match", `If-Match must be a single strong entity tag, e.g. "3"`)
+	if strings.HasPrefix(raw, "W/") || strings.Contains(raw, ",") {
+		return 0, invalid
+	}
+	unquoted, err := strconv.Unquote(raw)
+	if err != nil {
+		return 0, invalid
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, invalid
+	}
+	return version, nil
+}
+
+// respondPreconditionFailed répond 412 avec la représentation courante de la ressource,
+// pour que le client puisse fusionner ses modifications et réessayer.
+func (s *Server) respondPreconditionFailed(w http.ResponseWriter, err error, current interface{}, version int64) {
+	log.Printf("[WARN] %v", err)
+	p := newProblem(http.StatusPreconditionFailed, "", err.Error())
+	p.Current = current
+	setETag(w, version)
+	writeProblem(w, p)
+}
+
+// respondWriteError traite une erreur d'écriture conditionnelle : sur une version périmée,
+// relit la ressource via load et répond 412 ; sinon délègue à respondStoreError.
+func (s *Server) respondWriteError(w http.ResponseWriter, message string, err error, load func() (interface{}, int64, error)) {
+	if errors.Is(err, store.ErrPreconditionFailed) {
+		current, version, loadErr := load()
+		if loadErr == nil {
+			s.respondPreconditionFailed(w, err, current, version)
+			return
+		}
+		err = fmt.Errorf("%w (reload failed: %v)", err, loadErr)
+	}
+	s.respondStoreError(w, message, err)
+}
+
+// currentWine, currentTobacco et currentCave relisent une ressource pour respondWriteError
+func (s *Server) currentWine(r *http.Request, id int64) func() (interface{}, int64, error) {
+	return func() (interface{}, int64, error) {
+		wine, err := s.store.GetWineByID(r.Context(), id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return wine, wine.Version, nil
+	}
+}
+
+func (s *Server) currentTobacco(r *http.Request, id int64) func() (interface{}, int64, error) {
+	return func() (interface{}, int64, error) {
+		item, err := s.store.GetTobaccoByID(r.Context(), id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return item, item.Version, nil
+	}
+}
+
+func (s *Server) currentCave(r *http.Request, id int64) func() (interface{}, int64, error) {
+	return func() (interface{}, int64, error) {
+		cave, err := s.store.GetCaveByID(r.Context(), id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return cave, cave.Version, nil
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// maxVolumeML borne le volume d'une bouteille (au-delà du nabuchodonosor)
+const maxVolumeML = 30000
+
+// validateFormat valide le format et le volume d'un vin (le store complète l'un par l'autre)
+func validateFormat(verr *store.ValidationError, wine *domain.Wine) {
+	if wine.Format != "" && wine.Format != domain.FormatCustom {
+		if _, ok := domain.FindBottleFormat(wine.Format); !ok {
+			verr.Add("format", fmt.Sprintf("unknown bottle format %q (see GET /formats)", wine.Format))
+		}
+	}
+	if wine.VolumeML < 0 || wine.VolumeML > maxVolumeML {
+		verr.Add("volume_ml", fmt.Sprintf("volume_ml must be between 1 and %d", maxVolumeML))
+	}
+}
+
+// handleGetBottleFormats liste les formats de bouteille connus
+func (s *Server) handleGetBottleFormats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.BottleFormats())
+}
+
+// handleGetInventory retourne l'inventaire en bouteilles, en litres et en valeur
+func (s *Server) handleGetInventory(w http.ResponseWriter, r *http.Request) {
+	inventory, err := s.store.GetInventory(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to compute inventory", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inventory)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romain/glou-server/internal/store"
+)
+
+// Bornes des recherches dans le référentiel géographique
+const (
+	defaultAppellationResults = 10
+	maxAppellationResults     = 50
+	geocodeResults            = 8
+)
+
+// nominatimClient interroge Nominatim quand le référentiel intégré ne connaît pas le lieu
+var nominatimClient = &http.Client{Timeout: 5 * time.Second}
+
+// geocodeResult reprend le format Nominatim attendu par le client web (LocationAutocomplete)
+type geocodeResult struct {
+	DisplayName string `json:"display_name"`
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	City        string `json:"city"`
+	Country     string `json:"country"`
+	Kind        string `json:"kind,omitempty"` // country, region ou appellation (référentiel intégré)
+	ID          string `json:"id,omitempty"`
+}
+
+// handleGetCountries liste les pays du référentiel avec leurs régions
+func (s *Server) handleGetCountries(w http.ResponseWriter, r *http.Request) {
+	countries, err := s.store.GetCountries(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch countries", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(countries)
+}
+
+// handleSearchAppellations autocomplète les appellations (?q=, ?country=, ?region=, ?limit=)
+func (s *Server) handleSearchAppellations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultAppellationResults
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 || limit > maxAppellationResults {
+			s.respondStoreError(w, "Invalid appellation search",
+				store.NewValidationError("limit", fmt.Sprintf("limit must be between 1 and %d", maxAppellationResults)))
+			return
+		}
+	}
+
+	appellations, err := s.store.SearchAppellations(r.Context(), query.Get("q"), query.Get("country"), query.Get("region"), limit)
+	if err != nil {
+		s.respondStoreError(w, "Failed to search appellations", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(appellations)
+}
+
+// handleGetAppellation retourne une appellation du référentiel
+func (s *Server) handleGetAppellation(w http.ResponseWriter, r *http.Request) {
+	appellation, err := s.store.GetAppellation(r.Context(), r.PathValue("id"))
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch appellation", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(appellation)
+}
+
+// handleGetCountryStats agrège le stock par pays pour la carte
+func (s *Server) handleGetCountryStats(w http.ResponseWriter, r *http.Request) {
+	breakdown, err := s.store.GetGeoBreakdown(r.Context(), "country", "")
+	if err != nil {
+		s.respondStoreError(w, "Failed to aggregate wines by country", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(breakdown)
+}
+
+// handleGetRegionStats agrège le stock par région pour la carte (?country= pour un seul pays)
+func (s *Server) handleGetRegionStats(w http.ResponseWriter, r *http.Request) {
+	breakdown, err := s.store.GetGeoBreakdown(r.Context(), "region", r.URL.Query().Get("country"))
+	if err != nil {
+		s.respondStoreError(w, "Failed to aggregate wines by region", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(breakdown)
+}
+
+// handleGeocodeSearch recherche un lieu (?q=) : d'abord dans le référentiel intégré,
+// puis via Nominatim s'il ne donne rien. Une erreur de Nominatim donne une liste vide.
+func (s *Server) handleGeocodeSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		s.respondStoreError(w, "Invalid geocoding search", store.NewValidationError("q", "q is required"))
+		return
+	}
+	if len(q) > 200 {
+		s.respondStoreError(w, "Invalid geocoding search", store.NewValidationError("q", "q too long (max 200 characters)"))
+		return
+	}
+
+	places, err := s.store.SearchPlaces(r.Context(), q, geocodeResults)
+	if err != nil {
+		s.respondStoreError(w, "Failed to search places", err)
+		return
+	}
+
+	results := make([]geocodeResult, 0, len(places))
+	for _, p := range places {
+		parts := []string{p.Name}
+		if p.Region != "" && p.Region != p.Name {
+			parts = append(parts, p.Region)
+		}
+		if p.Kind != "country" {
+			parts = append(parts, p.Country)
+		}
+		results = append(results, geocodeResult{
+			DisplayName: strings.Join(parts, ", "),
+			Lat:         strconv.FormatFloat(p.Lat, 'f', -1, 64),
+			Lon:         strconv.FormatFloat(p.Lon, 'f', -1, 64),
+			City:        p.Name,
+			Country:     p.Country,
+			Kind:        p.Kind,
+			ID:          p.ID,
+		})
+	}
+	if len(results) == 0 {
+		online, err := searchNominatim(r.Context(), q)
+		if err != nil {
+			log.Printf("Nominatim geocoding failed: %v", err)
+		}
+		results = append(results, online...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// searchNominatim interroge l'API de géocodage d'OpenStreetMap
+func searchNominatim(ctx context.Context, q string) ([]geocodeResult, error) {
+	endpoint := "https://nominatim.openstreetmap.org/search?format=json&addressdetails=1&limit=" +
+		strconv.Itoa(geocodeResults) + "&q=" + url.QueryEscape(q)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Nominatim exige un User-Agent identifiant l'application
+	req.Header.Set("User-Agent", "Glou-WineManager/1.0")
+
+	resp, err := nominatimClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nominatim returned status %d", resp.StatusCode)
+	}
+
+	var found []struct {
+		DisplayName string `json:"display_name"`
+		Lat         string `json:"lat"`
+		Lon         string `json:"lon"`
+		Address     struct {
+			City    string `json:"city"`
+			Town    string `json:"town"`
+			Village string `json:"village"`
+			Country string `json:"country"`
+		} `json:"address"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		return nil, fmt.Errorf("invalid nominatim response: %w", err)
+	}
+
+	results := make([]geocodeResult, 0, len(found))
+	for _, f := range found {
+		city := f.Address.City
+		if city == "" {
+			city = f.Address.Town
+		}
+		if city == "" {
+			city = f.Address.Village
+		}
+		results = append(results, geocodeResult{
+			DisplayName: f.DisplayName,
+			Lat:         f.Lat,
+			Lon:         f.Lon,
+			City:        city,
+			Country:     f.Address.Country,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// Bornes des assemblages et de l'autocomplétion des cépages
+const (
+	maxBlendComponents   = 20
+	defaultGrapeMatches  = 10
+	maxGrapeMatches      = 50
+	blendTotalTolerance  = 0.5 // Arrondis des pourcentages saisis
+	maxGrapeNameLength   = 100
+	maxGrapeSynonymCount = 50
+)
+
+// validateBlend valide la composition d'un vin (champs blend[i].*)
+func validateBlend(verr *store.ValidationError, blend []*domain.BlendComponent) {
+	if len(blend) > maxBlendComponents {
+		verr.Add("blend", fmt.Sprintf("too many grapes in blend (max %d)", maxBlendComponents))
+		return
+	}
+	total := 0.0
+	for i, c := range blend {
+		field := fmt.Sprintf("blend[%d]", i)
+		if c == nil {
+			verr.Add(field, "blend component is required")
+			continue
+		}
+		c.Grape = strings.TrimSpace(c.Grape)
+		if c.GrapeID == 0 && domain.NormalizeGrapeName(c.Grape) == "" {
+			verr.Add(field+".grape", "grape or grape_id is required")
+		} else if len(c.Grape) > maxGrapeNameLength {
+			verr.Add(field+".grape", fmt.Sprintf("grape name too long (max %d characters)", maxGrapeNameLength))
+		}
+		if c.Percentage != nil {
+			if *c.Percentage <= 0 || *c.Percentage > 100 {
+				verr.Add(field+".percentage", "percentage must be greater than 0 and at most 100")
+			} else {
+				total += *c.Percentage
+			}
+		}
+	}
+	if total > 100+blendTotalTolerance {
+		verr.Add("blend", fmt.Sprintf("blend percentages add up to %g%% (max 100%%)", total))
+	}
+}
+
+// ValidateGrape valide un cépage avant enregistrement
+func ValidateGrape(g *domain.GrapeVariety) error {
+	verr := &store.ValidationError{}
+	g.Name = strings.TrimSpace(g.Name)
+	if domain.NormalizeGrapeName(g.Name) == "" {
+		verr.Add("name", "grape name is required")
+	} else if len(g.Name) > maxGrapeNameLength {
+		verr.Add("name", fmt.Sprintf("grape name too long (max %d characters)", maxGrapeNameLength))
+	}
+	if g.Color != "" && g.Color != domain.GrapeColorRed && g.Color != domain.GrapeColorWhite {
+		verr.Add("color", "color must be red or white")
+	}
+	if len(g.Synonyms) > maxGrapeSynonymCount {
+		verr.Add("synonyms", fmt.Sprintf("too many synonyms (max %d)", maxGrapeSynonymCount))
+	}
+	for i, synonym := range g.Synonyms {
+		if len(synonym) > maxGrapeNameLength {
+			verr.Add(fmt.Sprintf("synonyms[%d]", i), fmt.Sprintf("synonym too long (max %d characters)", maxGrapeNameLength))
+		}
+	}
+	return verr.Err()
+}
+
+// handleGetGrapes liste le référentiel des cépages
+func (s *Server) handleGetGrapes(w http.ResponseWriter, r *http.Request) {
+	grapes, err := s.store.GetGrapes(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch grape varieties", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grapes)
+}
+
+// handleMatchGrapes autocomplète les cépages par nom ou synonyme (?name=, ?limit=)
+func (s *Server) handleMatchGrapes(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if strings.TrimSpace(name) == "" {
+		s.respondStoreError(w, "Invalid grape lookup", store.NewValidationError("name", "name is required"))
+		return
+	}
+	limit := defaultGrapeMatches
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 || limit > maxGrapeMatches {
+			s.respondStoreError(w, "Invalid grape lookup",
+				store.NewValidationError("limit", fmt.Sprintf("limit must be between 1 and %d", maxGrapeMatches)))
+			return
+		}
+	}
+
+	grapes, err := s.store.MatchGrapes(r.Context(), name, limit)
+	if err != nil {
+		s.respondStoreError(w, "Failed to match grape varieties", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grapes)
+}
+
+// handleGetGrape retourne un cépage par son ID
+func (s *Server) handleGetGrape(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid grape ID", err)
+		return
+	}
+
+	grape, err := s.store.GetGrape(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch grape variety", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grape)
+}
+
+// handleCreateGrape ajoute un cépage au référentiel
+func (s *Server) handleCreateGrape(w http.ResponseWriter, r *http.Request) {
+	var grape domain.GrapeVariety
+	if err := json.NewDecoder(r.Body).Decode(&grape); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := ValidateGrape(&grape); err != nil {
+		s.respondStoreError(w, "Invalid grape variety", err)
+		return
+	}
+
+	id, err := s.store.CreateGrape(r.Context(), &grape)
+	if err != nil {
+		s.respondStoreError(w, "Failed to create grape variety", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "grape", id, "grape_created", map[string]interface{}{"name": grape.Name}, s.getClientIP(r))
+
+	created, err := s.store.GetGrape(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch grape variety", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleUpdateGrape met à jour un cépage (nom, couleur, synonymes)
+func (s *Server) handleUpdateGrape(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid grape ID", err)
+		return
+	}
+
+	var grape domain.GrapeVariety
+	if err := json.NewDecoder(r.Body).Decode(&grape); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	grape.ID = id
+	if err := ValidateGrape(&grape); err != nil {
+		s.respondStoreError(w, "Invalid grape variety", err)
+		return
+	}
+
+	if err := s.store.UpdateGrape(r.Context(), &grape); err != nil {
+		s.respondStoreError(w, "Failed to update grape variety", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "grape", id, "grape_updated", map[string]interface{}{"name": grape.Name}, s.getClientIP(r))
+
+	updated, err := s.store.GetGrape(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch grape variety", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// handleDeleteGrape supprime un cépage absent de tout assemblage
+func (s *Server) handleDeleteGrape(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid grape ID", err)
+		return
+	}
+
+	if err := s.store.DeleteGrape(r.Context(), id); err != nil {
+		s.respondStoreError(w, "Failed to delete grape variety", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "grape", id, "grape_deleted", nil, s.getClientIP(r))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetGrapeWines liste les vins contenant un cépage
+func (s *Server) handleGetGrapeWines(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid grape ID", err)
+		return
+	}
+
+	wines, err := s.store.GetGrapeWines(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch grape wines", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wines)
+}
+
+// handleGetGrapeStats agrège les vins, bouteilles et notes par cépage
+func (s *Server) handleGetGrapeStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.store.GetGrapeStats(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to aggregate wines by grape", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handleGetBlendStats classe les assemblages par note moyenne (?grape= pour ceux contenant un cépage)
+func (s *Server) handleGetBlendStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.store.GetBlendStats(r.Context(), r.URL.Query().Get("grape"))
+	if err != nil {
+		s.respondStoreError(w, "Failed to aggregate wines by blend", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -21,21 +22,13 @@ import (
 	"github.com/romain/glou-server/internal/store"
 )
 
-// Valid wine types
-var validWineTypes = map[string]bool{
-	"Red":       true,
-	"White":     true,
-	"Rosé":      true,
-	"Sparkling": true,
-	"Beer":      true,
-	"Spirit":    true,
-}
-
 // Valid alert types
 var validAlertTypes = map[string]bool{
-	"low_stock":      true,
-	"apogee_reached": true,
-	"apogee_ended":   true,
+	"low_stock":               true,
+	"apogee_reached":          true,
+	"apogee_ended":            true,
+	"best_before_approaching": true,
+	"opened_too_long":         true,
 }
 
 // mimeTypeHandler ajoute les bons Content-Type aux fichiers statiques
@@ -131,42 +124,58 @@ func safeJoin(base, rel string) (string, bool) {
 	return full, true
 }
 
-// ValidateWine validates wine data before storage
+// ValidateWine validates wine data before storage.
+// Les erreurs sont regroupées par champ dans une *store.ValidationError.
 func ValidateWine(wine *domain.Wine) error {
+	verr := &store.ValidationError{}
 	if wine.Name == "" {
-		return errors.New("wine name is required")
-	}
-	if len(wine.Name) > 255 {
-		return errors.New("wine name too long (max 255 characters)")
-	}
-	if wine.Region == "" {
-		return errors.New("wine region is required")
-	}
-	if len(wine.Region) > 255 {
-		return errors.New("wine region too long (max 255 characters)")
+		verr.Add("name", "wine name is required")
+	} else if len(wine.Name) > 255 {
+		verr.Add("name", "wine name too long (max 255 characters)")
+	}
+	// Accepte les types canoniques et les anciens libellés ("Red", "Rosé"...)
+	requestedType := wine.BottleType
+	if requestedType == "" {
+		requestedType = wine.WineType
+	}
+	if bottleType, ok := domain.NormalizeBottleType(requestedType); ok {
+		wine.BottleType = bottleType
+		wine.WineType = bottleType
+	} else {
+		verr.Add("bottle_type", fmt.Sprintf("invalid bottle type: must be one of %s", strings.Join(domain.BottleTypes(), ", ")))
 	}
-	if wine.Vintage < 1900 || wine.Vintage > time.Now().Year() {
-		return fmt.Errorf("invalid vintage: must be between 1900 and %d", time.Now().Year())
+	// La région peut être déduite de l'appellation (référentiel géographique) ; une bière peut s'en passer
+	if wine.Region == "" && wine.AppellationID == nil && wine.BottleType != domain.BottleTypeBeer {
+		verr.Add("region", "wine region is required")
+	} else if len(wine.Region) > 255 {
+		verr.Add("region", "wine region too long (max 255 characters)")
 	}
-	if !validWineTypes[wine.WineType] {
-		return fmt.Errorf("invalid wine type: must be Red, White, Rosé, or Sparkling")
+	if wine.AppellationID != nil && len(*wine.AppellationID) > 100 {
+		verr.Add("appellation_id", "appellation ID too long (max 100 characters)")
 	}
+	validateVintage(verr, wine)
+	validateBeer(verr, wine)
+	validateSpirit(verr, wine)
 	if wine.Quantity < 0 {
-		return errors.New("quantity cannot be negative")
+		verr.Add("quantity", "quantity cannot be negative")
+	} else if wine.Quantity > domain.MaxBottleQuantity {
+		verr.Add("quantity", fmt.Sprintf("quantity cannot exceed %d", domain.MaxBottleQuantity))
 	}
 	if wine.Rating != nil && (*wine.Rating < 0 || *wine.Rating > 5) {
-		return errors.New("rating must be between 0 and 5")
+		verr.Add("rating", "rating must be between 0 and 5")
 	}
-	if wine.AlcoholLevel != nil && (*wine.AlcoholLevel < 0 || *wine.AlcoholLevel > 20) {
-		return errors.New("alcohol level must be between 0 and 20")
+	if maxLevel := maxAlcoholLevel(wine.BottleType); wine.AlcoholLevel != nil && (*wine.AlcoholLevel < 0 || *wine.AlcoholLevel > maxLevel) {
+		verr.Add("alcohol_level", fmt.Sprintf("alcohol level must be between 0 and %g", maxLevel))
 	}
 	if wine.Price != nil && *wine.Price < 0 {
-		return errors.New("price cannot be negative")
+		verr.Add("price", "price cannot be negative")
 	}
 	if wine.MinApogeeDate != nil && wine.MaxApogeeDate != nil && wine.MinApogeeDate.After(*wine.MaxApogeeDate) {
-		return errors.New("min apogee date must be before max apogee date")
+		verr.Add("min_apogee_date", "min apogee date must be before max apogee date")
 	}
-	return nil
+	validateFormat(verr, wine)
+	validateBlend(verr, wine.Blend)
+	return verr.Err()
 }
 
 // ValidateAlert validates alert data before storage
@@ -175,7 +184,7 @@ func ValidateAlert(alert *domain.Alert) error {
 		return errors.New("wine ID is required")
 	}
 	if !validAlertTypes[alert.AlertType] {
-		return fmt.Errorf("invalid alert type: must be low_stock, apogee_reached, or apogee_ended")
+		return fmt.Errorf("invalid alert type: must be low_stock, apogee_reached, apogee_ended, best_before_approaching or opened_too_long")
 	}
 	if alert.Status != "active" && alert.Status != "dismissed" {
 		return fmt.Errorf("invalid alert status: must be active or dismissed")
@@ -199,6 +208,8 @@ type Server struct {
 	config          *Config
 	limiter         *RateLimiter
 	notifierManager *notifier.NotifierManager
+	backups         *store.BackupManager
+	replicator      *store.Replicator
 }
 
 // corsMiddleware sécurisé avec vérification d'origine
@@ -268,9 +279,16 @@ func (s *Server) setupRoutes() {
 	s.router.HandleFunc("PUT /api/user/me", authRequired(s.handleUpdateUser))
 	s.router.HandleFunc("POST /api/user/change-password", authRequired(s.handleChangePassword))
 
-	// Geocoding - Protégé par authentification
+	// Geocoding - Protégé par authentification (référentiel intégré, puis Nominatim)
 	s.router.HandleFunc("GET /api/geocoding/search", authRequired(s.handleGeocodeSearch))
 
+	// Référentiel géographique - Protégé par authentification
+	s.router.HandleFunc("GET /geo/countries", authRequired(s.handleGetCountries))
+	s.router.HandleFunc("GET /geo/appellations", authRequired(s.handleSearchAppellations))
+	s.router.HandleFunc("GET /geo/appellations/{id}", authRequired(s.handleGetAppellation))
+	s.router.HandleFunc("GET /geo/stats/countries", authRequired(s.handleGetCountryStats))
+	s.router.HandleFunc("GET /geo/stats/regions", authRequired(s.handleGetRegionStats))
+
 	// Raccourci pour les routes réservées aux administrateurs
 	adminOnly := func(next http.HandlerFunc) http.HandlerFunc {
 		return authRequired(s.setupCheckMiddleware(s.adminRequiredMiddleware(next)))
@@ -289,22 +307,85 @@ func (s *Server) setupRoutes() {
 	s.router.HandleFunc("GET /wines/{id}", authRequired(s.handleGetWineByID))
 	s.router.HandleFunc("DELETE /wines/{id}", authRequired(s.handleDeleteWine))
 	s.router.HandleFunc("PUT /wines/{id}", authRequired(s.handleUpdateWine))
+	s.router.HandleFunc("PATCH /wines/{id}", authRequired(s.handlePatchWine))
+	s.router.HandleFunc("POST /wines/{id}/rebuy", authRequired(s.handleRebuyWine))
+	s.router.HandleFunc("POST /wines/{id}/move", authRequired(s.handleMoveWine))
+	s.router.HandleFunc("GET /wines/{id}/movements", authRequired(s.handleGetWineMovements))
+	s.router.HandleFunc("GET /wines/{id}/units", authRequired(s.handleGetWineUnits))
+	s.router.HandleFunc("POST /wines/{id}/units", authRequired(s.handleAddWineUnit))
+	s.router.HandleFunc("GET /units", authRequired(s.handleFindUnit))
+	s.router.HandleFunc("GET /units/opened", authRequired(s.handleGetOpenedBottles))
+	s.router.HandleFunc("GET /units/{id}", authRequired(s.handleGetUnit))
+	s.router.HandleFunc("PUT /units/{id}", authRequired(s.handleUpdateUnit))
+	s.router.HandleFunc("POST /units/{id}/open", authRequired(s.handleOpenUnit))
+	s.router.HandleFunc("GET /wines/{id}/purchases", authRequired(s.handleGetWinePurchases))
+	s.router.HandleFunc("POST /wines/{id}/purchases", authRequired(s.handleCreateWinePurchase))
+
+	// Producteurs
+	s.router.HandleFunc("GET /producers", authRequired(s.handleGetProducers))
+	s.router.HandleFunc("POST /producers", authRequired(s.handleCreateProducer))
+	s.router.HandleFunc("GET /producers/match", authRequired(s.handleMatchProducers))
+	s.router.HandleFunc("GET /producers/{id}", authRequired(s.handleGetProducer))
+	s.router.HandleFunc("PUT /producers/{id}", authRequired(s.handleUpdateProducer))
+	s.router.HandleFunc("DELETE /producers/{id}", authRequired(s.handleDeleteProducer))
+	s.router.HandleFunc("GET /producers/{id}/wines", authRequired(s.handleGetProducerWines))
+	s.router.HandleFunc("GET /producers/{id}/consumption", authRequired(s.handleGetProducerConsumption))
+
+	// Cépages et assemblages
+	s.router.HandleFunc("GET /grapes", authRequired(s.handleGetGrapes))
+	s.router.HandleFunc("POST /grapes", authRequired(s.handleCreateGrape))
+	s.router.HandleFunc("GET /grapes/match", authRequired(s.handleMatchGrapes))
+	s.router.HandleFunc("GET /grapes/stats", authRequired(s.handleGetGrapeStats))
+	s.router.HandleFunc("GET /grapes/stats/blends", authRequired(s.handleGetBlendStats))
+	s.router.HandleFunc("GET /grapes/{id}", authRequired(s.handleGetGrape))
+	s.router.HandleFunc("PUT /grapes/{id}", authRequired(s.handleUpdateGrape))
+	s.router.HandleFunc("DELETE /grapes/{id}", authRequired(s.handleDeleteGrape))
+	s.router.HandleFunc("GET /grapes/{id}/wines", authRequired(s.handleGetGrapeWines))
+
+	// Formats de bouteille et inventaire en litres
+	s.router.HandleFunc("GET /formats", authRequired(s.handleGetBottleFormats))
+	s.router.HandleFunc("GET /inventory", authRequired(s.handleGetInventory))
+
+	// Recherche plein texte (vins, tabacs, commentaires)
+	s.router.HandleFunc("GET /search", authRequired(s.handleSearch))
 
 	// Tobacco - Protégées par authentification
 	s.router.HandleFunc("GET /tobacco", authRequired(s.handleGetTobacco))
 	s.router.HandleFunc("POST /tobacco", authRequired(s.handleCreateTobacco))
 	s.router.HandleFunc("GET /tobacco/{id}", authRequired(s.handleGetTobaccoByID))
 	s.router.HandleFunc("PUT /tobacco/{id}", authRequired(s.handleUpdateTobacco))
+	s.router.HandleFunc("PATCH /tobacco/{id}", authRequired(s.handlePatchTobacco))
 	s.router.HandleFunc("DELETE /tobacco/{id}", authRequired(s.handleDeleteTobacco))
 
+	s.router.HandleFunc("GET /tobacco/{id}/purchases", authRequired(s.handleGetTobaccoPurchases))
+	s.router.HandleFunc("POST /tobacco/{id}/purchases", authRequired(s.handleCreateTobaccoPurchase))
+
 	// Preflight CORS for tobacco endpoints
 	s.router.HandleFunc("OPTIONS /tobacco", applyCorsOnly(s.handleOptions))
 	s.router.HandleFunc("OPTIONS /tobacco/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /tobacco/{id}/purchases", applyCorsOnly(s.handleOptions))
+
+	// Achats : lots d'achat et marchands
+	s.router.HandleFunc("PUT /purchases/{id}", authRequired(s.handleUpdatePurchaseLot))
+	s.router.HandleFunc("DELETE /purchases/{id}", authRequired(s.handleDeletePurchaseLot))
+	s.router.HandleFunc("GET /merchants", authRequired(s.handleGetMerchants))
+	s.router.HandleFunc("POST /merchants", authRequired(s.handleCreateMerchant))
+	s.router.HandleFunc("GET /merchants/{id}", authRequired(s.handleGetMerchant))
+	s.router.HandleFunc("PUT /merchants/{id}", authRequired(s.handleUpdateMerchant))
+	s.router.HandleFunc("DELETE /merchants/{id}", authRequired(s.handleDeleteMerchant))
+	s.router.HandleFunc("GET /merchants/{id}/purchases", authRequired(s.handleGetMerchantPurchases))
+	s.router.HandleFunc("OPTIONS /purchases/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /merchants", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /merchants/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /merchants/{id}/purchases", applyCorsOnly(s.handleOptions))
 
 	// Caves - Protégées par authentification
 	s.router.HandleFunc("GET /caves", authRequired(s.handleGetCaves))
 	s.router.HandleFunc("POST /caves", authRequired(s.handleCreateCave))
+	s.router.HandleFunc("GET /caves/{id}", authRequired(s.handleGetCave))
 	s.router.HandleFunc("PUT /caves/{id}", authRequired(s.handleUpdateCave))
+	s.router.HandleFunc("PATCH /caves/{id}", authRequired(s.handlePatchCave))
+	s.router.HandleFunc("DELETE /caves/{id}", authRequired(s.handleDeleteCave))
 
 	// Bottles - Protégées par authentification
 	s.router.HandleFunc("GET /bottles", authRequired(s.handleGetAllBottles))
@@ -312,7 +393,19 @@ func (s *Server) setupRoutes() {
 
 	// Cells - Protégées par authentification
 	s.router.HandleFunc("GET /caves/{caveID}/cells", authRequired(s.handleGetCells))
+	s.router.HandleFunc("GET /caves/{caveID}/layout", authRequired(s.handleGetCaveLayout))
+	s.router.HandleFunc("POST /caves/{caveID}/placement-plan", authRequired(s.handlePlanPlacement))
+	s.router.HandleFunc("POST /caves/{caveID}/placement-plan/apply", authRequired(s.handleApplyPlacement))
+
+	// Catalogue de modèles de caves (intégré + personnalisés par les administrateurs)
+	s.router.HandleFunc("GET /cave-models", authRequired(s.handleGetCaveModels))
+	s.router.HandleFunc("GET /cave-models/{id}", authRequired(s.handleGetCaveModel))
 	s.router.HandleFunc("POST /cells", authRequired(s.handleCreateCell))
+	s.router.HandleFunc("DELETE /cells/{id}", authRequired(s.handleDeleteCell))
+
+	// Corbeille - éléments supprimés, restaurables jusqu'à la purge
+	s.router.HandleFunc("GET /trash", authRequired(s.handleGetTrash))
+	s.router.HandleFunc("POST /trash/{type}/{id}/restore", authRequired(s.handleRestoreFromTrash))
 
 	// Preflight CORS for cells
 	s.router.HandleFunc("OPTIONS /cells", applyCorsOnly(s.handleOptions))
@@ -350,6 +443,16 @@ func (s *Server) setupRoutes() {
 	s.router.HandleFunc("GET /admin", adminOnly(s.handleAdminDashboard))
 	s.router.HandleFunc("GET /api/admin/settings", adminOnly(s.handleGetSettings))
 	s.router.HandleFunc("PUT /api/admin/settings", adminOnly(s.handleUpdateSettings))
+	s.router.HandleFunc("PATCH /api/admin/settings", adminOnly(s.handlePatchSettings))
+
+	// Sauvegardes
+	s.router.HandleFunc("GET /api/admin/backups", adminOnly(s.handleListBackups))
+	s.router.HandleFunc("POST /api/admin/backups", adminOnly(s.handleCreateBackup))
+	s.router.HandleFunc("GET /api/admin/backups/{name}", adminOnly(s.handleDownloadBackup))
+	s.router.HandleFunc("POST /api/admin/cave-models", adminOnly(s.handleCreateCaveModel))
+	s.router.HandleFunc("PUT /api/admin/cave-models/{id}", adminOnly(s.handleUpdateCaveModel))
+	s.router.HandleFunc("DELETE /api/admin/cave-models/{id}", adminOnly(s.handleDeleteCaveModel))
+	s.router.HandleFunc("POST /api/admin/producers/{id}/merge", adminOnly(s.handleMergeProducers))
 	s.router.HandleFunc("POST /api/admin/upload-logo", adminOnly(s.handleUploadLogo))
 	s.router.HandleFunc("GET /api/admin/stats", adminOnly(s.handleAdminStats))
 	s.router.HandleFunc("GET /api/admin/users", adminOnly(s.handleGetUsers))
@@ -358,6 +461,7 @@ func (s *Server) setupRoutes() {
 	s.router.HandleFunc("POST /api/enrich/barcode", authRequired(handleEnrichByBarcode))
 	s.router.HandleFunc("POST /api/enrich/name", authRequired(handleEnrichByName))
 	s.router.HandleFunc("POST /api/enrich/spirit", authRequired(handleEnrichSpirit))
+	s.router.HandleFunc("POST /api/enrich/beer", authRequired(s.handleEnrichBeer))
 	s.router.HandleFunc("POST /api/enrich/bulk", authRequired(handleBulkEnrich))
 
 	// Enrichment - Image recognition (mobile camera) - Protégé par authentification
@@ -367,9 +471,45 @@ func (s *Server) setupRoutes() {
 	// OPTIONS
 	s.router.HandleFunc("OPTIONS /wines", applyCorsOnly(s.handleOptions))
 	s.router.HandleFunc("OPTIONS /wines/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /wines/{id}/rebuy", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /wines/{id}/move", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /wines/{id}/movements", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /wines/{id}/units", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /units", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /units/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /units/opened", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /units/{id}/open", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /wines/{id}/purchases", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /producers", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /producers/match", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /producers/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /producers/{id}/wines", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /producers/{id}/consumption", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /grapes", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /grapes/match", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /grapes/stats", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /grapes/stats/blends", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /grapes/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /grapes/{id}/wines", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /geo/countries", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /geo/appellations", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /geo/appellations/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /geo/stats/countries", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /geo/stats/regions", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /formats", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /inventory", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /api/admin/producers/{id}/merge", applyCorsOnly(s.handleOptions))
 	s.router.HandleFunc("OPTIONS /caves", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /caves/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /cells/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /trash/{type}/{id}/restore", applyCorsOnly(s.handleOptions))
 	s.router.HandleFunc("OPTIONS /alerts", applyCorsOnly(s.handleOptions))
 	s.router.HandleFunc("OPTIONS /api/admin/settings", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /api/admin/backups", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /cave-models", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /cave-models/{id}", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /api/admin/cave-models", applyCorsOnly(s.handleOptions))
+	s.router.HandleFunc("OPTIONS /api/admin/cave-models/{id}", applyCorsOnly(s.handleOptions))
 
 	// Health check
 	s.router.HandleFunc("GET /health", applySecurityMiddlewares(s.handleHealth))
@@ -454,14 +594,21 @@ func (s *Server) Start(addr string) error {
 	return nil
 }
 
-// handleGetWines retourne la liste de tous les vins
+// handleGetWines retourne la liste des vins (filtres, tri et pagination par curseur)
 func (s *Server) handleGetWines(w http.ResponseWriter, r *http.Request) {
-	wines, err := s.store.GetWines(r.Context())
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid list parameters", err)
+		return
+	}
+
+	wines, page, err := s.store.ListWines(r.Context(), opts)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "Failed to fetch wines", err)
+		s.respondStoreError(w, "Failed to fetch wines", err)
 		return
 	}
 
+	writePageHeaders(w, r, page, opts.Limit)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(wines)
 }
@@ -487,7 +634,7 @@ func (s *Server) handleCreateWine(w http.ResponseWriter, r *http.Request) {
 
 	// Validation complète
 	if err := ValidateWine(&wine); err != nil {
-		s.respondError(w, http.StatusBadRequest, err.Error(), nil)
+		s.respondStoreError(w, "Invalid wine", err)
 		return
 	}
 
@@ -495,24 +642,42 @@ func (s *Server) handleCreateWine(w http.ResponseWriter, r *http.Request) {
 		wine.Quantity = 1
 	}
 
-	id, err := s.store.CreateWine(r.Context(), &wine)
+	// Rattacher la bouteille à l'utilisateur connecté
+	if wine.UserID == nil {
+		if userID, ok := getUserFromContext(r.Context()); ok {
+			owner := strconv.FormatInt(userID, 10)
+			wine.UserID = &owner
+		}
+	}
+
+	// Producteur non reconnu : proposer les producteurs existants qui lui ressemblent
+	suggestions := s.producerSuggestions(r, &wine)
+
+	id, err := s.store.CreateWine(r.Context(), &wine, overflowAllowed(r))
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "Failed to create wine", err)
+		s.respondStoreError(w, "Failed to create wine", err)
 		return
 	}
 
-	wine.ID = id
-	wine.CreatedAt = time.Now()
-
 	// Audit
-	s.store.LogActivity(r.Context(), "wine", wine.ID, "wine_created", map[string]interface{}{"name": wine.Name, "region": wine.Region, "vintage": wine.Vintage}, s.getClientIP(r))
+	s.store.LogActivity(r.Context(), "wine", id, "wine_created", map[string]interface{}{"name": wine.Name, "region": wine.Region, "vintage": wine.Vintage}, s.getClientIP(r))
 
+	// Relire le vin : version, cave déduite de l'emplacement, format, date de création
+	created, err := s.store.GetWineByID(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch wine", err)
+		return
+	}
+	created.ProducerSuggestions = suggestions
+
+	setETag(w, created.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(wine)
+	json.NewEncoder(w).Encode(created)
 }
 
-// handleDeleteWine supprime ou décrémente la quantité d'un vin
+// handleDeleteWine met le vin à la corbeille, d'où il peut être restauré.
+// ?all=true, historique, a le même effet. If-Match optionnel : 412 si la version a changé.
 func (s *Server) handleDeleteWine(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -521,41 +686,42 @@ func (s *Server) handleDeleteWine(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.store.DeleteWine(r.Context(), id); err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			s.respondError(w, http.StatusNotFound, "Wine not found", err)
-		} else {
-			s.respondError(w, http.StatusInternalServerError, "Failed to delete wine", err)
-		}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
+	if err := s.store.SoftDelete(r.Context(), store.TrashWine, id, version); err != nil {
+		s.respondWriteError(w, "Failed to delete wine", err, s.currentWine(r, id))
 		return
 	}
 
 	// Audit
-	s.store.LogActivity(r.Context(), "wine", id, "wine_deleted_or_decremented", map[string]string{"id": idStr}, s.getClientIP(r))
+	s.store.LogActivity(r.Context(), "wine", id, "wine_trashed", map[string]string{"id": idStr}, s.getClientIP(r))
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// handleHealth retourne le statut du serveur
+// handleHealth retourne le statut du serveur et, si elle est active, celui de la réplication.
+// Une réplique en retard rend le statut "degraded" sans faire échouer le healthcheck.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprintf(w, `{"status":"healthy"}`)
-}
-
-// respondError écrit une réponse d'erreur JSON
-func (s *Server) respondError(w http.ResponseWriter, statusCode int, message string, err error) {
-	logMessage := message
-	if err != nil {
-		logMessage = fmt.Sprintf("%s: %v", message, err)
+	if s.replicator == nil {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"healthy"}`)
+		return
 	}
-	log.Printf("[ERROR] %s", logMessage)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	response := map[string]interface{}{
-		"error": message,
+	replication := s.replicator.Status()
+	status := "healthy"
+	if !replication.Healthy {
+		status = "degraded"
 	}
-	json.NewEncoder(w).Encode(response)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      status,
+		"replication": replication,
+	})
 }
 
 // handleGetWineByID retourne un vin par son ID
@@ -569,15 +735,16 @@ func (s *Server) handleGetWineByID(w http.ResponseWriter, r *http.Request) {
 
 	wine, err := s.store.GetWineByID(r.Context(), id)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "Wine not found", err)
+		s.respondStoreError(w, "Failed to fetch wine", err)
 		return
 	}
 
+	setETag(w, wine.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(wine)
 }
 
-// handleUpdateWine met à jour un vin
+// handleUpdateWine met à jour un vin (If-Match optionnel : 412 si la version a changé)
 func (s *Server) handleUpdateWine(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -586,6 +753,12 @@ func (s *Server) handleUpdateWine(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
 	var wine domain.Wine
 	if err := json.NewDecoder(r.Body).Decode(&wine); err != nil {
 		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
@@ -593,31 +766,166 @@ func (s *Server) handleUpdateWine(w http.ResponseWriter, r *http.Request) {
 	}
 
 	wine.ID = id
+	// La version attendue vient uniquement de If-Match, pas du corps
+	wine.Version = version
 
 	// Validation complète
 	if err := ValidateWine(&wine); err != nil {
-		s.respondError(w, http.StatusBadRequest, err.Error(), nil)
+		s.respondStoreError(w, "Invalid wine", err)
 		return
 	}
 
-	if err := s.store.UpdateWine(r.Context(), &wine); err != nil {
-		s.respondError(w, http.StatusInternalServerError, "Failed to update wine", err)
+	if err := s.store.UpdateWine(r.Context(), &wine, overflowAllowed(r)); err != nil {
+		s.respondWriteError(w, "Failed to update wine", err, s.currentWine(r, id))
 		return
 	}
 
 	// Audit
 	s.store.LogActivity(r.Context(), "wine", wine.ID, "wine_updated", map[string]interface{}{"name": wine.Name, "region": wine.Region, "vintage": wine.Vintage}, s.getClientIP(r))
 
+	setETag(w, wine.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wine)
+}
+
+// handlePatchWine applique un JSON Merge Patch (RFC 7396) à un vin :
+// seuls les champs fournis sont validés et modifiés ; null efface une valeur.
+func (s *Server) handlePatchWine(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid wine ID", err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
+	patch, err := decodeMergePatch(r)
+	if err != nil {
+		s.respondPatchError(w, "Invalid merge patch", err)
+		return
+	}
+	// wine_type reste l'alias historique de bottle_type
+	if alias, ok := patch["wine_type"]; ok {
+		if _, ok := patch["bottle_type"]; !ok {
+			patch["bottle_type"] = alias
+		}
+	}
+	// null efface l'assemblage (un assemblage absent est laissé tel quel)
+	if blend, ok := patch["blend"]; ok && blend == nil {
+		patch["blend"] = []interface{}{}
+	}
+
+	var wine *domain.Wine
+	var changes map[string]fieldChange
+	for attempt := 1; ; attempt++ {
+		wine, err = s.store.GetWineByID(r.Context(), id)
+		if err != nil {
+			s.respondStoreError(w, "Failed to fetch wine", err)
+			return
+		}
+		expected := wine.Version
+		if version != 0 {
+			expected = version
+		}
+		format := wine.Format
+
+		changes, err = applyMergePatch(wine, patch, "id", "created_at", "version", "status", "finished_at")
+		if err == nil {
+			err = patchedFieldsOnly(ValidateWine(wine), patch)
+		}
+		if err != nil {
+			s.respondStoreError(w, "Invalid wine", err)
+			return
+		}
+		if len(changes) == 0 {
+			// Rien à écrire : seule la précondition est vérifiée
+			if version != 0 && wine.Version != version {
+				s.respondPreconditionFailed(w, store.StaleVersion("wine", id, wine.Version), wine, wine.Version)
+				return
+			}
+			break
+		}
+
+		wine.ID = id
+		wine.Version = expected
+		err = s.store.UpdateWine(r.Context(), wine, overflowAllowed(r))
+		if errors.Is(err, store.ErrPreconditionFailed) && version == 0 && attempt < maxPatchAttempts {
+			continue // modifié entre la lecture et l'écriture : réappliquer le patch
+		}
+		if err != nil {
+			s.respondWriteError(w, "Failed to update wine", err, s.currentWine(r, id))
+			return
+		}
+		// Un volume seul fait passer au format correspondant (ou custom)
+		if _, ok := changes["format"]; !ok && wine.Format != format {
+			changes["format"] = fieldChange{From: format, To: wine.Format}
+		}
+		break
+	}
+
+	// Audit
+	if len(changes) > 0 {
+		s.store.LogActivity(r.Context(), "wine", id, "wine_patched", map[string]interface{}{"fields": changedFields(changes), "changes": changes}, s.getClientIP(r))
+	}
+
+	setETag(w, wine.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wine)
+}
+
+// handleRebuyWine recrée une entrée en stock à partir d'un vin (terminé ou non).
+// Corps optionnel : {"quantity": 6, "price": 12.5, "cell_id": 3}
+func (s *Server) handleRebuyWine(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid wine ID", err)
+		return
+	}
+
+	var req struct {
+		Quantity int      `json:"quantity"`
+		Price    *float32 `json:"price"`
+		CellID   *int64   `json:"cell_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	wine, err := s.store.RebuyWine(r.Context(), id, req.Quantity, req.Price, req.CellID, overflowAllowed(r))
+	if err != nil {
+		s.respondStoreError(w, "Failed to re-buy wine", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "wine", wine.ID, "wine_rebought", map[string]interface{}{"source_id": id, "qty": wine.Quantity}, s.getClientIP(r))
+
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(wine)
 }
 
 // handleSearchWines recherche des vins
 func (s *Server) handleSearchWines(w http.ResponseWriter, r *http.Request) {
 	filters := map[string]interface{}{
+		"q":      r.URL.Query().Get("q"),
 		"name":   r.URL.Query().Get("name"),
 		"region": r.URL.Query().Get("region"),
 		"type":   r.URL.Query().Get("type"),
+		"grape":  r.URL.Query().Get("grape"),
+	}
+
+	if r.URL.Query().Get("include") == "finished" {
+		filters["include_finished"] = true
 	}
 
 	if vintage := r.URL.Query().Get("vintage"); vintage != "" {
@@ -651,12 +959,19 @@ func (s *Server) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	alerts, err := s.store.GetAlerts(r.Context())
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid list parameters", err)
+		return
+	}
+
+	alerts, page, err := s.store.ListAlerts(r.Context(), opts)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "Failed to fetch alerts", err)
+		s.respondStoreError(w, "Failed to fetch alerts", err)
 		return
 	}
 
+	writePageHeaders(w, r, page, opts.Limit)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(alerts)
 }
@@ -686,8 +1001,7 @@ func (s *Server) handleCreateAlert(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Valider le type d'alerte
-	validTypes := map[string]bool{"low_stock": true, "apogee_reached": true, "apogee_ended": true}
-	if !validTypes[alert.AlertType] {
+	if !validAlertTypes[alert.AlertType] {
 		s.respondError(w, http.StatusBadRequest, "Invalid alert_type", nil)
 		return
 	}
@@ -784,7 +1098,7 @@ func (s *Server) handleRecordConsumption(w http.ResponseWriter, r *http.Request)
 
 	id, err := s.store.RecordConsumption(r.Context(), &consumption)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "Failed to record consumption", err)
+		s.respondStoreError(w, "Failed to record consumption", err)
 		return
 	}
 
@@ -792,7 +1106,7 @@ func (s *Server) handleRecordConsumption(w http.ResponseWriter, r *http.Request)
 	consumption.CreatedAt = time.Now()
 
 	// Audit
-	s.store.LogActivity(r.Context(), "consumption", consumption.ID, "consumption_recorded", map[string]interface{}{"wine_id": consumption.WineID, "qty": consumption.Quantity}, s.getClientIP(r))
+	s.store.LogActivity(r.Context(), "consumption", consumption.ID, "consumption_recorded", map[string]interface{}{"wine_id": consumption.WineID, "qty": consumption.Quantity, "volume_ml": consumption.VolumeML}, s.getClientIP(r))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -803,6 +1117,11 @@ func main() {
 	// Charger la configuration
 	config := LoadConfig()
 
+	// Sous-commandes CLI (migrate, ...)
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(config, os.Args[1:]))
+	}
+
 	// Valider la configuration
 	if err := config.Validate(); err != nil {
 		log.Fatalf("Configuration error: %v", err)
@@ -849,9 +1168,34 @@ func main() {
 
 	log.Println("Alert generator started (interval: 1 hour)")
 
+	// Purger la corbeille selon la durée de rétention (toutes les 6 heures)
+	trashPurger := store.NewTrashPurger(s)
+	trashPurger.Start(6 * time.Hour)
+	defer trashPurger.Stop()
+
+	// Sauvegardes planifiées (VACUUM INTO + rotation)
+	backups := store.NewBackupManager(s, config.BackupDir, config.backupPolicy())
+	if config.BackupInterval > 0 {
+		backupScheduler := store.NewBackupScheduler(backups)
+		backupScheduler.Start(config.BackupInterval)
+		defer backupScheduler.Stop()
+		log.Printf("Backup scheduler started (interval: %s, dir: %s)", config.BackupInterval, config.BackupDir)
+	}
+
+	// Réplication continue du WAL vers REPLICA_DIR
+	var replicator *store.Replicator
+	if config.ReplicaDir != "" {
+		replicator = store.NewReplicator(s, config.DBPath, config.ReplicaDir, config.replicaPolicy())
+		replicator.Start(config.ReplicaSyncInterval)
+		defer replicator.Stop()
+		log.Printf("Replication started (interval: %s, dir: %s)", config.ReplicaSyncInterval, config.ReplicaDir)
+	}
+
 	// Créer et démarrer le serveur avec configuration de sécurité
 	server := NewServer(s, config)
 	server.notifierManager = nm
+	server.backups = backups
+	server.replicator = replicator
 	addr := ":" + config.Port
 	if err := server.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Server error: %v", err)
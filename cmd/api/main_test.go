@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+func TestPatchWineVolumeOnly(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "glou.db"))
+	if err != nil {
+		t.Fatalf("failed to open test store: %v", err)
+	}
+	defer st.Close()
+	s := &Server{store: st, config: &Config{}}
+
+	ctx := context.Background()
+	id, err := st.CreateWine(ctx, &domain.Wine{Name: "Margaux", BottleType: "red_wine", Region: "Bordeaux", Vintage: 2015, Quantity: 1}, false)
+	if err != nil {
+		t.Fatalf("CreateWine: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/wines/"+strconv.FormatInt(id, 10), strings.NewReader(`{"volume_ml": 1500}`))
+	req.Header.Set("Content-Type", mergePatchContentType)
+	req.SetPathValue("id", strconv.FormatInt(id, 10))
+	rec := httptest.NewRecorder()
+	s.handlePatchWine(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	wine, err := st.GetWineByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetWineByID: %v", err)
+	}
+	if wine.VolumeML != 1500 || wine.Format != "magnum" {
+		t.Errorf("expected a 1500 ml magnum, got %d ml %s", wine.VolumeML, wine.Format)
+	}
+
+	entries, err := st.GetActivityLogForEntity(ctx, "wine", id)
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("expected an activity entry, got %v", err)
+	}
+	var details struct {
+		Fields []string `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(entries[0].Details), &details); err != nil {
+		t.Fatalf("failed to decode activity details: %v", err)
+	}
+	if strings.Join(details.Fields, ",") != "format,volume_ml" {
+		t.Errorf("expected format and volume_ml to be logged, got %v", details.Fields)
+	}
+}
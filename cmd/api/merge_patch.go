@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/romain/glou-server/internal/store"
+)
+
+// mergePatchContentType est le type MIME des patchs RFC 7396
+const mergePatchContentType = "application/merge-patch+json"
+
+// maxPatchAttempts borne les relectures quand un PATCH sans If-Match croise une autre écriture
+const maxPatchAttempts = 3
+
+// fieldChange décrit la modification d'un champ, pour le journal d'activité
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// decodeMergePatch lit le corps d'une requête PATCH.
+// Le patch doit être un objet JSON (application/merge-patch+json ou application/json).
+func decodeMergePatch(r *http.Request) (map[string]interface{}, error) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != mergePatchContentType && mediaType != "application/json") {
+			return nil, errUnsupportedPatchType
+		}
+	}
+
+	var patch map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		return nil, store.NewValidationError("body", "merge patch must be a JSON object")
+	}
+	return patch, nil
+}
+
+// errUnsupportedPatchType signale un Content-Type de patch non supporté (415)
+var errUnsupportedPatchType = fmt.Errorf("PATCH requires Content-Type %s", mergePatchContentType)
+
+// mergePatch applique récursivement un patch RFC 7396 à un document JSON :
+// null supprime le membre, un objet est fusionné, toute autre valeur remplace.
+func mergePatch(doc map[string]interface{}, patch map[string]interface{}) map[string]interface{} {
+	if doc == nil {
+		doc = make(map[string]interface{})
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(doc, key)
+			continue
+		}
+		if patchObj, ok := value.(map[string]interface{}); ok {
+			docObj, _ := doc[key].(map[string]interface{})
+			doc[key] = mergePatch(docObj, patchObj)
+			continue
+		}
+		doc[key] = value
+	}
+	return doc
+}
+
+// toJSONMap convertit une valeur en document JSON générique
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// applyMergePatch applique patch à target (pointeur vers une struct JSON).
+// Les champs readOnly et les champs inconnus sont refusés ; target est entièrement
+// reconstruit à partir du document fusionné, de sorte qu'un null efface bien la valeur.
+// Retourne les champs réellement modifiés.
+func applyMergePatch(target interface{}, patch map[string]interface{}, readOnly ...string) (map[string]fieldChange, error) {
+	verr := &store.ValidationError{}
+	for _, field := range readOnly {
+		if _, ok := patch[field]; ok {
+			verr.Add(field, field+" is read-only")
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	before, err := toJSONMap(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode current resource: %w", err)
+	}
+	original, _ := toJSONMap(target) // copie intacte pour le calcul des changements
+
+	merged, err := json.Marshal(mergePatch(before, patch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merged resource: %w", err)
+	}
+
+	value := reflect.ValueOf(target).Elem()
+	value.Set(reflect.Zero(value.Type()))
+
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return nil, patchDecodeError(err)
+	}
+
+	after, err := toJSONMap(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode patched resource: %w", err)
+	}
+
+	changes := make(map[string]fieldChange)
+	for field := range patch {
+		if !reflect.DeepEqual(original[field], after[field]) {
+			changes[field] = fieldChange{From: original[field], To: after[field]}
+		}
+	}
+	return changes, nil
+}
+
+// patchDecodeError traduit une erreur de décodage JSON en erreur de validation par champ
+func patchDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return store.NewValidationError(typeErr.Field, fmt.Sprintf("%s must be %s", typeErr.Field, jsonTypeName(typeErr.Type)))
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return store.NewValidationError(field, "unknown field "+field)
+	}
+	return store.NewValidationError("body", err.Error())
+}
+
+// jsonTypeName décrit le type JSON attendu pour un type Go
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	if t.String() == "time.Time" {
+		return "an RFC 3339 date"
+	}
+	return "an object"
+}
+
+// patchedFieldsOnly ne conserve, d'une erreur de validation, que les champs présents dans le patch :
+// un PATCH ne doit pas échouer à cause d'une donnée existante qu'il ne touche pas.
+func patchedFieldsOnly(err error, patch map[string]interface{}) error {
+	var verr *store.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	kept := &store.ValidationError{}
+	for _, f := range verr.Fields {
+		// "layout.zones[0].name" relève du membre "layout"
+		member, _, _ := strings.Cut(f.Field, ".")
+		member, _, _ = strings.Cut(member, "[")
+		if _, ok := patch[member]; ok {
+			kept.Add(f.Field, f.Message)
+		}
+	}
+	return kept.Err()
+}
+
+// changedFields retourne la liste triée des champs modifiés
+func changedFields(changes map[string]fieldChange) []string {
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// respondPatchError traite les erreurs propres au PATCH (415) puis délègue à respondStoreError
+func (s *Server) respondPatchError(w http.ResponseWriter, message string, err error) {
+	if errors.Is(err, errUnsupportedPatchType) {
+		s.respondError(w, http.StatusUnsupportedMediaType, err.Error(), nil)
+		return
+	}
+	s.respondStoreError(w, message, err)
+}
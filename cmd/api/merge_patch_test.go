@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/romain/glou-server/internal/store"
+)
+
+type patchTarget struct {
+	ID      int64             `json:"id"`
+	Name    string            `json:"name"`
+	Rating  *float64          `json:"rating"`
+	Details map[string]string `json:"details"`
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	rating := 4.5
+	target := patchTarget{ID: 1, Name: "Margaux", Rating: &rating, Details: map[string]string{"grape": "merlot", "oak": "new"}}
+	patch := map[string]interface{}{
+		"name":    "Margaux",
+		"rating":  nil,
+		"details": map[string]interface{}{"oak": nil, "soil": "gravel"},
+	}
+
+	changes, err := applyMergePatch(&target, patch, "id")
+	if err != nil {
+		t.Fatalf("applyMergePatch: %v", err)
+	}
+	if target.Rating != nil {
+		t.Errorf("expected null to clear rating, got %v", *target.Rating)
+	}
+	if len(target.Details) != 2 || target.Details["grape"] != "merlot" || target.Details["soil"] != "gravel" {
+		t.Errorf("expected details to be merged, got %v", target.Details)
+	}
+	if target.ID != 1 || target.Name != "Margaux" {
+		t.Errorf("expected untouched fields to be kept, got %+v", target)
+	}
+	if fields := changedFields(changes); len(fields) != 2 || fields[0] != "details" || fields[1] != "rating" {
+		t.Errorf("expected changes on [details rating], got %v", fields)
+	}
+}
+
+func TestApplyMergePatchRejectsReadOnlyAndUnknownFields(t *testing.T) {
+	target := patchTarget{ID: 1}
+
+	var verr *store.ValidationError
+	_, err := applyMergePatch(&target, map[string]interface{}{"id": 2}, "id")
+	if !errors.As(err, &verr) || verr.Fields[0].Field != "id" {
+		t.Errorf("expected a validation error on id, got %v", err)
+	}
+	_, err = applyMergePatch(&target, map[string]interface{}{"colour": "red"}, "id")
+	if !errors.As(err, &verr) || verr.Fields[0].Field != "colour" {
+		t.Errorf("expected a validation error on colour, got %v", err)
+	}
+}
@@ -89,8 +89,9 @@ func (s *Server) secureCorMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// Vérifier l'origine
 		if origin != "" && s.config.IsOriginAllowed(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token, If-Match")
+			w.Header().Set("Access-Control-Expose-Headers", "Link, X-Next-Cursor, ETag")
 			w.Header().Set("Access-Control-Max-Age", "3600")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Add("Vary", "Origin")
@@ -221,7 +222,7 @@ func (s *Server) setupCheckMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		isComplete, err := s.store.IsSetupComplete(r.Context())
 		if err != nil {
 			log.Printf("Error checking setup status: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			s.respondError(w, http.StatusInternalServerError, "Internal server error", err)
 			return
 		}
 
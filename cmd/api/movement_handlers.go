@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// handleMoveWine déplace tout ou partie d'un vin vers une autre cave ou un autre emplacement
+// (If-Match optionnel ; un déplacement partiel scinde l'entrée, unit_id déplace une seule bouteille)
+func (s *Server) handleMoveWine(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid wine ID", err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
+	var req struct {
+		Quantity int    `json:"quantity"` // 0 : toute l'entrée
+		UnitID   *int64 `json:"unit_id"`  // Bouteille précise, à la place de quantity
+		CaveID   *int64 `json:"cave_id"`
+		CellID   *int64 `json:"cell_id"`
+		Note     string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if len(req.Note) > 500 {
+		s.respondStoreError(w, "Invalid move", store.NewValidationError("note", "note too long (max 500 characters)"))
+		return
+	}
+
+	var result *domain.MoveResult
+	if req.UnitID != nil {
+		if req.Quantity > 1 {
+			s.respondStoreError(w, "Invalid move", store.NewValidationError("quantity", "a bottle unit move has quantity 1"))
+			return
+		}
+		result, err = s.store.MoveUnit(r.Context(), id, *req.UnitID, req.CaveID, req.CellID, req.Note, version, overflowAllowed(r))
+	} else {
+		result, err = s.store.MoveWine(r.Context(), id, req.Quantity, req.CaveID, req.CellID, req.Note, version, overflowAllowed(r))
+	}
+	if err != nil {
+		s.respondWriteError(w, "Failed to move wine", err, s.currentWine(r, id))
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "wine", id, "wine_moved", map[string]interface{}{
+		"qty":         result.Movement.Quantity,
+		"target_id":   result.Target.ID,
+		"to_cave_id":  result.Movement.ToCaveID,
+		"to_cell_id":  result.Movement.ToCellID,
+		"movement_id": result.Movement.ID,
+		"unit_id":     result.Movement.UnitID,
+	}, s.getClientIP(r))
+
+	setETag(w, result.Source.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleGetWineMovements retourne l'historique des déplacements d'un vin
+func (s *Server) handleGetWineMovements(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid wine ID", err)
+		return
+	}
+
+	movements, err := s.store.GetWineMovements(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch wine movements", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movements)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/romain/glou-server/internal/store"
+)
+
+// maxPageSize borne le paramètre limit des listes
+const maxPageSize = 500
+
+// parseListOptions lit limit, cursor, sort et les filtres de la query string.
+// Sans limit ni cursor, la liste est renvoyée en entier (compatibilité) ;
+// avec un cursor seul, la taille de page vient de Settings.RowsPerPage.
+func (s *Server) parseListOptions(r *http.Request) (store.ListOptions, error) {
+	query := r.URL.Query()
+	opts := store.ListOptions{
+		Cursor: query.Get("cursor"),
+		Sort:   query.Get("sort"),
+	}
+	verr := &store.ValidationError{}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			verr.Add("limit", fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+		}
+		opts.Limit = limit
+	} else if opts.Cursor != "" {
+		opts.Limit = s.defaultPageSize(r)
+	}
+
+	parseInt := func(name string) *int {
+		raw := query.Get(name)
+		if raw == "" {
+			return nil
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			verr.Add(name, name+" must be an integer")
+			return nil
+		}
+		return &v
+	}
+	parseInt64 := func(name string) *int64 {
+		raw := query.Get(name)
+		if raw == "" {
+			return nil
+		}
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			verr.Add(name, name+" must be an integer")
+			return nil
+		}
+		return &v
+	}
+	parseFloat := func(name string) *float64 {
+		raw := query.Get(name)
+		if raw == "" {
+			return nil
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			verr.Add(name, name+" must be a number")
+			return nil
+		}
+		return &v
+	}
+
+	// include=finished affiche aussi les vins terminés (archivés)
+	includeFinished := false
+	for _, include := range strings.Split(query.Get("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "":
+		case "finished":
+			includeFinished = true
+		default:
+			verr.Add("include", fmt.Sprintf("unknown include value %q", include))
+		}
+	}
+
+	opts.Filters = store.ListFilters{
+		VintageMin: parseInt("vintage_min"),
+		VintageMax: parseInt("vintage_max"),
+		PriceMin:   parseFloat("price_min"),
+		PriceMax:   parseFloat("price_max"),
+		RatingMin:  parseFloat("rating_min"),
+		CaveID:     parseInt64("cave_id"),
+		CellID:     parseInt64("cell_id"),
+
+		IncludeFinished: includeFinished,
+	}
+
+	return opts, verr.Err()
+}
+
+// defaultPageSize retourne Settings.RowsPerPage (10 si indisponible)
+func (s *Server) defaultPageSize(r *http.Request) int {
+	settings, err := s.store.GetSettings(r.Context())
+	if err != nil || settings.RowsPerPage <= 0 {
+		return 10
+	}
+	if settings.RowsPerPage > maxPageSize {
+		return maxPageSize
+	}
+	return settings.RowsPerPage
+}
+
+// writePageHeaders expose la page suivante via les en-têtes Link (rel="next") et X-Next-Cursor
+func writePageHeaders(w http.ResponseWriter, r *http.Request, page *store.PageInfo, limit int) {
+	if page == nil || !page.HasMore {
+		return
+	}
+
+	next := *r.URL
+	query := next.Query()
+	query.Set("cursor", page.NextCursor)
+	query.Set("limit", strconv.Itoa(limit))
+	next.RawQuery = query.Encode()
+
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	w.Header().Set("X-Next-Cursor", page.NextCursor)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// placementRequest désigne le vin à ranger : un vin existant (wine_id) ou à créer (wine)
+type placementRequest struct {
+	WineID     *int64             `json:"wine_id"`
+	Wine       *domain.Wine       `json:"wine"`
+	Quantity   int                `json:"quantity"`   // Plan : bouteilles à ranger (défaut : quantité du vin)
+	Placements []domain.Placement `json:"placements"` // Application : emplacements retenus
+}
+
+// maxPlacementQuantity borne le nombre de bouteilles d'un plan
+const maxPlacementQuantity = 1000
+
+// handlePlanPlacement propose un plan de rangement classé pour des bouteilles entrantes
+func (s *Server) handlePlanPlacement(w http.ResponseWriter, r *http.Request) {
+	caveID, err := strconv.ParseInt(r.PathValue("caveID"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cave ID", err)
+		return
+	}
+
+	var req placementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	var wine *domain.Wine
+	switch {
+	case req.WineID != nil:
+		wine, err = s.store.GetWineByID(r.Context(), *req.WineID)
+		if err != nil {
+			s.respondStoreError(w, "Failed to fetch wine", err)
+			return
+		}
+	case req.Wine != nil:
+		wine = req.Wine
+		// Seuls le type et la fenêtre de consommation comptent ici
+		requestedType := wine.BottleType
+		if requestedType == "" {
+			requestedType = wine.WineType
+		}
+		if bottleType, ok := domain.NormalizeBottleType(requestedType); ok {
+			wine.BottleType = bottleType
+		}
+		wine.ID = 0
+	default:
+		s.respondStoreError(w, "Invalid placement request", store.NewValidationError("wine_id", "wine_id or wine is required"))
+		return
+	}
+
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = wine.Quantity
+	}
+	if quantity <= 0 || quantity > maxPlacementQuantity {
+		s.respondStoreError(w, "Invalid placement request",
+			store.NewValidationError("quantity", "quantity must be between 1 and "+strconv.Itoa(maxPlacementQuantity)))
+		return
+	}
+
+	plan, err := s.store.PlanPlacement(r.Context(), caveID, wine, quantity)
+	if err != nil {
+		s.respondStoreError(w, "Failed to plan placement", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// handleApplyPlacement applique un plan de rangement en un seul appel : range un vin
+// existant (wine_id) ou crée le vin (wine) dans les emplacements retenus
+func (s *Server) handleApplyPlacement(w http.ResponseWriter, r *http.Request) {
+	caveID, err := strconv.ParseInt(r.PathValue("caveID"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid cave ID", err)
+		return
+	}
+
+	var req placementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	var wines []*domain.Wine
+	status := http.StatusOK
+	switch {
+	case req.WineID != nil:
+		wines, err = s.store.PlaceWine(r.Context(), caveID, *req.WineID, req.Placements, overflowAllowed(r))
+		if err != nil {
+			s.respondStoreError(w, "Failed to apply placement", err)
+			return
+		}
+		for _, wine := range wines {
+			s.store.LogActivity(r.Context(), "wine", wine.ID, "wine_placed", map[string]interface{}{"source_id": *req.WineID, "cell_id": wine.CellID, "qty": wine.Quantity}, s.getClientIP(r))
+		}
+	case req.Wine != nil:
+		// La quantité vient du plan
+		total := 0
+		for _, p := range req.Placements {
+			total += p.Quantity
+		}
+		req.Wine.Quantity = total
+		if err := ValidateWine(req.Wine); err != nil {
+			s.respondStoreError(w, "Invalid wine", err)
+			return
+		}
+		wines, err = s.store.CreatePlacedWine(r.Context(), caveID, req.Wine, req.Placements, overflowAllowed(r))
+		if err != nil {
+			s.respondStoreError(w, "Failed to apply placement", err)
+			return
+		}
+		for _, wine := range wines {
+			s.store.LogActivity(r.Context(), "wine", wine.ID, "wine_created", map[string]interface{}{"name": wine.Name, "region": wine.Region, "vintage": wine.Vintage, "cell_id": wine.CellID, "qty": wine.Quantity}, s.getClientIP(r))
+		}
+		status = http.StatusCreated
+	default:
+		s.respondStoreError(w, "Invalid placement request", store.NewValidationError("wine_id", "wine_id or wine is required"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(wines)
+}
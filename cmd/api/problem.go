@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/romain/glou-server/internal/store"
+)
+
+// Problem est une réponse d'erreur RFC 7807 (application/problem+json)
+type Problem struct {
+	Type   string             `json:"type"`
+	Title  string             `json:"title"`
+	Status int                `json:"status"`
+	Detail string             `json:"detail,omitempty"`
+	Code   string             `json:"code"`
+	Errors []store.FieldError `json:"errors,omitempty"`
+	// Current contient la représentation courante en cas de conflit de version (412)
+	Current interface{} `json:"current,omitempty"`
+	// Error reprend Detail pour les clients qui lisent encore le champ "error"
+	Error string `json:"error"`
+}
+
+// problemCodes associe un code machine stable à chaque statut HTTP
+var problemCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusPreconditionFailed:    "precondition_failed",
+	http.StatusRequestEntityTooLarge: "payload_too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_media_type",
+	http.StatusUnprocessableEntity:   "validation_failed",
+	http.StatusTooManyRequests:       "rate_limited",
+	http.StatusInternalServerError:   "internal_error",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+// newProblem construit un Problem à partir d'un statut et d'un message
+func newProblem(status int, code, detail string) *Problem {
+	if code == "" {
+		code = problemCodes[status]
+		if code == "" {
+			code = "error"
+		}
+	}
+	return &Problem{
+		Type:   "urn:glou:problem:" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Error:  detail,
+	}
+}
+
+// writeProblem écrit un Problem avec le Content-Type RFC 7807
+func writeProblem(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// respondProblem écrit une erreur problem+json sans passer par le Server
+// (handlers d'enrichissement qui ne sont pas des méthodes)
+func respondProblem(w http.ResponseWriter, status int, message string) {
+	writeProblem(w, newProblem(status, "", message))
+}
+
+// respondError écrit une réponse d'erreur problem+json et journalise la cause
+func (s *Server) respondError(w http.ResponseWriter, statusCode int, message string, err error) {
+	logMessage := message
+	if err != nil {
+		logMessage = fmt.Sprintf("%s: %v", message, err)
+	}
+	log.Printf("[ERROR] %s", logMessage)
+
+	writeProblem(w, newProblem(statusCode, "", message))
+}
+
+// respondStoreError traduit une erreur du store en statut HTTP :
+// ErrNotFound → 404, ErrConflict → 409, ErrPreconditionFailed → 412,
+// ErrValidation → 400 avec le détail par champ.
+// message est utilisé pour les erreurs internes.
+func (s *Server) respondStoreError(w http.ResponseWriter, message string, err error) {
+	var validationErr *store.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		log.Printf("[ERROR] %s: %v", message, err)
+		p := newProblem(http.StatusBadRequest, "validation_failed", validationErr.Error())
+		p.Errors = validationErr.Fields
+		writeProblem(w, p)
+	case errors.Is(err, store.ErrNotFound):
+		s.respondError(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, store.ErrConflict):
+		s.respondError(w, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, store.ErrPreconditionFailed):
+		s.respondError(w, http.StatusPreconditionFailed, err.Error(), nil)
+	default:
+		s.respondError(w, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// Bornes des suggestions de producteurs
+const (
+	defaultProducerMatches = 5
+	maxProducerMatches     = 20
+)
+
+// ValidateProducer valide un producteur avant enregistrement
+func ValidateProducer(p *domain.Producer) error {
+	verr := &store.ValidationError{}
+	p.Name = strings.TrimSpace(p.Name)
+	if domain.NormalizeProducerName(p.Name) == "" {
+		verr.Add("name", "producer name is required")
+	} else if len(p.Name) > 255 {
+		verr.Add("name", "producer name too long (max 255 characters)")
+	}
+	if len(p.Aliases) > 50 {
+		verr.Add("aliases", "too many aliases (max 50)")
+	}
+	for i, alias := range p.Aliases {
+		if len(alias) > 255 {
+			verr.Add(fmt.Sprintf("aliases[%d]", i), "alias too long (max 255 characters)")
+		}
+	}
+	for _, f := range []struct {
+		name  string
+		value string
+		max   int
+	}{{"country", p.Country, 100}, {"region", p.Region, 100}, {"website", p.Website, 255}, {"notes", p.Notes, 2000}} {
+		if len(f.value) > f.max {
+			verr.Add(f.name, f.name+" too long (max "+strconv.Itoa(f.max)+" characters)")
+		}
+	}
+	return verr.Err()
+}
+
+// producerSuggestions retourne les producteurs proches d'un nom saisi, sauf s'il est reconnu tel quel
+func (s *Server) producerSuggestions(r *http.Request, wine *domain.Wine) []*domain.ProducerMatch {
+	if wine.ProducerID != nil || strings.TrimSpace(wine.Producer) == "" {
+		return nil
+	}
+	matches, err := s.store.MatchProducers(r.Context(), wine.Producer, 3)
+	if err != nil || (len(matches) > 0 && matches[0].Score == 1) {
+		return nil
+	}
+	return matches
+}
+
+// handleGetProducers liste les producteurs
+func (s *Server) handleGetProducers(w http.ResponseWriter, r *http.Request) {
+	producers, err := s.store.GetProducers(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch producers", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(producers)
+}
+
+// handleMatchProducers suggère des producteurs existants proches d'un nom (?name=, ?limit=)
+func (s *Server) handleMatchProducers(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if strings.TrimSpace(name) == "" {
+		s.respondStoreError(w, "Invalid producer lookup", store.NewValidationError("name", "name is required"))
+		return
+	}
+	limit := defaultProducerMatches
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 || limit > maxProducerMatches {
+			s.respondStoreError(w, "Invalid producer lookup",
+				store.NewValidationError("limit", fmt.Sprintf("limit must be between 1 and %d", maxProducerMatches)))
+			return
+		}
+	}
+
+	matches, err := s.store.MatchProducers(r.Context(), name, limit)
+	if err != nil {
+		s.respondStoreError(w, "Failed to match producers", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matches)
+}
+
+// handleGetProducer retourne un producteur par son ID
+func (s *Server) handleGetProducer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid producer ID", err)
+		return
+	}
+
+	producer, err := s.store.GetProducer(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch producer", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(producer)
+}
+
+// handleCreateProducer crée un producteur
+func (s *Server) handleCreateProducer(w http.ResponseWriter, r *http.Request) {
+	var producer domain.Producer
+	if err := json.NewDecoder(r.Body).Decode(&producer); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := ValidateProducer(&producer); err != nil {
+		s.respondStoreError(w, "Invalid producer", err)
+		return
+	}
+
+	id, err := s.store.CreateProducer(r.Context(), &producer)
+	if err != nil {
+		s.respondStoreError(w, "Failed to create producer", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "producer", id, "producer_created", map[string]interface{}{"name": producer.Name}, s.getClientIP(r))
+
+	created, err := s.store.GetProducer(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch producer", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleUpdateProducer met à jour un producteur (son nom est reporté sur ses vins)
+func (s *Server) handleUpdateProducer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid producer ID", err)
+		return
+	}
+
+	var producer domain.Producer
+	if err := json.NewDecoder(r.Body).Decode(&producer); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	producer.ID = id
+	if err := ValidateProducer(&producer); err != nil {
+		s.respondStoreError(w, "Invalid producer", err)
+		return
+	}
+
+	if err := s.store.UpdateProducer(r.Context(), &producer); err != nil {
+		s.respondStoreError(w, "Failed to update producer", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "producer", id, "producer_updated", map[string]interface{}{"name": producer.Name}, s.getClientIP(r))
+
+	updated, err := s.store.GetProducer(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch producer", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// handleDeleteProducer supprime un producteur sans vin rattaché
+func (s *Server) handleDeleteProducer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid producer ID", err)
+		return
+	}
+
+	if err := s.store.DeleteProducer(r.Context(), id); err != nil {
+		s.respondStoreError(w, "Failed to delete producer", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "producer", id, "producer_deleted", nil, s.getClientIP(r))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMergeProducers fusionne des producteurs en doublon dans le producteur {id} (admin)
+func (s *Server) handleMergeProducers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid producer ID", err)
+		return
+	}
+
+	var req struct {
+		SourceIDs []int64 `json:"source_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	merged, err := s.store.MergeProducers(r.Context(), id, req.SourceIDs)
+	if err != nil {
+		s.respondStoreError(w, "Failed to merge producers", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "producer", id, "producers_merged", map[string]interface{}{"source_ids": req.SourceIDs, "name": merged.Name}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merged)
+}
+
+// handleGetProducerWines liste les vins d'un producteur
+func (s *Server) handleGetProducerWines(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid producer ID", err)
+		return
+	}
+
+	wines, err := s.store.GetProducerWines(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch producer wines", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wines)
+}
+
+// handleGetProducerConsumption retourne l'historique de dégustation des vins d'un producteur
+func (s *Server) handleGetProducerConsumption(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid producer ID", err)
+		return
+	}
+
+	history, err := s.store.GetProducerConsumption(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch producer consumption", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// currencyPattern : code devise ISO 4217 (ex. "EUR")
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ValidateMerchant valide un marchand avant enregistrement
+func ValidateMerchant(m *domain.Merchant) error {
+	verr := &store.ValidationError{}
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		verr.Add("name", "merchant name is required")
+	} else if len(m.Name) > 255 {
+		verr.Add("name", "merchant name too long (max 255 characters)")
+	}
+	if m.Email != "" {
+		if _, err := mail.ParseAddress(m.Email); err != nil {
+			verr.Add("email", "invalid email address")
+		}
+	}
+	for _, f := range []struct {
+		name  string
+		value string
+		max   int
+	}{{"website", m.Website, 255}, {"email", m.Email, 255}, {"phone", m.Phone, 50}, {"address", m.Address, 500}, {"notes", m.Notes, 2000}} {
+		if len(f.value) > f.max {
+			verr.Add(f.name, f.name+" too long (max "+strconv.Itoa(f.max)+" characters)")
+		}
+	}
+	return verr.Err()
+}
+
+// ValidatePurchaseLot valide un lot d'achat ; devise et date ont une valeur par défaut (EUR, aujourd'hui)
+func ValidatePurchaseLot(l *domain.PurchaseLot) error {
+	verr := &store.ValidationError{}
+	if l.Quantity <= 0 {
+		verr.Add("quantity", "quantity must be greater than 0")
+	}
+	if l.UnitPrice < 0 {
+		verr.Add("unit_price", "unit_price cannot be negative")
+	}
+	l.Currency = strings.ToUpper(strings.TrimSpace(l.Currency))
+	if l.Currency == "" {
+		l.Currency = "EUR"
+	} else if !currencyPattern.MatchString(l.Currency) {
+		verr.Add("currency", "currency must be a 3-letter ISO 4217 code")
+	}
+	if l.PurchaseDate.IsZero() {
+		l.PurchaseDate = time.Now()
+	} else if l.PurchaseDate.After(time.Now().Add(24 * time.Hour)) {
+		verr.Add("purchase_date", "purchase_date cannot be in the future")
+	}
+	if len(l.InvoiceRef) > 100 {
+		verr.Add("invoice_ref", "invoice_ref too long (max 100 characters)")
+	}
+	if len(l.Notes) > 1000 {
+		verr.Add("notes", "notes too long (max 1000 characters)")
+	}
+	return verr.Err()
+}
+
+// handleGetMerchants liste les marchands
+func (s *Server) handleGetMerchants(w http.ResponseWriter, r *http.Request) {
+	merchants, err := s.store.GetMerchants(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch merchants", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merchants)
+}
+
+// handleGetMerchant retourne un marchand par son ID
+func (s *Server) handleGetMerchant(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid merchant ID", err)
+		return
+	}
+
+	merchant, err := s.store.GetMerchant(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch merchant", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merchant)
+}
+
+// handleCreateMerchant crée un marchand
+func (s *Server) handleCreateMerchant(w http.ResponseWriter, r *http.Request) {
+	var merchant domain.Merchant
+	if err := json.NewDecoder(r.Body).Decode(&merchant); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := ValidateMerchant(&merchant); err != nil {
+		s.respondStoreError(w, "Invalid merchant", err)
+		return
+	}
+
+	id, err := s.store.CreateMerchant(r.Context(), &merchant)
+	if err != nil {
+		s.respondStoreError(w, "Failed to create merchant", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "merchant", id, "merchant_created", map[string]interface{}{"name": merchant.Name}, s.getClientIP(r))
+
+	created, err := s.store.GetMerchant(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch merchant", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleUpdateMerchant met à jour un marchand
+func (s *Server) handleUpdateMerchant(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid merchant ID", err)
+		return
+	}
+
+	var merchant domain.Merchant
+	if err := json.NewDecoder(r.Body).Decode(&merchant); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	merchant.ID = id
+	if err := ValidateMerchant(&merchant); err != nil {
+		s.respondStoreError(w, "Invalid merchant", err)
+		return
+	}
+
+	if err := s.store.UpdateMerchant(r.Context(), &merchant); err != nil {
+		s.respondStoreError(w, "Failed to update merchant", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "merchant", id, "merchant_updated", map[string]interface{}{"name": merchant.Name}, s.getClientIP(r))
+
+	updated, err := s.store.GetMerchant(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch merchant", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// handleDeleteMerchant supprime un marchand sans achat enregistré
+func (s *Server) handleDeleteMerchant(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid merchant ID", err)
+		return
+	}
+
+	if err := s.store.DeleteMerchant(r.Context(), id); err != nil {
+		s.respondStoreError(w, "Failed to delete merchant", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "merchant", id, "merchant_deleted", nil, s.getClientIP(r))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetMerchantPurchases retourne l'historique des achats faits chez un marchand
+func (s *Server) handleGetMerchantPurchases(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid merchant ID", err)
+		return
+	}
+
+	lots, err := s.store.GetMerchantPurchases(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch merchant purchases", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lots)
+}
+
+// handleGetWinePurchases retourne les lots d'achat d'un vin avec leurs coûts
+func (s *Server) handleGetWinePurchases(w http.ResponseWriter, r *http.Request) {
+	s.getPurchaseHistory(w, r, domain.PurchaseItemWine)
+}
+
+// handleCreateWinePurchase enregistre un lot d'achat pour un vin
+func (s *Server) handleCreateWinePurchase(w http.ResponseWriter, r *http.Request) {
+	s.createPurchaseLot(w, r, domain.PurchaseItemWine)
+}
+
+// handleGetTobaccoPurchases retourne les lots d'achat d'un tabac avec leurs coûts
+func (s *Server) handleGetTobaccoPurchases(w http.ResponseWriter, r *http.Request) {
+	s.getPurchaseHistory(w, r, domain.PurchaseItemTobacco)
+}
+
+// handleCreateTobaccoPurchase enregistre un lot d'achat pour un tabac
+func (s *Server) handleCreateTobaccoPurchase(w http.ResponseWriter, r *http.Request) {
+	s.createPurchaseLot(w, r, domain.PurchaseItemTobacco)
+}
+
+// getPurchaseHistory répond avec l'historique d'achat de l'article {id}
+func (s *Server) getPurchaseHistory(w http.ResponseWriter, r *http.Request, itemType string) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid "+itemType+" ID", err)
+		return
+	}
+
+	history, err := s.store.GetPurchaseHistory(r.Context(), itemType, id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch purchases", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// createPurchaseLot enregistre un lot d'achat pour l'article {id}
+func (s *Server) createPurchaseLot(w http.ResponseWriter, r *http.Request, itemType string) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid "+itemType+" ID", err)
+		return
+	}
+
+	var lot domain.PurchaseLot
+	if err := json.NewDecoder(r.Body).Decode(&lot); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	lot.ItemType = itemType
+	lot.ItemID = id
+	if err := ValidatePurchaseLot(&lot); err != nil {
+		s.respondStoreError(w, "Invalid purchase lot", err)
+		return
+	}
+
+	lotID, err := s.store.CreatePurchaseLot(r.Context(), &lot)
+	if err != nil {
+		s.respondStoreError(w, "Failed to create purchase lot", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), itemType, id, "purchase_added", map[string]interface{}{"lot_id": lotID, "qty": lot.Quantity, "unit_price": lot.UnitPrice, "currency": lot.Currency, "merchant_id": lot.MerchantID}, s.getClientIP(r))
+
+	created, err := s.store.GetPurchaseLot(r.Context(), lotID)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch purchase lot", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleUpdatePurchaseLot met à jour un lot d'achat
+func (s *Server) handleUpdatePurchaseLot(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid purchase lot ID", err)
+		return
+	}
+
+	var lot domain.PurchaseLot
+	if err := json.NewDecoder(r.Body).Decode(&lot); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	lot.ID = id
+	if err := ValidatePurchaseLot(&lot); err != nil {
+		s.respondStoreError(w, "Invalid purchase lot", err)
+		return
+	}
+
+	if err := s.store.UpdatePurchaseLot(r.Context(), &lot); err != nil {
+		s.respondStoreError(w, "Failed to update purchase lot", err)
+		return
+	}
+
+	updated, err := s.store.GetPurchaseLot(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch purchase lot", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), updated.ItemType, updated.ItemID, "purchase_updated", map[string]interface{}{"lot_id": id, "qty": updated.Quantity, "unit_price": updated.UnitPrice, "currency": updated.Currency}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// handleDeletePurchaseLot supprime un lot d'achat
+func (s *Server) handleDeletePurchaseLot(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid purchase lot ID", err)
+		return
+	}
+
+	lot, err := s.store.GetPurchaseLot(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch purchase lot", err)
+		return
+	}
+	if err := s.store.DeletePurchaseLot(r.Context(), id); err != nil {
+		s.respondStoreError(w, "Failed to delete purchase lot", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), lot.ItemType, lot.ItemID, "purchase_deleted", map[string]interface{}{"lot_id": id}, s.getClientIP(r))
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// handleSearch effectue une recherche plein texte sur les vins, tabacs et
+// commentaires de dégustation : GET /search?q=...&limit=...
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		s.respondError(w, http.StatusBadRequest, "Query parameter q is required", nil)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 || l > maxSearchLimit {
+			s.respondError(w, http.StatusBadRequest, "limit must be between 1 and 100", err)
+			return
+		}
+		limit = l
+	}
+
+	results, err := s.store.Search(r.Context(), q, limit)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to search", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   q,
+		"results": results,
+		"count":   len(results),
+	})
+}
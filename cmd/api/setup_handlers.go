@@ -53,14 +53,14 @@ func (s *Server) handleCheckSetup(w http.ResponseWriter, r *http.Request) {
 	// Vérifier si le setup est déjà complété
 	isComplete, err := s.store.IsSetupComplete(ctx)
 	if err != nil {
-		http.Error(w, "Failed to check setup status", http.StatusInternalServerError)
+		s.respondError(w, http.StatusInternalServerError, "Failed to check setup status", err)
 		return
 	}
 
 	// Vérifier si un admin existe
 	hasAdmin, err := s.store.HasAdminUser(ctx)
 	if err != nil {
-		http.Error(w, "Failed to check admin user", http.StatusInternalServerError)
+		s.respondError(w, http.StatusInternalServerError, "Failed to check admin user", err)
 		return
 	}
 
@@ -81,7 +81,7 @@ func (s *Server) handleCheckSMTPConfig(w http.ResponseWriter, r *http.Request) {
 	// Récupérer les paramètres
 	settings, err := s.store.GetSettings(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+		s.respondError(w, http.StatusInternalServerError, "Failed to get settings", err)
 		return
 	}
 
@@ -100,7 +100,7 @@ func (s *Server) handleSetupWizard(w http.ResponseWriter, r *http.Request) {
 	// Vérifier si le setup est déjà complété
 	isComplete, err := s.store.IsSetupComplete(ctx)
 	if err != nil {
-		http.Error(w, "Failed to check setup status", http.StatusInternalServerError)
+		s.respondError(w, http.StatusInternalServerError, "Failed to check setup status", err)
 		return
 	}
 
@@ -121,39 +121,39 @@ func (s *Server) handleCompleteSetup(w http.ResponseWriter, r *http.Request) {
 	// Vérifier si le setup est déjà complété
 	isComplete, err := s.store.IsSetupComplete(ctx)
 	if err != nil {
-		http.Error(w, "Failed to check setup status", http.StatusInternalServerError)
+		s.respondError(w, http.StatusInternalServerError, "Failed to check setup status", err)
 		return
 	}
 
 	if isComplete {
-		http.Error(w, "Setup already completed", http.StatusBadRequest)
+		s.respondError(w, http.StatusBadRequest, "Setup already completed", nil)
 		return
 	}
 
 	// Décoder la requête
 	var req SetupRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
 	// Valider les champs obligatoires
 	if err := validateSetupRequest(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.respondError(w, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
 
 	// Créer l'utilisateur admin
 	_, err = s.store.CreateUser(ctx, req.Username, req.Email, req.Password, "admin")
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create admin user: %v", err), http.StatusInternalServerError)
+		s.respondStoreError(w, "Failed to create admin user", err)
 		return
 	}
 
 	// Mettre à jour les paramètres
 	settings, err := s.store.GetSettings(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+		s.respondError(w, http.StatusInternalServerError, "Failed to get settings", err)
 		return
 	}
 
@@ -192,7 +192,7 @@ func (s *Server) handleCompleteSetup(w http.ResponseWriter, r *http.Request) {
 
 	// Sauvegarder les paramètres
 	if err := s.store.UpdateSettings(ctx, settings); err != nil {
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		s.respondError(w, http.StatusInternalServerError, "Failed to update settings", err)
 		return
 	}
 
@@ -218,7 +218,7 @@ func (s *Server) handleCompleteSetup(w http.ResponseWriter, r *http.Request) {
 
 	// Marquer le setup comme complété
 	if err := s.store.MarkSetupComplete(ctx); err != nil {
-		http.Error(w, "Failed to mark setup complete", http.StatusInternalServerError)
+		s.respondError(w, http.StatusInternalServerError, "Failed to mark setup complete", err)
 		return
 	}
 
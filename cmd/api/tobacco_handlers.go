@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// ValidateTobacco valide un produit tabac avant enregistrement
+func ValidateTobacco(t *domain.Tobacco) error {
+	verr := &store.ValidationError{}
+	if t.Name == "" {
+		verr.Add("name", "tobacco name is required")
+	} else if len(t.Name) > 255 {
+		verr.Add("name", "tobacco name too long (max 255 characters)")
+	}
+	if t.Quantity < 0 {
+		verr.Add("quantity", "quantity cannot be negative")
+	}
+	if t.PurchasePrice != nil && *t.PurchasePrice < 0 {
+		verr.Add("purchase_price", "purchase price cannot be negative")
+	}
+	if t.CurrentValue != nil && *t.CurrentValue < 0 {
+		verr.Add("current_value", "current value cannot be negative")
+	}
+	return verr.Err()
+}
+
+// handleGetTobacco retourne la liste des tabacs (filtres, tri et pagination par curseur)
+func (s *Server) handleGetTobacco(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid list parameters", err)
+		return
+	}
+
+	items, page, err := s.store.ListTobaccos(r.Context(), opts)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch tobacco", err)
+		return
+	}
+
+	writePageHeaders(w, r, page, opts.Limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// handleGetTobaccoByID retourne un tabac par son ID
+func (s *Server) handleGetTobaccoByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid tobacco ID", err)
+		return
+	}
+
+	item, err := s.store.GetTobaccoByID(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch tobacco", err)
+		return
+	}
+
+	setETag(w, item.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// handleCreateTobacco ajoute un tabac
+func (s *Server) handleCreateTobacco(w http.ResponseWriter, r *http.Request) {
+	var item domain.Tobacco
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := ValidateTobacco(&item); err != nil {
+		s.respondStoreError(w, "Invalid tobacco", err)
+		return
+	}
+
+	if item.Quantity <= 0 {
+		item.Quantity = 1
+	}
+
+	id, err := s.store.CreateTobacco(r.Context(), &item, overflowAllowed(r))
+	if err != nil {
+		s.respondStoreError(w, "Failed to create tobacco", err)
+		return
+	}
+
+	item.ID = id
+	item.CreatedAt = time.Now()
+
+	// Audit
+	s.store.LogActivity(r.Context(), "tobacco", item.ID, "tobacco_created", map[string]interface{}{"name": item.Name, "brand": item.Brand}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+// handleUpdateTobacco met à jour un tabac (If-Match optionnel : 412 si la version a changé)
+func (s *Server) handleUpdateTobacco(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid tobacco ID", err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
+	var item domain.Tobacco
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	item.ID = id
+	item.Version = version
+
+	if err := ValidateTobacco(&item); err != nil {
+		s.respondStoreError(w, "Invalid tobacco", err)
+		return
+	}
+
+	if err := s.store.UpdateTobacco(r.Context(), &item, overflowAllowed(r)); err != nil {
+		s.respondWriteError(w, "Failed to update tobacco", err, s.currentTobacco(r, id))
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "tobacco", item.ID, "tobacco_updated", map[string]interface{}{"name": item.Name, "brand": item.Brand}, s.getClientIP(r))
+
+	setETag(w, item.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// handlePatchTobacco applique un JSON Merge Patch (RFC 7396) à un tabac
+func (s *Server) handlePatchTobacco(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid tobacco ID", err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
+	patch, err := decodeMergePatch(r)
+	if err != nil {
+		s.respondPatchError(w, "Invalid merge patch", err)
+		return
+	}
+
+	var item *domain.Tobacco
+	var changes map[string]fieldChange
+	for attempt := 1; ; attempt++ {
+		item, err = s.store.GetTobaccoByID(r.Context(), id)
+		if err != nil {
+			s.respondStoreError(w, "Failed to fetch tobacco", err)
+			return
+		}
+		expected := item.Version
+		if version != 0 {
+			expected = version
+		}
+
+		changes, err = applyMergePatch(item, patch, "id", "created_at", "version")
+		if err == nil {
+			err = patchedFieldsOnly(ValidateTobacco(item), patch)
+		}
+		if err != nil {
+			s.respondStoreError(w, "Invalid tobacco", err)
+			return
+		}
+		if len(changes) == 0 {
+			// Rien à écrire : seule la précondition est vérifiée
+			if version != 0 && item.Version != version {
+				s.respondPreconditionFailed(w, store.StaleVersion("tobacco", id, item.Version), item, item.Version)
+				return
+			}
+			break
+		}
+
+		item.ID = id
+		item.Version = expected
+		err = s.store.UpdateTobacco(r.Context(), item, overflowAllowed(r))
+		if errors.Is(err, store.ErrPreconditionFailed) && version == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		if err != nil {
+			s.respondWriteError(w, "Failed to update tobacco", err, s.currentTobacco(r, id))
+			return
+		}
+		break
+	}
+
+	// Audit
+	if len(changes) > 0 {
+		s.store.LogActivity(r.Context(), "tobacco", id, "tobacco_patched", map[string]interface{}{"fields": changedFields(changes), "changes": changes}, s.getClientIP(r))
+	}
+
+	setETag(w, item.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// handleDeleteTobacco décrémente la quantité d'un tabac (la dernière unité part à la corbeille),
+// ou met le produit entier à la corbeille avec ?all=true. If-Match optionnel : 412 si la version a changé.
+func (s *Server) handleDeleteTobacco(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid tobacco ID", err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		s.respondStoreError(w, "Invalid If-Match header", err)
+		return
+	}
+
+	// ?all=true envoie le produit entier à la corbeille (restaurable)
+	if r.URL.Query().Get("all") == "true" {
+		if err := s.store.SoftDelete(r.Context(), store.TrashTobacco, id, version); err != nil {
+			s.respondWriteError(w, "Failed to delete tobacco", err, s.currentTobacco(r, id))
+			return
+		}
+		s.store.LogActivity(r.Context(), "tobacco", id, "tobacco_trashed", map[string]string{"id": idStr}, s.getClientIP(r))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := s.store.DeleteTobacco(r.Context(), id, version); err != nil {
+		s.respondWriteError(w, "Failed to delete tobacco", err, s.currentTobacco(r, id))
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "tobacco", id, "tobacco_deleted_or_decremented", map[string]string{"id": idStr}, s.getClientIP(r))
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/romain/glou-server/internal/store"
+)
+
+// handleGetTrash liste les éléments de la corbeille avec leur date de purge
+func (s *Server) handleGetTrash(w http.ResponseWriter, r *http.Request) {
+	items, err := s.store.ListTrash(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch trash", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// handleRestoreFromTrash restaure un élément de la corbeille (wine, tobacco, cave, cell)
+func (s *Server) handleRestoreFromTrash(w http.ResponseWriter, r *http.Request) {
+	entityType := r.PathValue("type")
+	if !store.IsTrashType(entityType) {
+		s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Unknown trash type %q", entityType), nil)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid ID", err)
+		return
+	}
+
+	if err := s.store.Restore(r.Context(), entityType, id); err != nil {
+		s.respondStoreError(w, "Failed to restore item", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), entityType, id, entityType+"_restored", map[string]string{"id": idStr}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"type": entityType, "id": id, "restored": true})
+}
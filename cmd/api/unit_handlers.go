@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+	"github.com/romain/glou-server/internal/store"
+)
+
+// ValidateUnit valide les attributs d'une bouteille
+func ValidateUnit(u *domain.BottleUnit) error {
+	verr := &store.ValidationError{}
+	if len(u.Lot) > 100 {
+		verr.Add("lot", "lot too long (max 100 characters)")
+	}
+	if len(u.Barcode) > 128 {
+		verr.Add("barcode", "barcode too long (max 128 characters)")
+	}
+	if u.FillLevel != "" && !slices.Contains(domain.FillLevels(), u.FillLevel) {
+		verr.Add("fill_level", "fill_level must be one of neck, top_shoulder, high_shoulder, mid_shoulder, low_shoulder")
+	}
+	validatePreservation(verr, u.Preservation)
+	if u.PurchasePrice != nil && *u.PurchasePrice < 0 {
+		verr.Add("purchase_price", "purchase_price cannot be negative")
+	}
+	// La consommation passe par POST /consumption (unit_id) pour garder l'historique
+	if u.State != domain.UnitStateInStock && u.State != domain.UnitStateOpened && u.State != domain.UnitStateGifted {
+		verr.Add("state", "state must be in_stock, opened or gifted; record consumption with POST /consumption")
+	}
+	return verr.Err()
+}
+
+// validatePreservation vérifie la méthode de conservation d'une bouteille ouverte
+func validatePreservation(verr *store.ValidationError, preservation string) {
+	if preservation != domain.PreservationNone && !slices.Contains(domain.Preservations(), preservation) {
+		verr.Add("preservation", "preservation must be one of "+strings.Join(domain.Preservations(), ", "))
+	}
+}
+
+// handleGetWineUnits liste les bouteilles d'un vin (?state= pour filtrer)
+func (s *Server) handleGetWineUnits(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid wine ID", err)
+		return
+	}
+
+	state := r.URL.Query().Get("state")
+	if state != "" && !slices.Contains(domain.UnitStates(), state) {
+		s.respondStoreError(w, "Invalid state filter", store.NewValidationError("state", "unknown bottle state"))
+		return
+	}
+
+	units, err := s.store.GetWineUnits(r.Context(), id, state)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch bottles", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(units)
+}
+
+// handleAddWineUnit ajoute une bouteille à un vin (rangée avec l'entrée sauf cave_id/cell_id)
+func (s *Server) handleAddWineUnit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid wine ID", err)
+		return
+	}
+
+	var unit domain.BottleUnit
+	if err := json.NewDecoder(r.Body).Decode(&unit); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	unit.State = domain.UnitStateInStock
+	if err := ValidateUnit(&unit); err != nil {
+		s.respondStoreError(w, "Invalid bottle", err)
+		return
+	}
+
+	created, err := s.store.AddUnit(r.Context(), id, &unit, overflowAllowed(r))
+	if err != nil {
+		s.respondStoreError(w, "Failed to add bottle", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "wine", id, "unit_added", map[string]interface{}{"unit_id": created.ID, "cell_id": created.CellID}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleGetUnit retourne une bouteille par son identifiant
+func (s *Server) handleGetUnit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid bottle ID", err)
+		return
+	}
+
+	unit, err := s.store.GetUnit(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch bottle", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(unit)
+}
+
+// handleFindUnit retrouve une bouteille par son code-barres ou QR (?barcode=)
+func (s *Server) handleFindUnit(w http.ResponseWriter, r *http.Request) {
+	barcode := r.URL.Query().Get("barcode")
+	if barcode == "" {
+		s.respondStoreError(w, "Invalid bottle lookup", store.NewValidationError("barcode", "barcode is required"))
+		return
+	}
+
+	unit, err := s.store.GetUnitByBarcode(r.Context(), barcode)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch bottle", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(unit)
+}
+
+// handleUpdateUnit met à jour une bouteille : lot, prix, niveau, code-barres et état
+func (s *Server) handleUpdateUnit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid bottle ID", err)
+		return
+	}
+
+	var unit domain.BottleUnit
+	if err := json.NewDecoder(r.Body).Decode(&unit); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	unit.ID = id
+	if err := ValidateUnit(&unit); err != nil {
+		s.respondStoreError(w, "Invalid bottle", err)
+		return
+	}
+
+	if err := s.store.UpdateUnit(r.Context(), &unit, overflowAllowed(r)); err != nil {
+		s.respondStoreError(w, "Failed to update bottle", err)
+		return
+	}
+
+	updated, err := s.store.GetUnit(r.Context(), id)
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch bottle", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "wine", updated.WineID, "unit_updated", map[string]interface{}{"unit_id": id, "state": updated.State}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// handleOpenUnit ouvre une bouteille ({"preservation": "vacuum", "opened_at": ...}, tous deux facultatifs)
+func (s *Server) handleOpenUnit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid bottle ID", err)
+		return
+	}
+
+	var req struct {
+		Preservation string     `json:"preservation"`
+		OpenedAt     *time.Time `json:"opened_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	verr := &store.ValidationError{}
+	validatePreservation(verr, req.Preservation)
+	if req.OpenedAt != nil && req.OpenedAt.After(time.Now()) {
+		verr.Add("opened_at", "opened_at cannot be in the future")
+	}
+	if err := verr.Err(); err != nil {
+		s.respondStoreError(w, "Invalid bottle opening", err)
+		return
+	}
+
+	unit, err := s.store.OpenUnit(r.Context(), id, req.Preservation, req.OpenedAt)
+	if err != nil {
+		s.respondStoreError(w, "Failed to open bottle", err)
+		return
+	}
+
+	// Audit
+	s.store.LogActivity(r.Context(), "wine", unit.WineID, "unit_opened", map[string]interface{}{"unit_id": id, "preservation": unit.Preservation}, s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(unit)
+}
+
+// handleGetOpenedBottles liste les bouteilles ouvertes, les plus urgentes d'abord
+func (s *Server) handleGetOpenedBottles(w http.ResponseWriter, r *http.Request) {
+	opened, err := s.store.GetOpenedBottles(r.Context())
+	if err != nil {
+		s.respondStoreError(w, "Failed to fetch opened bottles", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(opened)
+}
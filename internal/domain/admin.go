@@ -39,6 +39,7 @@ type Settings struct {
 	// Advanced
 	MaxRequestBodySize int64 `json:"max_request_body_size"` // Max upload
 	SessionTimeout     int   `json:"session_timeout"`       // Minutes
+	TrashRetentionDays int   `json:"trash_retention_days"`  // Durée de conservation de la corbeille
 
 	// SMTP Configuration
 	SMTPConfigured bool `json:"smtp_configured"` // SMTP est-il configuré?
@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// Backup décrit un instantané de la base dans le répertoire de sauvegarde
+type Backup struct {
+	Name      string    `json:"name"` // ex: glou-20260102-030000.db
+	Size      int64     `json:"size"` // octets
+	CreatedAt time.Time `json:"created_at"`
+}
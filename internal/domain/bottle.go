@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Bottle represents a beverage item in a cave (wine, beer, spirit, cigar, etc.)
 // This is an enhanced version of Wine supporting multiple beverage types
@@ -10,11 +13,15 @@ type Bottle struct {
 	UserID          *string    `json:"user_id"` // New: For multi-user support
 	Name            string     `json:"name"`
 	Producer        string     `json:"producer"`
+	ProducerID      *int64     `json:"producer_id"` // Linked producer; Producer holds its name
 	Region          string     `json:"region"`
+	AppellationID   *string    `json:"appellation_id"` // Appellation of the built-in gazetteer
 	Vintage         int        `json:"vintage"`
 	BottleType      string     `json:"bottle_type"` // New: Support multiple types (wine, beer, spirit, cigar)
 	WineType        string     `json:"wine_type"`   // Alias for backward compatibility with BottleType
 	Quantity        int        `json:"quantity"`
+	Format          string     `json:"format"`    // See BottleFormats; empty keeps the stored format on update
+	VolumeML        int        `json:"volume_ml"` // Volume of one bottle, set by a known format; alone it picks the format
 	CellID          *int64     `json:"cell_id"`
 	CreatedAt       time.Time  `json:"created_at"`
 	MinApogeeDate   *time.Time `json:"min_apogee_date,omitempty"`
@@ -29,8 +36,43 @@ type Bottle struct {
 	BarCode         string     `json:"bar_code"`
 	Image           string     `json:"image"`
 	ExternalID      string     `json:"external_id"`
+	Status          string     `json:"status"`                // in_stock, finished
+	FinishedAt      *time.Time `json:"finished_at,omitempty"` // Date de la dernière bouteille bue
+	Version         int64      `json:"version"`               // Version de ligne (ETag)
+
+	// Similar existing producers, returned on creation when the producer was not recognized
+	ProducerSuggestions []*ProducerMatch `json:"producer_suggestions,omitempty"`
+
+	// Grape composition, dominant grape first. Nil leaves the stored blend unchanged on update.
+	Blend []*BlendComponent `json:"blend"`
+
+	// Beer attributes (the brewery is the producer)
+	Style      string     `json:"style"`                 // Beer style (IPA, Stout...) or spirit category (Single Malt, Rhum agricole...)
+	IBU        *int       `json:"ibu"`                   // Bitterness
+	EBC        *int       `json:"ebc"`                   // Colour
+	PackagedAt *time.Time `json:"packaged_at,omitempty"` // Bottling or canning date
+	BestBefore *time.Time `json:"best_before,omitempty"`
+
+	// Spirit attributes; Vintage stays 0 for a no-age-statement (NAS) bottle
+	AgeStatement       *int   `json:"age_statement"` // Years, nil for NAS
+	Distillery         string `json:"distillery"`
+	CaskType           string `json:"cask_type"` // Oloroso sherry butt, ex-bourbon barrel...
+	CaskNumber         string `json:"cask_number"`
+	DistilledYear      *int   `json:"distilled_year"`
+	BottledYear        *int   `json:"bottled_year"`
+	CaskStrength       bool   `json:"cask_strength"`
+	IndependentBottler string `json:"independent_bottler"` // Empty for an official bottling
 }
 
+// MaxBottleQuantity bounds the bottles of a stock line: each bottle is a bottle unit row
+const MaxBottleQuantity = 10000
+
+// Statuts d'une bouteille : un vin terminé est archivé avec son historique
+const (
+	BottleStatusInStock  = "in_stock"
+	BottleStatusFinished = "finished"
+)
+
 // Bottle type constants - Support 7 types of beverages
 const (
 	BottleTypeRedWine       = "red_wine"
@@ -54,3 +96,29 @@ func BottleTypes() []string {
 		BottleTypeCigar,
 	}
 }
+
+// legacyBottleTypes associe les anciens libellés de l'API ("Red", "Rosé"...) aux types canoniques
+var legacyBottleTypes = map[string]string{
+	"red":       BottleTypeRedWine,
+	"white":     BottleTypeWhiteWine,
+	"rosé":      BottleTypeRoseWine,
+	"rose":      BottleTypeRoseWine,
+	"sparkling": BottleTypeSparklingWine,
+	"beer":      BottleTypeBeer,
+	"spirit":    BottleTypeSpirit,
+	"cigar":     BottleTypeCigar,
+}
+
+// NormalizeBottleType convertit un type (canonique ou ancien libellé) en type canonique
+func NormalizeBottleType(t string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(t))
+	for _, bt := range BottleTypes() {
+		if key == bt {
+			return bt, true
+		}
+	}
+	if bt, ok := legacyBottleTypes[key]; ok {
+		return bt, true
+	}
+	return "", false
+}
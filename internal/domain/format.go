@@ -0,0 +1,147 @@
+package domain
+
+// BottleFormat is a container size. Stock lines record a format and its volume in ml.
+type BottleFormat struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	VolumeML int    `json:"volume_ml"`
+	Kind     string `json:"kind"` // wine, beer or spirit
+}
+
+// Default formats and glass sizes
+const (
+	FormatStandard  = "standard"
+	FormatCustom    = "custom" // Any other volume
+	DefaultVolumeML = 750
+
+	WineGlassML   = 125
+	BeerGlassML   = 250
+	SpiritGlassML = 40
+)
+
+// bottleFormats lists the known formats, smallest first within each kind
+var bottleFormats = []BottleFormat{
+	{ID: "piccolo", Name: "Piccolo", VolumeML: 187, Kind: "wine"},
+	{ID: "half", Name: "Demi-bouteille", VolumeML: 375, Kind: "wine"},
+	{ID: "pot", Name: "Pot lyonnais", VolumeML: 460, Kind: "wine"},
+	{ID: "medium", Name: "Medium", VolumeML: 500, Kind: "wine"},
+	{ID: "clavelin", Name: "Clavelin", VolumeML: 620, Kind: "wine"},
+	{ID: FormatStandard, Name: "Bouteille", VolumeML: DefaultVolumeML, Kind: "wine"},
+	{ID: "liter", Name: "Litre", VolumeML: 1000, Kind: "wine"},
+	{ID: "magnum", Name: "Magnum", VolumeML: 1500, Kind: "wine"},
+	{ID: "jeroboam", Name: "Jéroboam", VolumeML: 3000, Kind: "wine"},
+	{ID: "rehoboam", Name: "Réhoboam", VolumeML: 4500, Kind: "wine"},
+	{ID: "methuselah", Name: "Mathusalem", VolumeML: 6000, Kind: "wine"},
+	{ID: "salmanazar", Name: "Salmanazar", VolumeML: 9000, Kind: "wine"},
+	{ID: "balthazar", Name: "Balthazar", VolumeML: 12000, Kind: "wine"},
+	{ID: "nebuchadnezzar", Name: "Nabuchodonosor", VolumeML: 15000, Kind: "wine"},
+
+	{ID: "beer_bottle_250", Name: "Bouteille 25 cl", VolumeML: 250, Kind: "beer"},
+	{ID: "beer_bottle_330", Name: "Bouteille 33 cl", VolumeML: 330, Kind: "beer"},
+	{ID: "beer_bottle_750", Name: "Bouteille 75 cl", VolumeML: 750, Kind: "beer"},
+	{ID: "can_330", Name: "Canette 33 cl", VolumeML: 330, Kind: "beer"},
+	{ID: "can_440", Name: "Canette 44 cl", VolumeML: 440, Kind: "beer"},
+	{ID: "can_500", Name: "Canette 50 cl", VolumeML: 500, Kind: "beer"},
+	{ID: "pint_568", Name: "Pinte", VolumeML: 568, Kind: "beer"},
+
+	{ID: "spirit_50", Name: "Mignonnette", VolumeML: 50, Kind: "spirit"},
+	{ID: "spirit_200", Name: "Flasque 20 cl", VolumeML: 200, Kind: "spirit"},
+	{ID: "spirit_350", Name: "Flasque 35 cl", VolumeML: 350, Kind: "spirit"},
+	{ID: "spirit_500", Name: "Bouteille 50 cl", VolumeML: 500, Kind: "spirit"},
+	{ID: "spirit_700", Name: "Bouteille 70 cl", VolumeML: 700, Kind: "spirit"},
+	{ID: "spirit_1000", Name: "Bouteille 1 l", VolumeML: 1000, Kind: "spirit"},
+	{ID: "spirit_1750", Name: "Bouteille 1,75 l", VolumeML: 1750, Kind: "spirit"},
+}
+
+// BottleFormats returns the known formats
+func BottleFormats() []BottleFormat {
+	return append([]BottleFormat(nil), bottleFormats...)
+}
+
+// FindBottleFormat returns a known format by ID
+func FindBottleFormat(id string) (BottleFormat, bool) {
+	for _, f := range bottleFormats {
+		if f.ID == id {
+			return f, true
+		}
+	}
+	return BottleFormat{}, false
+}
+
+// formatKind returns the kind of formats used for a bottle type
+func formatKind(bottleType string) string {
+	switch bottleType {
+	case BottleTypeBeer, BottleTypeSpirit:
+		return bottleType
+	}
+	return "wine"
+}
+
+// DefaultBottleFormat returns the usual format of a bottle type
+func DefaultBottleFormat(bottleType string) BottleFormat {
+	switch formatKind(bottleType) {
+	case BottleTypeBeer:
+		f, _ := FindBottleFormat("beer_bottle_330")
+		return f
+	case BottleTypeSpirit:
+		f, _ := FindBottleFormat("spirit_700")
+		return f
+	}
+	f, _ := FindBottleFormat(FormatStandard)
+	return f
+}
+
+// FormatForVolume returns the format matching a volume, preferring the bottle type's
+// kind of formats, or the custom format when none has this volume
+func FormatForVolume(bottleType string, volumeML int) string {
+	found := FormatCustom
+	for _, f := range bottleFormats {
+		if f.VolumeML != volumeML {
+			continue
+		}
+		if f.Kind == formatKind(bottleType) {
+			return f.ID
+		}
+		if found == FormatCustom {
+			found = f.ID
+		}
+	}
+	return found
+}
+
+// DefaultGlassML returns the volume of a glass for a bottle type
+func DefaultGlassML(bottleType string) int {
+	switch bottleType {
+	case BottleTypeBeer:
+		return BeerGlassML
+	case BottleTypeSpirit:
+		return SpiritGlassML
+	}
+	return WineGlassML
+}
+
+// InventoryLine aggregates the bottles of one type and format
+type InventoryLine struct {
+	BottleType    string  `json:"bottle_type"`
+	Format        string  `json:"format"`
+	VolumeML      int     `json:"volume_ml"`
+	Wines         int     `json:"wines"`          // Stock lines
+	Bottles       int     `json:"bottles"`        // Full bottles in stock
+	Liters        float64 `json:"liters"`         // Volume of the bottles in stock
+	OpenedBottles int     `json:"opened_bottles"` // Opened, not finished
+	OpenedLiters  float64 `json:"opened_liters"`  // Left in the opened bottles
+	PurchaseValue float64 `json:"purchase_value"` // Opened bottles count for their remaining share
+	CurrentValue  float64 `json:"current_value"`  // Current value, purchase price when unknown
+}
+
+// Inventory is the stock in bottles, liters and value (cigars excluded)
+type Inventory struct {
+	Bottles       int              `json:"bottles"`
+	Liters        float64          `json:"liters"`
+	OpenedBottles int              `json:"opened_bottles"`
+	OpenedLiters  float64          `json:"opened_liters"`
+	PurchaseValue float64          `json:"purchase_value"`
+	CurrentValue  float64          `json:"current_value"`
+	ValuePerLiter *float64         `json:"value_per_liter"` // Current value per liter, opened bottles included
+	Lines         []*InventoryLine `json:"lines"`
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Country is a wine-producing country of the built-in gazetteer
+type Country struct {
+	Code    string       `json:"code"` // ISO 3166-1 alpha-2
+	Name    string       `json:"name"`
+	Lat     float64      `json:"lat"`
+	Lon     float64      `json:"lon"`
+	Regions []*GeoRegion `json:"regions,omitempty"`
+}
+
+// GeoRegion is a wine region of a country (Bordeaux, Tuscany, California...)
+type GeoRegion struct {
+	ID               string         `json:"id"`
+	Name             string         `json:"name"`
+	Aliases          []string       `json:"aliases,omitempty"` // Other names, e.g. "Bourgogne" for Burgundy
+	CountryCode      string         `json:"country_code"`
+	Country          string         `json:"country"`
+	Lat              float64        `json:"lat"`
+	Lon              float64        `json:"lon"`
+	SubRegions       []string       `json:"sub_regions,omitempty"`
+	AppellationCount int            `json:"appellation_count"`
+	Appellations     []*Appellation `json:"appellations,omitempty"`
+}
+
+// Appellation is a protected designation (AOC, DOCG, AVA...) wines can be linked to
+type Appellation struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`                 // AOC, DOC, DOCG, DO, AVA, GI...
+	SubRegion   string   `json:"sub_region,omitempty"` // e.g. "Côte de Nuits"
+	Aliases     []string `json:"aliases,omitempty"`
+	RegionID    string   `json:"region_id"`
+	Region      string   `json:"region"`
+	CountryCode string   `json:"country_code"`
+	Country     string   `json:"country"`
+	Lat         float64  `json:"lat"`
+	Lon         float64  `json:"lon"`
+}
+
+// GeoArea is the stock of a country or a region, for the map
+type GeoArea struct {
+	ID      string         `json:"id"` // Country code or region ID
+	Name    string         `json:"name"`
+	Country string         `json:"country,omitempty"` // Set for regions
+	Lat     float64        `json:"lat"`
+	Lon     float64        `json:"lon"`
+	Wines   int            `json:"wines"`   // Wines in stock
+	Bottles int            `json:"bottles"` // Bottles in stock
+	ByType  map[string]int `json:"by_type"` // Bottles per bottle type
+}
+
+// GeoBreakdown aggregates the stock by country or by region
+type GeoBreakdown struct {
+	Level            string     `json:"level"` // country or region
+	Areas            []*GeoArea `json:"areas"` // Most bottles first
+	UnlocatedWines   int        `json:"unlocated_wines"`
+	UnlocatedBottles int        `json:"unlocated_bottles"`
+}
+
+// NormalizePlaceName returns the comparison key of a place name: lower case,
+// without accents, punctuation or hyphens ("Côte-Rôtie" and "cote rotie" give "cote rotie")
+func NormalizePlaceName(name string) string {
+	folded := accentFolding.Replace(strings.ToLower(name))
+	return strings.Join(strings.FieldsFunc(folded, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// Place is a country, region or appellation found in the gazetteer by a free-text search
+type Place struct {
+	Kind        string  `json:"kind"` // country, region or appellation
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Region      string  `json:"region,omitempty"`
+	CountryCode string  `json:"country_code"`
+	Country     string  `json:"country"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// Grape colors
+const (
+	GrapeColorRed   = "red"
+	GrapeColorWhite = "white"
+)
+
+// GrapeVariety is a grape of the reference table
+type GrapeVariety struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`    // red, white or empty when unknown
+	Synonyms  []string  `json:"synonyms"` // Other names, matched like the name (Shiraz for Syrah)
+	WineCount int       `json:"wine_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BlendComponent is one grape of a wine's blend
+type BlendComponent struct {
+	GrapeID    int64    `json:"grape_id"`   // Set from Grape when zero
+	Grape      string   `json:"grape"`      // Name or synonym; the reference name once saved
+	Percentage *float64 `json:"percentage"` // Share of the blend, nil when unknown
+}
+
+// GrapeStat aggregates the wines made (partly) from a grape
+type GrapeStat struct {
+	GrapeID       int64    `json:"grape_id"`
+	Grape         string   `json:"grape"`
+	Color         string   `json:"color"`
+	Wines         int      `json:"wines"`          // Wines containing the grape (trash excluded)
+	SingleVariety int      `json:"single_variety"` // Of which made from this grape only
+	Bottles       int      `json:"bottles"`        // Bottles in stock
+	RatedWines    int      `json:"rated_wines"`
+	AverageRating *float64 `json:"average_rating"`
+}
+
+// BlendStat aggregates the wines sharing the same set of grapes
+type BlendStat struct {
+	Grapes        []string `json:"grapes"` // Dominant grape first
+	Wines         int      `json:"wines"`
+	Bottles       int      `json:"bottles"`
+	RatedWines    int      `json:"rated_wines"`
+	AverageRating *float64 `json:"average_rating"`
+	BestRating    *float32 `json:"best_rating"`
+}
+
+// NormalizeGrapeName returns the comparison key of a grape name, with the same rules
+// as place names ("Gewürztraminer" and "GEWURZTRAMINER" give "gewurztraminer")
+func NormalizeGrapeName(name string) string {
+	return NormalizePlaceName(name)
+}
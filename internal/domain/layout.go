@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// CaveLayout describes the physical layout of a cave: zones (e.g. temperature
+// compartments) made of racks, each rack a grid of rows × columns of slots
+// holding Depth bottles front to back. Cells are generated from it, one per slot.
+type CaveLayout struct {
+	Zones []CaveZone `json:"zones"`
+}
+
+// CaveZone is a group of identical racks, optionally with its own temperature range
+type CaveZone struct {
+	Name           string   `json:"name"`
+	Racks          int      `json:"racks"`
+	Rows           int      `json:"rows"`                      // Rows per rack
+	Columns        int      `json:"columns"`                   // Slots per row
+	Depth          int      `json:"depth"`                     // Bottles per slot
+	MinTemperature *float64 `json:"min_temperature,omitempty"` // °C
+	MaxTemperature *float64 `json:"max_temperature,omitempty"` // °C
+}
+
+// CaveModel is a fridge or rack model whose layout is copied into new caves
+type CaveModel struct {
+	ID        string     `json:"id"` // Slug, e.g. "eurocave-pure-l"
+	Brand     string     `json:"brand"`
+	Name      string     `json:"name"`
+	Layout    CaveLayout `json:"layout"`
+	Capacity  int        `json:"capacity"` // Derived from the layout
+	BuiltIn   bool       `json:"built_in"` // Bundled with the server (read-only)
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// Slots returns the number of slots (cells) of the layout
+func (l *CaveLayout) Slots() int {
+	total := 0
+	for _, z := range l.Zones {
+		total += z.Racks * z.Rows * z.Columns
+	}
+	return total
+}
+
+// Capacity returns the number of bottles the layout can hold
+func (l *CaveLayout) Capacity() int {
+	total := 0
+	for _, z := range l.Zones {
+		total += z.Racks * z.Rows * z.Columns * z.Depth
+	}
+	return total
+}
+
+// SlotLocation returns the display label of a slot, e.g. "Haut-R2-L1-C3"
+func SlotLocation(zone string, rack, row, column int) string {
+	return fmt.Sprintf("%s-R%d-L%d-C%d", zone, rack, row, column)
+}
+
+// CaveGrid is the layout of a cave with per-slot occupancy
+type CaveGrid struct {
+	CaveID   int64       `json:"cave_id"`
+	Name     string      `json:"name"`
+	Capacity int         `json:"capacity"`
+	Current  int         `json:"current"`
+	Zones    []GridZone  `json:"zones"`
+	Unplaced []*GridSlot `json:"unplaced"` // Cells without a position in the layout
+}
+
+// GridZone is a zone of the grid
+type GridZone struct {
+	Name  string     `json:"name"`
+	Depth int        `json:"depth"`
+	Racks []GridRack `json:"racks"`
+}
+
+// GridRack is a rack of a zone
+type GridRack struct {
+	Rack int       `json:"rack"`
+	Rows []GridRow `json:"rows"`
+}
+
+// GridRow is a row of a rack
+type GridRow struct {
+	Row   int         `json:"row"`
+	Slots []*GridSlot `json:"slots"`
+}
+
+// GridSlot is a slot (cell) with what it holds
+type GridSlot struct {
+	Column   int        `json:"column,omitempty"`
+	CellID   int64      `json:"cell_id"`
+	Location string     `json:"location"`
+	Capacity int        `json:"capacity"`
+	Current  int        `json:"current"`
+	Items    []SlotItem `json:"items"`
+}
+
+// SlotItem is a wine or tobacco stored in a slot
+type SlotItem struct {
+	Type     string `json:"type"` // wine, tobacco
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Vintage  *int   `json:"vintage,omitempty"`
+	Quantity int    `json:"quantity"`
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// BottleMovement records bottles of a wine moved from one cave/cell to another
+type BottleMovement struct {
+	ID           int64     `json:"id"`
+	WineID       int64     `json:"wine_id"`           // Stock line the bottles left
+	TargetWineID int64     `json:"target_wine_id"`    // Stock line that received them (same line for a full move)
+	UnitID       *int64    `json:"unit_id,omitempty"` // Bottle moved, for a single-unit move
+	Quantity     int       `json:"quantity"`
+	FromCaveID   *int64    `json:"from_cave_id"`
+	FromCellID   *int64    `json:"from_cell_id"`
+	ToCaveID     *int64    `json:"to_cave_id"`
+	ToCellID     *int64    `json:"to_cell_id"`
+	FromCave     string    `json:"from_cave,omitempty"` // Cave name, for display
+	FromCell     string    `json:"from_cell,omitempty"` // Cell location, for display
+	ToCave       string    `json:"to_cave,omitempty"`
+	ToCell       string    `json:"to_cell,omitempty"`
+	Note         string    `json:"note"`
+	MovedAt      time.Time `json:"moved_at"`
+}
+
+// MoveResult is the outcome of a move: the source line and the line now holding the moved bottles
+type MoveResult struct {
+	Source   *Wine           `json:"source"`
+	Target   *Wine           `json:"target"` // Same line as Source for a full move
+	Movement *BottleMovement `json:"movement"`
+	Unit     *BottleUnit     `json:"unit,omitempty"` // Bottle moved, for a single-unit move
+}
@@ -0,0 +1,71 @@
+package domain
+
+import "time"
+
+// Preservation methods of an opened bottle
+const (
+	PreservationNone             = ""
+	PreservationStopper          = "stopper"
+	PreservationVacuum           = "vacuum"
+	PreservationInertGas         = "inert_gas"
+	PreservationSparklingStopper = "sparkling_stopper"
+	PreservationCoravin          = "coravin" // Poured through the cork: the bottle is barely exposed to air
+)
+
+// Preservations returns the accepted preservation methods (none excluded)
+func Preservations() []string {
+	return []string{
+		PreservationStopper,
+		PreservationVacuum,
+		PreservationInertGas,
+		PreservationSparklingStopper,
+		PreservationCoravin,
+	}
+}
+
+// FortifiedAlcoholLevel is the alcohol level from which a still wine keeps like a fortified wine
+const FortifiedAlcoholLevel = 15
+
+// shelfLifeDays gives, per category, the days an opened bottle keeps for each preservation
+// method. The "" entry applies to the methods not listed.
+var shelfLifeDays = map[string]map[string]int{
+	BottleTypeRedWine:       {"": 3, PreservationStopper: 4, PreservationVacuum: 7, PreservationInertGas: 10, PreservationCoravin: 180},
+	BottleTypeWhiteWine:     {"": 2, PreservationStopper: 3, PreservationVacuum: 5, PreservationInertGas: 7, PreservationCoravin: 180},
+	BottleTypeRoseWine:      {"": 2, PreservationStopper: 3, PreservationVacuum: 5, PreservationInertGas: 7, PreservationCoravin: 180},
+	BottleTypeSparklingWine: {"": 1, PreservationSparklingStopper: 3, PreservationInertGas: 3, PreservationCoravin: 30},
+	"fortified":             {"": 28, PreservationVacuum: 42, PreservationInertGas: 60, PreservationCoravin: 365},
+	BottleTypeBeer:          {"": 1},
+	BottleTypeSpirit:        {"": 730},
+}
+
+// ShelfLifeDays returns how many days an opened bottle keeps, or 0 when there is no rule
+// (cigars). Still wines from FortifiedAlcoholLevel follow the fortified wine rules.
+func ShelfLifeDays(bottleType string, alcoholLevel *float32, preservation string) int {
+	category := bottleType
+	switch bottleType {
+	case BottleTypeRedWine, BottleTypeWhiteWine, BottleTypeRoseWine:
+		if alcoholLevel != nil && *alcoholLevel >= FortifiedAlcoholLevel {
+			category = "fortified"
+		}
+	}
+	rules, ok := shelfLifeDays[category]
+	if !ok {
+		return 0
+	}
+	if days, ok := rules[preservation]; ok {
+		return days
+	}
+	return rules[""]
+}
+
+// OpenedBottle is an opened bottle unit with its wine and shelf life
+type OpenedBottle struct {
+	BottleUnit
+	WineName         string     `json:"wine_name"`
+	BottleType       string     `json:"bottle_type"`
+	VolumeML         int        `json:"volume_ml"`
+	RemainingPercent float64    `json:"remaining_percent"` // Fill level left, from the pours
+	ShelfLifeDays    int        `json:"shelf_life_days"`   // 0 when there is no rule
+	DrinkBy          *time.Time `json:"drink_by,omitempty"`
+	Overdue          bool       `json:"overdue"`
+}
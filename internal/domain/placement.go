@@ -0,0 +1,38 @@
+package domain
+
+// Drinking profiles used by the placement optimizer
+const (
+	PlacementDrinkSoon = "drink_soon" // Ready or ending soon: accessible slots
+	PlacementKeeper    = "keeper"     // Long ageing: deep slots
+	PlacementStandard  = "standard"
+)
+
+// PlacementPlan is a ranked proposal of cells for incoming bottles of a wine
+type PlacementPlan struct {
+	CaveID     int64                  `json:"cave_id"`
+	WineID     *int64                 `json:"wine_id,omitempty"` // Nil when planning for a wine not created yet
+	BottleType string                 `json:"bottle_type"`
+	Profile    string                 `json:"profile"` // drink_soon, keeper, standard
+	Quantity   int                    `json:"quantity"`
+	Placed     int                    `json:"placed"`
+	Unplaced   int                    `json:"unplaced"` // Bottles that fit nowhere (cave full or no suitable zone)
+	Placements []*PlacementSuggestion `json:"placements"`
+}
+
+// PlacementSuggestion is one cell of a plan, best first
+type PlacementSuggestion struct {
+	Rank     int      `json:"rank"`
+	CellID   int64    `json:"cell_id"`
+	Location string   `json:"location"`
+	Zone     string   `json:"zone,omitempty"`
+	Quantity int      `json:"quantity"` // Bottles to put in the cell
+	Free     int      `json:"free"`     // Free space before placing them (-1: unlimited)
+	Score    float64  `json:"score"`
+	Reasons  []string `json:"reasons"`
+}
+
+// Placement is a cell and a number of bottles, as sent back to apply a plan
+type Placement struct {
+	CellID   int64 `json:"cell_id"`
+	Quantity int   `json:"quantity"`
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Producer is a wine estate, house or brewery referenced by wines
+type Producer struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"` // Other spellings, matched like the name
+	Country   string    `json:"country"`
+	Region    string    `json:"region"`
+	Website   string    `json:"website"`
+	Notes     string    `json:"notes"`
+	WineCount int       `json:"wine_count"` // Wines linked to the producer (trash excluded)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProducerMatch is an existing producer similar to a searched name
+type ProducerMatch struct {
+	Producer  *Producer `json:"producer"`
+	Score     float64   `json:"score"`      // 1 for an exact match after normalization
+	MatchedOn string    `json:"matched_on"` // Name or alias that matched
+}
+
+// producerAbbreviations expands the usual abbreviations of producer names
+var producerAbbreviations = map[string]string{
+	"ch":   "chateau",
+	"cht":  "chateau",
+	"chat": "chateau",
+	"dom":  "domaine",
+	"dne":  "domaine",
+	"st":   "saint",
+	"ste":  "sainte",
+	"cie":  "compagnie",
+	"vve":  "veuve",
+	"&":    "et",
+	"and":  "et",
+}
+
+// accentFolding maps accented Latin letters to their base letter
+var accentFolding = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+)
+
+// NormalizeProducerName returns the comparison key of a producer name: lower case,
+// without accents or punctuation, with common abbreviations expanded
+// ("Ch. Margaux", "Château Margaux" and "CHATEAU MARGAUX" give "chateau margaux")
+func NormalizeProducerName(name string) string {
+	folded := accentFolding.Replace(strings.ToLower(name))
+	words := strings.FieldsFunc(folded, func(r rune) bool {
+		return r != '&' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if expanded, ok := producerAbbreviations[word]; ok {
+			words[i] = expanded
+		}
+	}
+	return strings.Join(words, " ")
+}
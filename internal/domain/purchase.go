@@ -0,0 +1,59 @@
+package domain
+
+import "time"
+
+// Merchant is a shop, estate or website where wines and tobaccos are bought
+type Merchant struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Website   string    `json:"website"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Address   string    `json:"address"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Purchasable item types
+const (
+	PurchaseItemWine    = "wine"
+	PurchaseItemTobacco = "tobacco"
+)
+
+// PurchaseLot records one acquisition of a wine or tobacco
+type PurchaseLot struct {
+	ID           int64     `json:"id"`
+	ItemType     string    `json:"item_type"` // wine or tobacco
+	ItemID       int64     `json:"item_id"`
+	ItemName     string    `json:"item_name,omitempty"` // For display
+	MerchantID   *int64    `json:"merchant_id"`
+	Merchant     string    `json:"merchant,omitempty"` // Merchant name, for display
+	PurchaseDate time.Time `json:"purchase_date"`
+	Quantity     int       `json:"quantity"`
+	UnitPrice    float64   `json:"unit_price"`
+	Currency     string    `json:"currency"` // ISO 4217 code, EUR by default
+	InvoiceRef   string    `json:"invoice_ref"`
+	Notes        string    `json:"notes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PurchaseCost summarizes the lots of one item in one currency.
+// Remaining stock is assumed to come from the most recent lots (first in, first out).
+type PurchaseCost struct {
+	Currency          string  `json:"currency"`
+	Lots              int     `json:"lots"`
+	Quantity          int     `json:"quantity"`     // Units bought
+	TotalCost         float64 `json:"total_cost"`   // Amount spent
+	AverageCost       float64 `json:"average_cost"` // Weighted average unit price
+	RemainingQuantity int     `json:"remaining_quantity"`
+	RemainingCost     float64 `json:"remaining_cost"` // Cost of the units still in stock
+}
+
+// PurchaseHistory lists the lots of an item with their cost summary (one entry per currency)
+type PurchaseHistory struct {
+	ItemType string          `json:"item_type"`
+	ItemID   int64           `json:"item_id"`
+	InStock  int             `json:"in_stock"`
+	Lots     []*PurchaseLot  `json:"lots"`
+	Costs    []*PurchaseCost `json:"costs"`
+}
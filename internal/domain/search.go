@@ -0,0 +1,13 @@
+package domain
+
+// SearchResult est un résultat de la recherche plein texte (vin ou tabac)
+type SearchResult struct {
+	Type      string   `json:"type"`       // "wine" ou "tobacco"
+	ID        int64    `json:"id"`         // ID du vin ou du tabac
+	Name      string   `json:"name"`       // Nom affiché
+	MatchedIn string   `json:"matched_in"` // "wine", "tobacco" ou "consumption" (commentaire de dégustation)
+	Snippet   string   `json:"snippet"`    // Extrait avec les termes surlignés (<mark>…</mark>)
+	Rank      float64  `json:"rank"`       // Score bm25 (plus petit = plus pertinent)
+	Wine      *Wine    `json:"wine,omitempty"`
+	Tobacco   *Tobacco `json:"tobacco,omitempty"`
+}
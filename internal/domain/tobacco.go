@@ -22,4 +22,5 @@ type Tobacco struct {
 	Binder        string `json:"binder"`         // Sous-cape
 
 	CreatedAt time.Time `json:"created_at"`
+	Version   int64     `json:"version"` // Row version (ETag)
 }
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// TrashItem représente un élément supprimé (corbeille), restaurable jusqu'à sa purge
+type TrashItem struct {
+	Type      string    `json:"type"` // "wine", "tobacco", "cave", "cell"
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"` // Date de suppression définitive
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// BottleUnit is one physical bottle of a wine stock line. The line's Quantity
+// is the number of its units in stock.
+type BottleUnit struct {
+	ID             int64      `json:"id"`
+	WineID         int64      `json:"wine_id"`
+	CellID         *int64     `json:"cell_id"`
+	CaveID         *int64     `json:"cave_id"`
+	Location       string     `json:"location,omitempty"` // Cell location, for display
+	Lot            string     `json:"lot"`
+	PurchasePrice  *float32   `json:"purchase_price"`
+	FillLevel      string     `json:"fill_level"`   // See FillLevels; empty when unknown
+	State          string     `json:"state"`        // in_stock, opened, consumed, gifted
+	RemainingML    *int       `json:"remaining_ml"` // Left in an opened bottle; nil when full
+	OpenedAt       *time.Time `json:"opened_at,omitempty"`
+	Preservation   string     `json:"preservation"` // See Preservations; empty when none
+	Barcode        string     `json:"barcode,omitempty"`
+	StateChangedAt *time.Time `json:"state_changed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Bottle unit states: only in_stock units count in the line quantity and cell occupancy
+const (
+	UnitStateInStock  = "in_stock"
+	UnitStateOpened   = "opened"
+	UnitStateConsumed = "consumed"
+	UnitStateGifted   = "gifted"
+)
+
+// UnitStates returns all bottle unit states
+func UnitStates() []string {
+	return []string{UnitStateInStock, UnitStateOpened, UnitStateConsumed, UnitStateGifted}
+}
+
+// FillLevels returns the accepted fill levels (ullage), from full to low
+func FillLevels() []string {
+	return []string{"neck", "top_shoulder", "high_shoulder", "mid_shoulder", "low_shoulder"}
+}
@@ -4,13 +4,15 @@ import "time"
 
 // Cave represents a wine cellar or storage location
 type Cave struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Model     *string   `json:"model,omitempty"` // Pointer to allow nil
-	Location  string    `json:"location"`
-	Capacity  int       `json:"capacity"`
-	Current   int       `json:"current"` // Current number of bottles
-	CreatedAt time.Time `json:"created_at"`
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	Model     *string     `json:"model,omitempty"` // Pointer to allow nil
+	Location  string      `json:"location"`
+	Capacity  int         `json:"capacity"`
+	Current   int         `json:"current"` // Current number of bottles
+	CreatedAt time.Time   `json:"created_at"`
+	Version   int64       `json:"version"`          // Row version (ETag)
+	Layout    *CaveLayout `json:"layout,omitempty"` // Physical layout; cells are generated from it
 }
 
 // Cell represents a storage cell or compartment within a cave
@@ -21,6 +23,12 @@ type Cell struct {
 	Capacity  int       `json:"capacity"`
 	Current   int       `json:"current"` // Current number of bottles
 	CreatedAt time.Time `json:"created_at"`
+
+	// Position of a cell generated from the cave layout (empty for free cells)
+	Zone   string `json:"zone,omitempty"`
+	Rack   int    `json:"rack,omitempty"`
+	Row    int    `json:"row,omitempty"`
+	Column int    `json:"column,omitempty"`
 }
 
 // Wine represents a wine bottle (alias of Bottle for backward compatibility)
@@ -30,7 +38,7 @@ type Wine = Bottle
 type Alert struct {
 	ID          int64      `json:"id"`
 	WineID      int64      `json:"wine_id"`
-	AlertType   string     `json:"alert_type"` // low_stock, apogee_reached, apogee_ended
+	AlertType   string     `json:"alert_type"` // low_stock, apogee_reached, apogee_ended, best_before_approaching, opened_too_long
 	Message     string     `json:"message"`
 	Status      string     `json:"status"` // active, dismissed
 	CreatedAt   time.Time  `json:"created_at"`
@@ -41,7 +49,11 @@ type Alert struct {
 type ConsumptionHistory struct {
 	ID        int64     `json:"id"`
 	WineID    int64     `json:"wine_id"`
-	Quantity  int       `json:"quantity"`         // Quantity consumed
+	UnitID    *int64    `json:"unit_id,omitempty"` // Bottle consumed, when tracked per unit
+	Quantity  int       `json:"quantity"`          // Whole bottles consumed (finished by a pour for glasses)
+	VolumeML  *int      `json:"volume_ml"`         // Volume poured; for glasses, Glasses * GlassML
+	Glasses   *float64  `json:"glasses,omitempty"` // Glasses poured from an opened bottle
+	GlassML   *int      `json:"glass_ml,omitempty"`
 	Comment   string    `json:"comment"`          // Tasting notes/comment
 	Reason    string    `json:"reason"`           // Reason for consumption
 	Rating    *float64  `json:"rating,omitempty"` // Rating
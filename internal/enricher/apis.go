@@ -3,6 +3,11 @@ package enricher
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/romain/glou-server/internal/domain"
 )
 
 // searchOpenFoodFacts searches Open Food Facts API
@@ -244,7 +249,8 @@ func (we *WineEnricher) searchTheCocktailDB(ctx context.Context, name string) (*
 	drink := resp.Drinks[0]
 	data := &EnrichedWineData{
 		Name:        drink.Name,
-		Type:        drink.Category,
+		Type:        domain.BottleTypeSpirit,
+		Style:       drink.Category,
 		ImageURL:    drink.ImageURL,
 		Description: fmt.Sprintf("Category: %s, Alcoholic: %s", drink.Type, drink.Alcoholic),
 	}
@@ -252,14 +258,59 @@ func (we *WineEnricher) searchTheCocktailDB(ctx context.Context, name string) (*
 	return data, nil
 }
 
+// searchTheCocktailDBIngredient searches TheCocktailDB ingredients, which describe the
+// spirits themselves (type, ABV, description) rather than cocktails
+func (we *WineEnricher) searchTheCocktailDBIngredient(ctx context.Context, name string) (*EnrichedWineData, error) {
+	endpoint := fmt.Sprintf("https://www.thecocktaildb.com/api/json/v1/1/search.php?i=%s", urlEncode(name))
+
+	body, err := we.makeRequest(ctx, "GET", endpoint, map[string]string{
+		"User-Agent": "Glou-WineManager/1.0",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Ingredients []struct {
+			Name        string `json:"strIngredient"`
+			Description string `json:"strDescription"`
+			Type        string `json:"strType"`
+			Alcohol     string `json:"strAlcohol"`
+			ABV         string `json:"strABV"`
+		} `json:"ingredients"`
+	}
+
+	if err := unmarshalJSON(body, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Ingredients) == 0 || !strings.EqualFold(resp.Ingredients[0].Alcohol, "yes") {
+		return nil, fmt.Errorf("spirit not found")
+	}
+
+	ingredient := resp.Ingredients[0]
+	data := &EnrichedWineData{
+		Name:        ingredient.Name,
+		Type:        domain.BottleTypeSpirit,
+		Style:       ingredient.Type,
+		Description: ingredient.Description,
+	}
+	if abv, err := strconv.ParseFloat(ingredient.ABV, 32); err == nil && abv > 0 {
+		data.AlcoholLevel = float32(abv)
+	}
+
+	return data, nil
+}
+
 // searchUntappd searches Untappd API for beer information
-// Note: Requires API key - adjust implementation if you have access
-func (we *WineEnricher) searchUntappd(ctx context.Context, name string, apiKey string) (*EnrichedWineData, error) {
-	if apiKey == "" {
-		return nil, fmt.Errorf("untappd API key not configured")
+// Requires an Untappd API client ID and secret
+func (we *WineEnricher) searchUntappd(ctx context.Context, name, clientID, clientSecret string) (*EnrichedWineData, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("untappd API credentials not configured")
 	}
 
-	endpoint := fmt.Sprintf("https://api.untappd.com/v4/search/beer?q=%s&client_id=%s", urlEncode(name), apiKey)
+	endpoint := fmt.Sprintf("https://api.untappd.com/v4/search/beer?q=%s&client_id=%s&client_secret=%s",
+		urlEncode(name), urlEncode(clientID), urlEncode(clientSecret))
 
 	body, err := we.makeRequest(ctx, "GET", endpoint, map[string]string{
 		"User-Agent": "Glou-WineManager/1.0",
@@ -276,11 +327,13 @@ func (we *WineEnricher) searchUntappd(ctx context.Context, name string, apiKey s
 						Name        string  `json:"beer_name"`
 						AbV         float64 `json:"beer_abv"`
 						IBU         float64 `json:"beer_ibu"`
+						Style       string  `json:"beer_style"`
 						Description string  `json:"beer_description"`
 						Label       string  `json:"beer_label"`
 					} `json:"beer"`
 					Brewery struct {
-						Name string `json:"brewery_name"`
+						Name    string `json:"brewery_name"`
+						Country string `json:"country_name"`
 					} `json:"brewery"`
 				} `json:"items"`
 			} `json:"beers"`
@@ -296,13 +349,17 @@ func (we *WineEnricher) searchUntappd(ctx context.Context, name string, apiKey s
 		return nil, fmt.Errorf("beer not found")
 	}
 
+	// Untappd ne donne pas la couleur (EBC) ; la brasserie devient le producteur
 	beer := items[0]
 	data := &EnrichedWineData{
 		Name:         beer.Beer.Name,
 		Producer:     beer.Brewery.Name,
-		Type:         "Beer",
+		Region:       beer.Brewery.Country,
+		Type:         domain.BottleTypeBeer,
 		AlcoholLevel: float32(beer.Beer.AbV),
-		Description:  fmt.Sprintf("IBU: %.1f - %s", beer.Beer.IBU, beer.Beer.Description),
+		Style:        beer.Beer.Style,
+		IBU:          int(math.Round(beer.Beer.IBU)),
+		Description:  beer.Beer.Description,
 		ImageURL:     beer.Beer.Label,
 	}
 
@@ -12,18 +12,26 @@ import (
 
 // EnrichedWineData represents all enriched data about a wine from external APIs
 type EnrichedWineData struct {
-	Name         string    `json:"name"`
-	Producer     string    `json:"producer"`
-	Region       string    `json:"region"`
-	Vintage      int       `json:"vintage,omitempty"`
-	Type         string    `json:"type"`
-	AlcoholLevel float32   `json:"alcohol_level,omitempty"`
-	Description  string    `json:"description,omitempty"`
-	Rating       float32   `json:"rating,omitempty"`
-	Price        float32   `json:"price,omitempty"`
-	ImageURL     string    `json:"image_url,omitempty"`
-	SourceAPIs   []string  `json:"source_apis"` // Which APIs provided the data
-	LastUpdated  time.Time `json:"last_updated"`
+	Name          string    `json:"name"`
+	Producer      string    `json:"producer"`
+	Region        string    `json:"region"`
+	Vintage       int       `json:"vintage,omitempty"`
+	Type          string    `json:"type"`
+	AlcoholLevel  float32   `json:"alcohol_level,omitempty"`
+	Style         string    `json:"style,omitempty"` // Beer style or spirit category
+	IBU           int       `json:"ibu,omitempty"`   // Beer bitterness
+	AgeStatement  int       `json:"age_statement,omitempty"`
+	Distillery    string    `json:"distillery,omitempty"`
+	CaskType      string    `json:"cask_type,omitempty"`
+	CaskStrength  bool      `json:"cask_strength,omitempty"`
+	DistilledYear int       `json:"distilled_year,omitempty"`
+	BottledYear   int       `json:"bottled_year,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	Rating        float32   `json:"rating,omitempty"`
+	Price         float32   `json:"price,omitempty"`
+	ImageURL      string    `json:"image_url,omitempty"`
+	SourceAPIs    []string  `json:"source_apis"` // Which APIs provided the data
+	LastUpdated   time.Time `json:"last_updated"`
 }
 
 // WineEnricher manages wine data enrichment from multiple APIs
@@ -95,16 +103,33 @@ func (we *WineEnricher) EnrichByName(ctx context.Context, name, producer, vintag
 	return data, nil
 }
 
-// EnrichSpirit searches for spirits/cocktails by name
+// EnrichSpirit searches for spirits/cocktails by name.
+// The spirit itself (TheCocktailDB ingredient) is tried before cocktails, then the age
+// statement, cask and distillation details are read from the label name.
 func (we *WineEnricher) EnrichSpirit(ctx context.Context, name string) (*EnrichedWineData, error) {
-	// Try TheCocktailDB
-	data, err := we.searchTheCocktailDB(ctx, name)
-	if err == nil && data != nil {
-		data.SourceAPIs = append(data.SourceAPIs, "TheCocktailDB")
-		return data, nil
+	data, err := we.searchTheCocktailDBIngredient(ctx, name)
+	if err != nil || data == nil {
+		data, err = we.searchTheCocktailDB(ctx, name)
+	}
+	if err != nil || data == nil {
+		return nil, fmt.Errorf("spirit '%s' not found", name)
 	}
 
-	return nil, fmt.Errorf("spirit '%s' not found", name)
+	data.merge(parseSpiritLabel(name))
+	data.SourceAPIs = append(data.SourceAPIs, "TheCocktailDB")
+	data.LastUpdated = time.Now()
+	return data, nil
+}
+
+// EnrichBeer searches Untappd for a beer by name
+func (we *WineEnricher) EnrichBeer(ctx context.Context, name, clientID, clientSecret string) (*EnrichedWineData, error) {
+	data, err := we.searchUntappd(ctx, name, clientID, clientSecret)
+	if err != nil {
+		return nil, fmt.Errorf("beer '%s' not found: %w", name, err)
+	}
+	data.SourceAPIs = append(data.SourceAPIs, "Untappd")
+	data.LastUpdated = time.Now()
+	return data, nil
 }
 
 // merge combines enriched data, preferring non-empty values
@@ -130,6 +155,30 @@ func (e *EnrichedWineData) merge(other *EnrichedWineData) {
 	if other.AlcoholLevel > 0 && e.AlcoholLevel == 0 {
 		e.AlcoholLevel = other.AlcoholLevel
 	}
+	if other.Style != "" && e.Style == "" {
+		e.Style = other.Style
+	}
+	if other.IBU > 0 && e.IBU == 0 {
+		e.IBU = other.IBU
+	}
+	if other.AgeStatement > 0 && e.AgeStatement == 0 {
+		e.AgeStatement = other.AgeStatement
+	}
+	if other.Distillery != "" && e.Distillery == "" {
+		e.Distillery = other.Distillery
+	}
+	if other.CaskType != "" && e.CaskType == "" {
+		e.CaskType = other.CaskType
+	}
+	if other.CaskStrength {
+		e.CaskStrength = true
+	}
+	if other.DistilledYear > 0 && e.DistilledYear == 0 {
+		e.DistilledYear = other.DistilledYear
+	}
+	if other.BottledYear > 0 && e.BottledYear == 0 {
+		e.BottledYear = other.BottledYear
+	}
 	if other.Description != "" && e.Description == "" {
 		e.Description = other.Description
 	}
@@ -0,0 +1,82 @@
+package enricher
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var (
+	// "12 Year Old", "12yo", "15 ans", "18 años"
+	ageStatementPattern = regexp.MustCompile(`(?i)\b(\d{1,2})\s*(?:-\s*)?(?:years?(?:[\s-]+old)?|yo\b|y\.o\.|ans\b|años\b)`)
+	// "Cask Strength", "Brut de fût", "Barrel Proof", "Full Proof"
+	caskStrengthPattern = regexp.MustCompile(`(?i)\b(?:cask strength|brut de f[uû]t|barrel proof|full proof)\b`)
+	// "Distilled 1998", "Dist. 1998", "Distillé en 1998"
+	distilledPattern = regexp.MustCompile(`(?i)\b(?:distilled|dist\.|distill[ée]+(?:\s+en)?)\s*((?:18|19|20)\d{2})\b`)
+	// "Bottled 2012", "Bot. 2012", "Embouteillé en 2012"
+	bottledPattern = regexp.MustCompile(`(?i)\b(?:bottled|bot\.|embouteill[ée]+(?:\s+en)?)\s*((?:18|19|20)\d{2})\b`)
+	// "1998-2012" or "1998/2012": distillation then bottling
+	yearRangePattern = regexp.MustCompile(`\b((?:18|19|20)\d{2})\s*[-/]\s*((?:18|19|20)\d{2})\b`)
+)
+
+// caskTypes maps the wood or former content named on a label to a cask type.
+// A keyword only counts next to a cask word, so that "Port Ellen" or "Port Charlotte"
+// are not read as port casks. More specific keywords come first.
+var caskTypes = []struct {
+	pattern  *regexp.Regexp
+	caskType string
+}{
+	{caskPattern(`oloroso`), "Oloroso sherry"},
+	{caskPattern(`pedro xim[eé]nez|px`), "PX sherry"},
+	{caskPattern(`sherry|x[eé]r[eè]s`), "Sherry"},
+	{caskPattern(`bourbon`), "Bourbon"},
+	{caskPattern(`port|porto`), "Port"},
+	{caskPattern(`madeira|mad[eè]re`), "Madeira"},
+	{caskPattern(`sauternes`), "Sauternes"},
+	{caskPattern(`rum|rhum`), "Rum"},
+	{caskPattern(`cognac`), "Cognac"},
+	{caskPattern(`virgin oak`), "Virgin oak"},
+	{caskPattern(`mizunara`), "Mizunara"},
+	{caskPattern(`wine|vin`), "Wine"},
+}
+
+func caskPattern(keywords string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\b(?:` + keywords + `)(?:\s+sherry)?\s+(?:casks?|barrels?|butts?|hogsheads?|finish|wood|matured|f[uû]ts?)\b|\bf[uû]ts?\s+(?:de\s+)?(?:` + keywords + `)\b`)
+}
+
+// parseSpiritLabel reads the age statement, cask and distillation details
+// written in a spirit name, as found on independent bottlings
+func parseSpiritLabel(name string) *EnrichedWineData {
+	data := &EnrichedWineData{}
+
+	if m := ageStatementPattern.FindStringSubmatch(name); m != nil {
+		data.AgeStatement, _ = strconv.Atoi(m[1])
+	}
+	data.CaskStrength = caskStrengthPattern.MatchString(name)
+
+	// "Cask Strength" is not a cask: "Port Ellen Cask Strength" has no port cask
+	label := caskStrengthPattern.ReplaceAllString(name, " ")
+	for _, c := range caskTypes {
+		if c.pattern.MatchString(label) {
+			data.CaskType = c.caskType
+			break
+		}
+	}
+
+	if m := distilledPattern.FindStringSubmatch(name); m != nil {
+		data.DistilledYear, _ = strconv.Atoi(m[1])
+	}
+	if m := bottledPattern.FindStringSubmatch(name); m != nil {
+		data.BottledYear, _ = strconv.Atoi(m[1])
+	}
+	if data.DistilledYear == 0 && data.BottledYear == 0 {
+		if m := yearRangePattern.FindStringSubmatch(name); m != nil {
+			distilled, _ := strconv.Atoi(m[1])
+			bottled, _ := strconv.Atoi(m[2])
+			if bottled >= distilled {
+				data.DistilledYear, data.BottledYear = distilled, bottled
+			}
+		}
+	}
+
+	return data
+}
@@ -0,0 +1,318 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// backupNameFormat horodate les sauvegardes (UTC) ; le nom sert aussi de date de création
+const backupNameFormat = "glou-20060102-150405.db"
+
+// backupNamePattern n'accepte que les noms produits par le BackupManager
+// (aucun séparateur de chemin possible)
+var backupNamePattern = regexp.MustCompile(`^glou-\d{8}-\d{6}\.db$`)
+
+// RetentionPolicy décrit les sauvegardes conservées lors de la rotation.
+// Une sauvegarde est gardée dès qu'une des règles la retient.
+type RetentionPolicy struct {
+	KeepLast   int // les N sauvegardes les plus récentes
+	KeepDaily  int // la plus récente de chacun des N derniers jours ayant une sauvegarde
+	KeepWeekly int // la plus récente de chacune des N dernières semaines ISO ayant une sauvegarde
+}
+
+// BackupManager crée, liste et fait tourner les sauvegardes d'une base
+type BackupManager struct {
+	store  *Store
+	dir    string
+	policy RetentionPolicy
+	mu     sync.Mutex // sérialise création et rotation
+}
+
+// NewBackupManager crée un BackupManager écrivant dans dir
+func NewBackupManager(store *Store, dir string, policy RetentionPolicy) *BackupManager {
+	return &BackupManager{store: store, dir: dir, policy: policy}
+}
+
+// Dir retourne le répertoire de sauvegarde
+func (bm *BackupManager) Dir() string {
+	return bm.dir
+}
+
+// Create produit un instantané cohérent de la base avec VACUUM INTO (sans arrêter le serveur),
+// puis applique la rotation.
+func (bm *BackupManager) Create(ctx context.Context) (*domain.Backup, error) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	if err := os.MkdirAll(bm.dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := time.Now().UTC().Format(backupNameFormat)
+	path := filepath.Join(bm.dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%w: backup %s already exists", ErrConflict, name)
+	}
+
+	// VACUUM INTO écrit d'abord dans un fichier temporaire : une sauvegarde
+	// interrompue n'apparaît jamais sous un nom valide.
+	tmpPath := path + ".tmp"
+	os.Remove(tmpPath)
+	if _, err := bm.store.Db.ExecContext(ctx, `VACUUM INTO ?`, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to finalize backup: %w", err)
+	}
+
+	backup, err := backupInfo(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := bm.rotate(); err != nil {
+		return backup, fmt.Errorf("backup created but rotation failed: %w", err)
+	}
+	return backup, nil
+}
+
+// List retourne les sauvegardes, de la plus récente à la plus ancienne
+func (bm *BackupManager) List() ([]*domain.Backup, error) {
+	entries, err := os.ReadDir(bm.dir)
+	if os.IsNotExist(err) {
+		return []*domain.Backup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	backups := make([]*domain.Backup, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !backupNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		backup, err := backupInfo(filepath.Join(bm.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Path retourne le chemin d'une sauvegarde existante à partir de son nom
+func (bm *BackupManager) Path(name string) (string, error) {
+	if !backupNamePattern.MatchString(name) {
+		return "", NewValidationError("name", fmt.Sprintf("invalid backup name %q", name))
+	}
+	path := filepath.Join(bm.dir, name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: backup %s", ErrNotFound, name)
+	} else if err != nil {
+		return "", fmt.Errorf("failed to stat backup: %w", err)
+	}
+	return path, nil
+}
+
+// Rotate supprime les sauvegardes qui ne sont plus retenues par la politique.
+// Retourne les noms supprimés.
+func (bm *BackupManager) Rotate() ([]string, error) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	return bm.rotate()
+}
+
+func (bm *BackupManager) rotate() ([]string, error) {
+	backups, err := bm.List()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0)
+	for _, backup := range backupsToRemove(backups, bm.policy) {
+		if err := os.Remove(filepath.Join(bm.dir, backup.Name)); err != nil {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", backup.Name, err)
+		}
+		removed = append(removed, backup.Name)
+	}
+	return removed, nil
+}
+
+// backupsToRemove applique la politique de rétention à des sauvegardes triées
+// de la plus récente à la plus ancienne
+func backupsToRemove(backups []*domain.Backup, policy RetentionPolicy) []*domain.Backup {
+	keep := make(map[string]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+
+	for i, backup := range backups {
+		if i < policy.KeepLast {
+			keep[backup.Name] = true
+		}
+		day := backup.CreatedAt.Format("2006-01-02")
+		if !days[day] && len(days) < policy.KeepDaily {
+			days[day] = true
+			keep[backup.Name] = true
+		}
+		year, week := backup.CreatedAt.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+		if !weeks[weekKey] && len(weeks) < policy.KeepWeekly {
+			weeks[weekKey] = true
+			keep[backup.Name] = true
+		}
+	}
+
+	remove := make([]*domain.Backup, 0)
+	for _, backup := range backups {
+		if !keep[backup.Name] {
+			remove = append(remove, backup)
+		}
+	}
+	return remove
+}
+
+// backupInfo lit la taille d'une sauvegarde et déduit sa date de son nom
+func backupInfo(path string) (*domain.Backup, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat backup: %w", err)
+	}
+	createdAt, err := time.Parse(backupNameFormat, info.Name())
+	if err != nil {
+		createdAt = info.ModTime().UTC()
+	}
+	return &domain.Backup{Name: info.Name(), Size: info.Size(), CreatedAt: createdAt}, nil
+}
+
+// InspectBackup vérifie l'intégrité d'un fichier de sauvegarde et retourne sa version de schéma
+func InspectBackup(ctx context.Context, path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, fmt.Errorf("%w: backup %s", ErrNotFound, path)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer db.Close()
+
+	var check string
+	if err := db.QueryRowContext(ctx, `PRAGMA quick_check`).Scan(&check); err != nil {
+		return 0, NewValidationError("backup", fmt.Sprintf("not a valid SQLite database: %v", err))
+	}
+	if check != "ok" {
+		return 0, NewValidationError("backup", "integrity check failed: "+check)
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil || !version.Valid {
+		return 0, NewValidationError("backup", "not a glou database (no schema_migrations)")
+	}
+	return int(version.Int64), nil
+}
+
+// RestoreBackup remplace la base dbPath par la sauvegarde backupPath.
+// Le serveur doit être arrêté. La sauvegarde doit avoir un schéma connu de ce binaire
+// (les migrations manquantes seront appliquées au prochain démarrage).
+// La base courante est conservée à côté (suffixe .pre-restore-<date>) ; son chemin est retourné.
+func RestoreBackup(ctx context.Context, backupPath, dbPath string) (string, error) {
+	if _, err := checkRestorable(ctx, backupPath); err != nil {
+		return "", err
+	}
+
+	// Copie dans un fichier temporaire puis renommage atomique
+	tmpPath := dbPath + ".restore.tmp"
+	if err := copyFile(backupPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to copy backup: %w", err)
+	}
+	return replaceDatabase(tmpPath, dbPath)
+}
+
+// checkRestorable vérifie qu'une base peut remplacer la base courante :
+// intègre et d'un schéma que ce binaire sait migrer. Retourne sa version de schéma.
+func checkRestorable(ctx context.Context, path string) (int, error) {
+	version, err := InspectBackup(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	if version > latest {
+		return 0, NewValidationError("backup", fmt.Sprintf(
+			"backup schema version %d is newer than this server supports (%d): upgrade glou-server first", version, latest))
+	}
+	return version, nil
+}
+
+// replaceDatabase renomme tmpPath en dbPath après avoir conservé une copie de la base courante
+// (et de son WAL éventuel). Retourne le chemin de cette copie.
+func replaceDatabase(tmpPath, dbPath string) (string, error) {
+	safetyCopy := ""
+	if _, err := os.Stat(dbPath); err == nil {
+		safetyCopy = dbPath + ".pre-restore-" + time.Now().UTC().Format("20060102-150405")
+		if err := copyFile(dbPath, safetyCopy); err != nil {
+			os.Remove(tmpPath)
+			return "", fmt.Errorf("failed to keep a copy of the current database: %w", err)
+		}
+		if _, err := os.Stat(dbPath + "-wal"); err == nil {
+			if err := copyFile(dbPath+"-wal", safetyCopy+"-wal"); err != nil {
+				os.Remove(tmpPath)
+				return safetyCopy, fmt.Errorf("failed to keep a copy of the current WAL: %w", err)
+			}
+		}
+	}
+
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		os.Remove(tmpPath)
+		return safetyCopy, fmt.Errorf("failed to replace database: %w", err)
+	}
+
+	// Un journal laissé par l'ancienne base serait rejoué sur la base restaurée
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		os.Remove(dbPath + suffix)
+	}
+
+	return safetyCopy, nil
+}
+
+// copyFile copie src vers dst et force l'écriture sur disque
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
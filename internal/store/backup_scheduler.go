@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// BackupScheduler crée périodiquement une sauvegarde (avec rotation)
+type BackupScheduler struct {
+	backups  *BackupManager
+	ticker   *time.Ticker
+	stopChan chan struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+}
+
+// NewBackupScheduler crée un BackupScheduler
+func NewBackupScheduler(backups *BackupManager) *BackupScheduler {
+	return &BackupScheduler{
+		backups:  backups,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start lance une sauvegarde à chaque intervalle (pas au démarrage)
+func (bs *BackupScheduler) Start(interval time.Duration) {
+	bs.mu.Lock()
+	bs.ticker = time.NewTicker(interval)
+	bs.done = make(chan struct{})
+	bs.mu.Unlock()
+
+	go func() {
+		defer close(bs.done)
+
+		for {
+			select {
+			case <-bs.ticker.C:
+				bs.backup()
+			case <-bs.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// backup crée une sauvegarde et la journalise
+func (bs *BackupScheduler) backup() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	backup, err := bs.backups.Create(ctx)
+	if err != nil {
+		log.Printf("Error creating scheduled backup: %v", err)
+		if backup == nil {
+			return
+		}
+	}
+	log.Printf("Scheduled backup created: %s (%d bytes)", backup.Name, backup.Size)
+	bs.backups.store.LogActivity(ctx, "backup", 0, "backup_created", map[string]interface{}{"name": backup.Name, "size": backup.Size, "scheduled": true}, "")
+}
+
+// Stop arrête les sauvegardes périodiques
+func (bs *BackupScheduler) Stop() {
+	bs.mu.Lock()
+	if bs.ticker != nil {
+		bs.ticker.Stop()
+		bs.ticker = nil
+	}
+	bs.mu.Unlock()
+
+	close(bs.stopChan)
+
+	if bs.done != nil {
+		<-bs.done
+	}
+}
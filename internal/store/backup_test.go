@@ -0,0 +1,43 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+func TestBackupsToRemove(t *testing.T) {
+	backup := func(name, date string) *domain.Backup {
+		createdAt, err := time.Parse("2006-01-02 15:04", date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &domain.Backup{Name: name, CreatedAt: createdAt}
+	}
+
+	// Du plus récent au plus ancien ; le 13/03/2024 est un mercredi (semaine ISO 11)
+	backups := []*domain.Backup{
+		backup("a", "2024-03-13 18:00"),
+		backup("b", "2024-03-13 06:00"),
+		backup("c", "2024-03-12 18:00"),
+		backup("d", "2024-03-11 18:00"),
+		backup("e", "2024-03-08 18:00"), // semaine 10
+		backup("f", "2024-03-07 18:00"),
+		backup("g", "2024-02-28 18:00"), // semaine 9
+	}
+
+	// a, b : deux dernières ; a, c : deux derniers jours ; a, e, g : trois dernières semaines
+	removed := backupsToRemove(backups, RetentionPolicy{KeepLast: 2, KeepDaily: 2, KeepWeekly: 3})
+	if len(removed) != 2 || removed[0].Name != "d" || removed[1].Name != "f" {
+		names := make([]string, 0, len(removed))
+		for _, b := range removed {
+			names = append(names, b.Name)
+		}
+		t.Errorf("expected [d f] removed, got %v", names)
+	}
+
+	if removed := backupsToRemove(backups, RetentionPolicy{KeepLast: 10}); len(removed) != 0 {
+		t.Errorf("expected nothing removed when the policy covers every backup, got %d", len(removed))
+	}
+}
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// caveColumns liste les colonnes lues par scanCave, dans l'ordre
+const caveColumns = `id, name, model, location, capacity, current, created_at, version, layout`
+
+// scanCave lit une ligne sélectionnée avec caveColumns
+func scanCave(row rowScanner) (*domain.Cave, error) {
+	cave := &domain.Cave{}
+	var layout sql.NullString
+	if err := row.Scan(&cave.ID, &cave.Name, &cave.Model, &cave.Location, &cave.Capacity, &cave.Current,
+		&cave.CreatedAt, &cave.Version, &layout); err != nil {
+		return nil, err
+	}
+	if layout.Valid && layout.String != "" {
+		cave.Layout = &domain.CaveLayout{}
+		if err := json.Unmarshal([]byte(layout.String), cave.Layout); err != nil {
+			return nil, fmt.Errorf("invalid layout for cave %d: %w", cave.ID, err)
+		}
+	}
+	return cave, nil
+}
+
+// encodeLayout sérialise l'agencement d'une cave et en déduit sa capacité
+func encodeLayout(cave *domain.Cave) (*string, error) {
+	if cave.Layout == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(cave.Layout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cave layout: %w", err)
+	}
+	cave.Capacity = cave.Layout.Capacity()
+	layout := string(data)
+	return &layout, nil
+}
+
+// cellColumns liste les colonnes lues par scanCell, dans l'ordre
+const cellColumns = `id, cave_id, location, capacity, current, created_at, zone, rack, row_no, column_no`
+
+// scanCell lit une ligne sélectionnée avec cellColumns
+func scanCell(row rowScanner) (*domain.Cell, error) {
+	cell := &domain.Cell{}
+	var zone sql.NullString
+	var rack, rowNo, columnNo sql.NullInt64
+	if err := row.Scan(&cell.ID, &cell.CaveID, &cell.Location, &cell.Capacity, &cell.Current, &cell.CreatedAt,
+		&zone, &rack, &rowNo, &columnNo); err != nil {
+		return nil, err
+	}
+	cell.Zone = zone.String
+	cell.Rack = int(rack.Int64)
+	cell.Row = int(rowNo.Int64)
+	cell.Column = int(columnNo.Int64)
+	return cell, nil
+}
+
+// nullIfEmpty convertit une chaîne vide en NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullIfZero convertit un entier nul en NULL
+func nullIfZero(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// slotPosition identifie un emplacement dans l'agencement
+type slotPosition struct {
+	zone              string
+	rack, row, column int
+}
+
+// syncLayoutCells aligne les emplacements positionnés d'une cave sur son agencement :
+// création des nouveaux, mise à jour de la profondeur, suppression de ceux qui sortent
+// de l'agencement s'ils sont vides. Les emplacements libres (sans position) ne sont pas touchés.
+func syncLayoutCells(ctx context.Context, tx *sql.Tx, caveID int64, layout *domain.CaveLayout) error {
+	type existingCell struct {
+		id                int64
+		capacity, current int
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT id, zone, rack, row_no, column_no, capacity, current FROM cells
+	WHERE cave_id = ? AND zone IS NOT NULL AND deleted_at IS NULL`, caveID)
+	if err != nil {
+		return fmt.Errorf("failed to query cave cells: %w", err)
+	}
+	existing := make(map[slotPosition]existingCell)
+	for rows.Next() {
+		var pos slotPosition
+		var cell existingCell
+		if err := rows.Scan(&cell.id, &pos.zone, &pos.rack, &pos.row, &pos.column, &cell.capacity, &cell.current); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan cave cell: %w", err)
+		}
+		existing[pos] = cell
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate cave cells: %w", err)
+	}
+
+	for _, zone := range layout.Zones {
+		for rack := 1; rack <= zone.Racks; rack++ {
+			for row := 1; row <= zone.Rows; row++ {
+				for column := 1; column <= zone.Columns; column++ {
+					pos := slotPosition{zone.Name, rack, row, column}
+					if cell, ok := existing[pos]; ok {
+						delete(existing, pos)
+						if cell.capacity != zone.Depth {
+							if _, err := tx.ExecContext(ctx, `UPDATE cells SET capacity = ? WHERE id = ?`, zone.Depth, cell.id); err != nil {
+								return fmt.Errorf("failed to resize cell: %w", err)
+							}
+						}
+						continue
+					}
+					if _, err := tx.ExecContext(ctx, `
+					INSERT INTO cells (cave_id, location, capacity, zone, rack, row_no, column_no)
+					VALUES (?, ?, ?, ?, ?, ?, ?)`,
+						caveID, domain.SlotLocation(zone.Name, rack, row, column), zone.Depth,
+						zone.Name, rack, row, column); err != nil {
+						return fmt.Errorf("failed to create cell: %w", err)
+					}
+				}
+			}
+		}
+	}
+
+	// Emplacements sortis de l'agencement
+	var occupied []string
+	for pos, cell := range existing {
+		if cell.current > 0 {
+			occupied = append(occupied, fmt.Sprintf("%s (%d)", domain.SlotLocation(pos.zone, pos.rack, pos.row, pos.column), cell.current))
+		}
+	}
+	if len(occupied) > 0 {
+		return fmt.Errorf("%w: the new layout removes slots that still hold items: %s",
+			ErrConflict, strings.Join(occupied, ", "))
+	}
+	for _, cell := range existing {
+		// Les vins terminés ou à la corbeille perdent simplement leur emplacement
+		for _, query := range []string{
+			`UPDATE wines SET cell_id = NULL WHERE cell_id = ?`,
+			`UPDATE tobaccos SET cell_id = NULL WHERE cell_id = ?`,
+			`DELETE FROM cells WHERE id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, query, cell.id); err != nil {
+				return fmt.Errorf("failed to remove cell: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetCaveLayout retourne la grille d'une cave avec l'occupation et le contenu de chaque emplacement
+func (s *Store) GetCaveLayout(ctx context.Context, caveID int64) (*domain.CaveGrid, error) {
+	cave, err := s.GetCaveByID(ctx, caveID)
+	if err != nil {
+		return nil, err
+	}
+	cells, err := s.GetCellsByCave(ctx, caveID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.QueryContext(ctx, `
+	SELECT 'wine', w.id, w.name, w.vintage, COUNT(*), u.cell_id FROM bottle_units u
+	JOIN wines w ON w.id = u.wine_id
+	WHERE u.cave_id = ? AND u.cell_id IS NOT NULL AND u.state = 'in_stock' AND w.deleted_at IS NULL
+	GROUP BY w.id, u.cell_id
+	UNION ALL
+	SELECT 'tobacco', id, name, NULL, quantity, cell_id FROM tobaccos
+	WHERE cave_id = ? AND cell_id IS NOT NULL AND deleted_at IS NULL AND quantity > 0
+	ORDER BY 3`, caveID, caveID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cave contents: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[int64][]domain.SlotItem)
+	for rows.Next() {
+		var item domain.SlotItem
+		var cellID int64
+		if err := rows.Scan(&item.Type, &item.ID, &item.Name, &item.Vintage, &item.Quantity, &cellID); err != nil {
+			return nil, fmt.Errorf("failed to scan cave content: %w", err)
+		}
+		items[cellID] = append(items[cellID], item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate cave contents: %w", err)
+	}
+
+	slots := make(map[slotPosition]*domain.GridSlot)
+	grid := &domain.CaveGrid{
+		CaveID:   cave.ID,
+		Name:     cave.Name,
+		Capacity: cave.Capacity,
+		Current:  cave.Current,
+		Zones:    make([]domain.GridZone, 0),
+		Unplaced: make([]*domain.GridSlot, 0),
+	}
+	for _, cell := range cells {
+		slot := &domain.GridSlot{
+			Column:   cell.Column,
+			CellID:   cell.ID,
+			Location: cell.Location,
+			Capacity: cell.Capacity,
+			Current:  cell.Current,
+			Items:    items[cell.ID],
+		}
+		if slot.Items == nil {
+			slot.Items = make([]domain.SlotItem, 0)
+		}
+		if cell.Zone == "" {
+			grid.Unplaced = append(grid.Unplaced, slot)
+			continue
+		}
+		slots[slotPosition{cell.Zone, cell.Rack, cell.Row, cell.Column}] = slot
+	}
+
+	if cave.Layout != nil {
+		for _, zone := range cave.Layout.Zones {
+			gz := domain.GridZone{Name: zone.Name, Depth: zone.Depth, Racks: make([]domain.GridRack, 0, zone.Racks)}
+			for rack := 1; rack <= zone.Racks; rack++ {
+				gr := domain.GridRack{Rack: rack, Rows: make([]domain.GridRow, 0, zone.Rows)}
+				for row := 1; row <= zone.Rows; row++ {
+					grow := domain.GridRow{Row: row, Slots: make([]*domain.GridSlot, 0, zone.Columns)}
+					for column := 1; column <= zone.Columns; column++ {
+						pos := slotPosition{zone.Name, rack, row, column}
+						if slot, ok := slots[pos]; ok {
+							grow.Slots = append(grow.Slots, slot)
+							delete(slots, pos)
+						}
+					}
+					gr.Rows = append(gr.Rows, grow)
+				}
+				gz.Racks = append(gz.Racks, gr)
+			}
+			grid.Zones = append(grid.Zones, gz)
+		}
+	}
+
+	// Emplacements positionnés hors de l'agencement courant (agencement retiré)
+	for _, cell := range cells {
+		if slot, ok := slots[slotPosition{cell.Zone, cell.Rack, cell.Row, cell.Column}]; ok && cell.Zone != "" {
+			grid.Unplaced = append(grid.Unplaced, slot)
+		}
+	}
+
+	return grid, nil
+}
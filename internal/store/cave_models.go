@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// Catalogue intégré de modèles du commerce. Les agencements sont indicatifs
+// (clayettes standard, bouteilles bordelaises) : la capacité réelle varie selon les formats.
+//
+//go:embed catalog/cave_models.json
+var builtinCaveModelsJSON []byte
+
+var (
+	builtinCaveModelsOnce sync.Once
+	builtinCaveModels     map[string]*domain.CaveModel
+	builtinCaveModelsErr  error
+)
+
+// loadBuiltinCaveModels décode le catalogue intégré (une seule fois)
+func loadBuiltinCaveModels() (map[string]*domain.CaveModel, error) {
+	builtinCaveModelsOnce.Do(func() {
+		var models []*domain.CaveModel
+		if err := json.Unmarshal(builtinCaveModelsJSON, &models); err != nil {
+			builtinCaveModelsErr = fmt.Errorf("invalid built-in cave model catalog: %w", err)
+			return
+		}
+		builtinCaveModels = make(map[string]*domain.CaveModel, len(models))
+		for _, m := range models {
+			m.BuiltIn = true
+			m.Capacity = m.Layout.Capacity()
+			builtinCaveModels[m.ID] = m
+		}
+	})
+	return builtinCaveModels, builtinCaveModelsErr
+}
+
+// cloneCaveModel copie un modèle du catalogue pour que l'appelant puisse le modifier
+func cloneCaveModel(m *domain.CaveModel) *domain.CaveModel {
+	c := *m
+	c.Layout.Zones = append([]domain.CaveZone(nil), m.Layout.Zones...)
+	return &c
+}
+
+// scanCaveModel lit une ligne de cave_models (id, brand, name, layout, created_at)
+func scanCaveModel(row rowScanner) (*domain.CaveModel, error) {
+	m := &domain.CaveModel{}
+	var layout string
+	var createdAt sql.NullTime
+	if err := row.Scan(&m.ID, &m.Brand, &m.Name, &layout, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(layout), &m.Layout); err != nil {
+		return nil, fmt.Errorf("invalid layout for cave model %s: %w", m.ID, err)
+	}
+	if createdAt.Valid {
+		m.CreatedAt = &createdAt.Time
+	}
+	m.Capacity = m.Layout.Capacity()
+	return m, nil
+}
+
+// ListCaveModels retourne le catalogue intégré et les modèles personnalisés, triés par marque et nom
+func (s *Store) ListCaveModels(ctx context.Context) ([]*domain.CaveModel, error) {
+	builtins, err := loadBuiltinCaveModels()
+	if err != nil {
+		return nil, err
+	}
+	models := make([]*domain.CaveModel, 0, len(builtins))
+	for _, m := range builtins {
+		models = append(models, cloneCaveModel(m))
+	}
+
+	rows, err := s.Db.QueryContext(ctx, `SELECT id, brand, name, layout, created_at FROM cave_models`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cave models: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		m, err := scanCaveModel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cave model: %w", err)
+		}
+		models = append(models, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate cave models: %w", err)
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].Brand != models[j].Brand {
+			return models[i].Brand < models[j].Brand
+		}
+		if models[i].Name != models[j].Name {
+			return models[i].Name < models[j].Name
+		}
+		return models[i].ID < models[j].ID
+	})
+	return models, nil
+}
+
+// GetCaveModel retourne un modèle du catalogue intégré ou personnalisé
+func (s *Store) GetCaveModel(ctx context.Context, id string) (*domain.CaveModel, error) {
+	builtins, err := loadBuiltinCaveModels()
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := builtins[id]; ok {
+		return cloneCaveModel(m), nil
+	}
+
+	m, err := scanCaveModel(s.Db.QueryRowContext(ctx,
+		`SELECT id, brand, name, layout, created_at FROM cave_models WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: cave model %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cave model: %w", err)
+	}
+	return m, nil
+}
+
+// CreateCaveModel ajoute un modèle personnalisé ; l'identifiant ne doit pas déjà exister
+func (s *Store) CreateCaveModel(ctx context.Context, m *domain.CaveModel) error {
+	builtins, err := loadBuiltinCaveModels()
+	if err != nil {
+		return err
+	}
+	if _, ok := builtins[m.ID]; ok {
+		return fmt.Errorf("%w: cave model %s is part of the built-in catalog", ErrConflict, m.ID)
+	}
+
+	layout, err := json.Marshal(m.Layout)
+	if err != nil {
+		return fmt.Errorf("failed to encode cave model layout: %w", err)
+	}
+	_, err = s.Db.ExecContext(ctx,
+		`INSERT INTO cave_models (id, brand, name, layout) VALUES (?, ?, ?, ?)`,
+		m.ID, m.Brand, m.Name, string(layout))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: cave model %s already exists", ErrConflict, m.ID)
+		}
+		return fmt.Errorf("failed to create cave model: %w", err)
+	}
+	return nil
+}
+
+// UpdateCaveModel remplace un modèle personnalisé ; le catalogue intégré est en lecture seule.
+// Les caves déjà créées depuis ce modèle gardent leur agencement.
+func (s *Store) UpdateCaveModel(ctx context.Context, m *domain.CaveModel) error {
+	if err := s.checkCustomCaveModel(m.ID); err != nil {
+		return err
+	}
+
+	layout, err := json.Marshal(m.Layout)
+	if err != nil {
+		return fmt.Errorf("failed to encode cave model layout: %w", err)
+	}
+	result, err := s.Db.ExecContext(ctx,
+		`UPDATE cave_models SET brand = ?, name = ?, layout = ? WHERE id = ?`,
+		m.Brand, m.Name, string(layout), m.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update cave model: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: cave model %s", ErrNotFound, m.ID)
+	}
+	return nil
+}
+
+// DeleteCaveModel supprime un modèle personnalisé ; le catalogue intégré est en lecture seule
+func (s *Store) DeleteCaveModel(ctx context.Context, id string) error {
+	if err := s.checkCustomCaveModel(id); err != nil {
+		return err
+	}
+
+	result, err := s.Db.ExecContext(ctx, `DELETE FROM cave_models WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete cave model: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: cave model %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+// checkCustomCaveModel refuse (ErrConflict) la modification d'un modèle intégré
+func (s *Store) checkCustomCaveModel(id string) error {
+	builtins, err := loadBuiltinCaveModels()
+	if err != nil {
+		return err
+	}
+	if _, ok := builtins[id]; ok {
+		return fmt.Errorf("%w: built-in cave model %s cannot be modified", ErrConflict, id)
+	}
+	return nil
+}
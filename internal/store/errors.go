@@ -0,0 +1,78 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Erreurs sentinelles du store, à tester avec errors.Is
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	// ErrPreconditionFailed signale une mise à jour conditionnelle sur une version périmée
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// FieldError décrit un champ invalide
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError regroupe les erreurs de validation par champ.
+// errors.Is(err, ErrValidation) est vrai pour toute ValidationError.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError crée une erreur de validation portant sur un seul champ
+func NewValidationError(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// Add ajoute une erreur sur un champ
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err retourne nil si aucun champ n'est invalide (évite le piège de l'interface nil)
+func (e *ValidationError) Err() error {
+	if e == nil || len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error implémente l'interface error
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is rattache toute ValidationError à ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// notFound construit une erreur ErrNotFound pour une entité
+func notFound(entity string, id int64) error {
+	return fmt.Errorf("%w: %s %d", ErrNotFound, entity, id)
+}
+
+// isUniqueViolation indique si l'erreur SQLite est une violation d'unicité
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
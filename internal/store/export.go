@@ -18,6 +18,7 @@ type ExportData struct {
 	Caves      []*domain.Cave               `json:"caves"`
 	Cells      []*domain.Cell               `json:"cells"`
 	Wines      []*domain.Wine               `json:"wines"`
+	Grapes     []*domain.GrapeVariety       `json:"grape_varieties"`
 	Alerts     []*domain.Alert              `json:"alerts"`
 	History    []*domain.ConsumptionHistory `json:"consumption_history"`
 }
@@ -39,6 +40,11 @@ func (s *Store) ExportJSON(ctx context.Context) ([]byte, error) {
 		return nil, fmt.Errorf("failed to fetch wines: %w", err)
 	}
 
+	grapes, err := s.GetGrapes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch grape varieties: %w", err)
+	}
+
 	alerts, err := s.GetAlerts(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch alerts: %w", err)
@@ -55,6 +61,7 @@ func (s *Store) ExportJSON(ctx context.Context) ([]byte, error) {
 		Caves:      caves,
 		Cells:      cells,
 		Wines:      wines,
+		Grapes:     grapes,
 		Alerts:     alerts,
 		History:    history,
 	}
@@ -81,7 +88,10 @@ func (s *Store) ExportWinesCSV(ctx context.Context, w io.Writer) error {
 	headers := []string{
 		"ID", "Name", "Region", "Vintage", "Type", "Quantity", "Producer",
 		"Alcohol Level", "Price", "Rating", "Comments", "Consumed",
-		"Min Apogee Date", "Max Apogee Date", "Cell ID", "Created At",
+		"Min Apogee Date", "Max Apogee Date", "Cave ID", "Cell ID", "Bar Code", "External ID", "Status", "Created At", "Grapes", "Format", "Volume (ml)",
+		"Style", "IBU", "EBC", "Packaged At", "Best Before",
+		"Age Statement", "Distillery", "Cask Type", "Cask Number", "Distilled Year", "Bottled Year",
+		"Cask Strength", "Independent Bottler",
 	}
 	if err := writer.Write(headers); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
@@ -104,8 +114,28 @@ func (s *Store) ExportWinesCSV(ctx context.Context, w io.Writer) error {
 			fmt.Sprintf("%d", wine.Consumed),
 			formatDate(wine.MinApogeeDate),
 			formatDate(wine.MaxApogeeDate),
+			formatInt64Ptr(wine.CaveID),
 			formatInt64Ptr(wine.CellID),
+			wine.BarCode,
+			wine.ExternalID,
+			wine.Status,
 			wine.CreatedAt.Format(time.RFC3339),
+			formatBlend(wine.Blend),
+			wine.Format,
+			fmt.Sprintf("%d", wine.VolumeML),
+			wine.Style,
+			formatIntPtr(wine.IBU),
+			formatIntPtr(wine.EBC),
+			formatDate(wine.PackagedAt),
+			formatDate(wine.BestBefore),
+			formatIntPtr(wine.AgeStatement),
+			wine.Distillery,
+			wine.CaskType,
+			wine.CaskNumber,
+			formatIntPtr(wine.DistilledYear),
+			formatIntPtr(wine.BottledYear),
+			fmt.Sprintf("%t", wine.CaskStrength),
+			wine.IndependentBottler,
 		}
 		if err := writer.Write(row); err != nil {
 			return fmt.Errorf("failed to write CSV row: %w", err)
@@ -207,10 +237,14 @@ func (s *Store) ImportJSON(ctx context.Context, data []byte) error {
 	// Importer les caves
 	caveMap := make(map[int64]int64) // old ID -> new ID
 	for _, cave := range importData.Caves {
+		layout, err := encodeLayout(cave)
+		if err != nil {
+			return err
+		}
 		result, err := tx.ExecContext(ctx,
-			`INSERT INTO caves (name, model, location, capacity, current, created_at) 
-			 VALUES (?, ?, ?, ?, ?, ?)`,
-			cave.Name, cave.Model, cave.Location, cave.Capacity, cave.Current, cave.CreatedAt,
+			`INSERT INTO caves (name, model, location, capacity, current, created_at, layout) 
+			 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			cave.Name, cave.Model, cave.Location, cave.Capacity, cave.Current, cave.CreatedAt, layout,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to import cave: %w", err)
@@ -224,9 +258,10 @@ func (s *Store) ImportJSON(ctx context.Context, data []byte) error {
 	for _, cell := range importData.Cells {
 		newCaveID := caveMap[cell.CaveID]
 		result, err := tx.ExecContext(ctx,
-			`INSERT INTO cells (cave_id, location, capacity, current, created_at) 
-			 VALUES (?, ?, ?, ?, ?)`,
+			`INSERT INTO cells (cave_id, location, capacity, current, created_at, zone, rack, row_no, column_no) 
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 			newCaveID, cell.Location, cell.Capacity, cell.Current, cell.CreatedAt,
+			nullIfEmpty(cell.Zone), nullIfZero(cell.Rack), nullIfZero(cell.Row), nullIfZero(cell.Column),
 		)
 		if err != nil {
 			return fmt.Errorf("failed to import cell: %w", err)
@@ -235,30 +270,82 @@ func (s *Store) ImportJSON(ctx context.Context, data []byte) error {
 		cellMap[cell.ID] = newID
 	}
 
+	// Importer les cépages absents du référentiel (nom ou synonyme inconnu)
+	for _, grape := range importData.Grapes {
+		existing, err := findGrape(ctx, tx, grape.Name)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			if _, err := insertGrape(ctx, tx, grape); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Importer les wines
 	wineMap := make(map[int64]int64)
 	for _, wine := range importData.Wines {
+		if wine.Quantity < 0 || wine.Quantity > domain.MaxBottleQuantity {
+			return NewValidationError("wines", fmt.Sprintf("wine %d: quantity must be between 0 and %d", wine.ID, domain.MaxBottleQuantity))
+		}
 		var newCellID *int64
 		if wine.CellID != nil {
 			id := cellMap[*wine.CellID]
 			newCellID = &id
 		}
 
+		var newCaveID *int64
+		if wine.CaveID != nil {
+			id := caveMap[*wine.CaveID]
+			newCaveID = &id
+		}
+
+		wineType := bottleType(wine)
+		if bt, ok := domain.NormalizeBottleType(wineType); ok {
+			wineType = bt
+		}
+		// Les exports antérieurs aux formats prennent le format usuel du type
+		if err := resolveFormat(wine); err != nil || wine.Format == "" {
+			f := domain.DefaultBottleFormat(wineType)
+			wine.Format, wine.VolumeML = f.ID, f.VolumeML
+		}
+
 		result, err := tx.ExecContext(ctx,
-			`INSERT INTO wines (name, region, vintage, type, quantity, cell_id, producer, 
-			 alcohol_level, price, rating, comments, consumed, min_apogee_date, 
-			 max_apogee_date, consumption_date, created_at) 
-			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			wine.Name, wine.Region, wine.Vintage, wine.WineType, wine.Quantity, newCellID,
-			wine.Producer, wine.AlcoholLevel, wine.Price, wine.Rating, wine.Comments,
+			`INSERT INTO wines (name, region, appellation_id, vintage, type, quantity, format, volume_ml, cell_id, cave_id, user_id, producer, 
+			 alcohol_level, price, current_value, rating, comments, consumed, min_apogee_date, 
+			 max_apogee_date, consumption_date, bar_code, image, external_id, status, finished_at,
+			 style, ibu, ebc, packaged_at, best_before, age_statement, distillery, cask_type, cask_number,
+			 distilled_year, bottled_year, cask_strength, independent_bottler, created_at) 
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			 ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			wine.Name, wine.Region, knownAppellation(wine.AppellationID), wine.Vintage, wineType, wine.Quantity,
+			wine.Format, wine.VolumeML, newCellID, newCaveID, wine.UserID,
+			wine.Producer, wine.AlcoholLevel, wine.Price, wine.CurrentValue, wine.Rating, wine.Comments,
 			wine.Consumed, wine.MinApogeeDate, wine.MaxApogeeDate, wine.ConsumptionDate,
-			wine.CreatedAt,
+			wine.BarCode, wine.Image, wine.ExternalID, wineStatus(wine.Quantity), wine.FinishedAt,
+			wine.Style, wine.IBU, wine.EBC, wine.PackagedAt, wine.BestBefore,
+			wine.AgeStatement, wine.Distillery, wine.CaskType, wine.CaskNumber, wine.DistilledYear, wine.BottledYear,
+			wine.CaskStrength, wine.IndependentBottler, wine.CreatedAt,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to import wine: %w", err)
 		}
 		newID, _ := result.LastInsertId()
 		wineMap[wine.ID] = newID
+
+		// Les cépages sont retrouvés par leur nom : les ID diffèrent d'une base à l'autre
+		for _, c := range wine.Blend {
+			c.GrapeID = 0
+		}
+		if err := saveBlend(ctx, tx, newID, wine.Blend); err != nil {
+			return err
+		}
+	}
+
+	// Rattacher les vins importés à leurs producteurs
+	if err := linkProducers(ctx, tx); err != nil {
+		return err
 	}
 
 	// Importer les alertes
@@ -278,9 +365,9 @@ func (s *Store) ImportJSON(ctx context.Context, data []byte) error {
 	for _, entry := range importData.History {
 		newWineID := wineMap[entry.WineID]
 		_, err := tx.ExecContext(ctx,
-			`INSERT INTO consumption_history (wine_id, quantity, rating, comment, reason, date, created_at) 
-			 VALUES (?, ?, ?, ?, ?, ?, ?)`,
-			newWineID, entry.Quantity, entry.Rating, entry.Comment, entry.Reason, entry.Date, entry.CreatedAt,
+			`INSERT INTO consumption_history (wine_id, quantity, volume_ml, glasses, glass_ml, rating, comment, reason, date, created_at) 
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			newWineID, entry.Quantity, entry.VolumeML, entry.Glasses, entry.GlassML, entry.Rating, entry.Comment, entry.Reason, entry.Date, entry.CreatedAt,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to import consumption history: %w", err)
@@ -309,10 +396,17 @@ func formatInt64Ptr(i *int64) string {
 	return fmt.Sprintf("%d", *i)
 }
 
+func formatIntPtr(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *i)
+}
+
 // GetAllCells récupère toutes les cellules
 func (s *Store) GetAllCells(ctx context.Context) ([]*domain.Cell, error) {
 	rows, err := s.Db.QueryContext(ctx, `
-		SELECT id, cave_id, location, capacity, current, created_at FROM cells
+		SELECT `+cellColumns+` FROM cells WHERE deleted_at IS NULL
 	`)
 	if err != nil {
 		return nil, err
@@ -321,11 +415,11 @@ func (s *Store) GetAllCells(ctx context.Context) ([]*domain.Cell, error) {
 
 	var cells []*domain.Cell
 	for rows.Next() {
-		var cell domain.Cell
-		if err := rows.Scan(&cell.ID, &cell.CaveID, &cell.Location, &cell.Capacity, &cell.Current, &cell.CreatedAt); err != nil {
+		cell, err := scanCell(rows)
+		if err != nil {
 			return nil, err
 		}
-		cells = append(cells, &cell)
+		cells = append(cells, cell)
 	}
 
 	return cells, rows.Err()
@@ -334,7 +428,7 @@ func (s *Store) GetAllCells(ctx context.Context) ([]*domain.Cell, error) {
 // GetAllConsumptionHistory récupère tout l'historique de consommation
 func (s *Store) GetAllConsumptionHistory(ctx context.Context) ([]*domain.ConsumptionHistory, error) {
 	rows, err := s.Db.QueryContext(ctx, `
-		SELECT id, wine_id, quantity, rating, comment, reason, date, created_at FROM consumption_history
+		SELECT id, wine_id, quantity, volume_ml, glasses, glass_ml, rating, comment, reason, date, created_at FROM consumption_history
 	`)
 	if err != nil {
 		return nil, err
@@ -344,7 +438,7 @@ func (s *Store) GetAllConsumptionHistory(ctx context.Context) ([]*domain.Consump
 	var history []*domain.ConsumptionHistory
 	for rows.Next() {
 		var entry domain.ConsumptionHistory
-		if err := rows.Scan(&entry.ID, &entry.WineID, &entry.Quantity, &entry.Rating, &entry.Comment, &entry.Reason, &entry.Date, &entry.CreatedAt); err != nil {
+		if err := rows.Scan(&entry.ID, &entry.WineID, &entry.Quantity, &entry.VolumeML, &entry.Glasses, &entry.GlassML, &entry.Rating, &entry.Comment, &entry.Reason, &entry.Date, &entry.CreatedAt); err != nil {
 			return nil, err
 		}
 		history = append(history, &entry)
@@ -0,0 +1,249 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// resolveFormat complète le format ou le volume d'un vin à partir de l'autre : un format
+// connu impose son volume, un volume seul désigne le format correspondant (ou custom).
+// Sans l'un ni l'autre, le vin garde son format (insertWine applique celui du type).
+func resolveFormat(wine *domain.Wine) error {
+	switch {
+	case wine.Format == "" && wine.VolumeML == 0:
+		return nil
+	case wine.Format == "":
+		wine.Format = domain.FormatForVolume(bottleType(wine), wine.VolumeML)
+		return nil
+	case wine.Format == domain.FormatCustom:
+		if wine.VolumeML <= 0 {
+			return NewValidationError("volume_ml", "volume_ml is required for a custom format")
+		}
+		return nil
+	}
+
+	f, ok := domain.FindBottleFormat(wine.Format)
+	if !ok {
+		return NewValidationError("format", fmt.Sprintf("unknown bottle format %q", wine.Format))
+	}
+	wine.VolumeML = f.VolumeML
+	return nil
+}
+
+// recordPour enregistre une dégustation au verre ou en ml, servie depuis une bouteille ouverte.
+// Sans UnitID, la bouteille ouverte la plus ancienne sert, sinon une bouteille en stock est ouverte.
+// La bouteille vidée passe à l'état consumed et compte comme une bouteille bue.
+func (s *Store) recordPour(ctx context.Context, consumption *domain.ConsumptionHistory) (int64, error) {
+	verr := &ValidationError{}
+	if consumption.Quantity != 0 {
+		verr.Add("quantity", "quantity cannot be combined with volume_ml or glasses")
+	}
+	if consumption.VolumeML != nil && consumption.Glasses != nil {
+		verr.Add("volume_ml", "give either volume_ml or glasses")
+	}
+	if consumption.VolumeML != nil && *consumption.VolumeML <= 0 {
+		verr.Add("volume_ml", "volume_ml must be greater than 0")
+	}
+	if consumption.Glasses != nil && *consumption.Glasses <= 0 {
+		verr.Add("glasses", "glasses must be greater than 0")
+	}
+	if consumption.GlassML != nil && *consumption.GlassML <= 0 {
+		verr.Add("glass_ml", "glass_ml must be greater than 0")
+	}
+	if err := verr.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var unit *domain.BottleUnit
+	if consumption.UnitID != nil {
+		if unit, err = getUnit(ctx, tx, `u.id = ?`, *consumption.UnitID); err != nil {
+			return 0, err
+		}
+		if consumption.WineID == 0 {
+			consumption.WineID = unit.WineID
+		}
+		if unit.WineID != consumption.WineID {
+			return 0, NewValidationError("unit_id", fmt.Sprintf("bottle unit %d does not belong to wine %d", unit.ID, consumption.WineID))
+		}
+		if unit.State != domain.UnitStateInStock && unit.State != domain.UnitStateOpened {
+			return 0, fmt.Errorf("%w: bottle unit %d is already %s", ErrConflict, unit.ID, unit.State)
+		}
+	}
+
+	var wineType string
+	var bottleML int
+	err = tx.QueryRowContext(ctx, `SELECT type, volume_ml FROM wines WHERE id = ? AND deleted_at IS NULL`, consumption.WineID).
+		Scan(&wineType, &bottleML)
+	if err == sql.ErrNoRows {
+		return 0, notFound("wine", consumption.WineID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query wine: %w", err)
+	}
+
+	if consumption.Glasses != nil {
+		if consumption.GlassML == nil {
+			glassML := domain.DefaultGlassML(wineType)
+			consumption.GlassML = &glassML
+		}
+		volume := int(math.Round(*consumption.Glasses * float64(*consumption.GlassML)))
+		consumption.VolumeML = &volume
+	} else {
+		consumption.GlassML = nil
+	}
+
+	if unit == nil {
+		var unitID int64
+		err := tx.QueryRowContext(ctx, `
+		SELECT id FROM bottle_units WHERE wine_id = ? AND state IN ('opened', 'in_stock')
+		ORDER BY state <> 'opened', CASE WHEN state = 'opened' THEN id ELSE -id END LIMIT 1`, consumption.WineID).Scan(&unitID)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%w: wine %d has no opened or in-stock bottle", ErrConflict, consumption.WineID)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to query bottle units: %w", err)
+		}
+		if unit, err = getUnit(ctx, tx, `u.id = ?`, unitID); err != nil {
+			return 0, err
+		}
+		consumption.UnitID = &unit.ID
+	}
+
+	remaining := bottleML
+	if unit.State == domain.UnitStateOpened && unit.RemainingML != nil {
+		remaining = *unit.RemainingML
+	}
+	if *consumption.VolumeML > remaining {
+		field := "volume_ml"
+		if consumption.Glasses != nil {
+			field = "glasses"
+		}
+		return 0, NewValidationError(field, fmt.Sprintf("only %d ml left in bottle unit %d", remaining, unit.ID))
+	}
+	left := remaining - *consumption.VolumeML
+	state := domain.UnitStateOpened
+	consumption.Quantity = 0
+	if left == 0 {
+		state = domain.UnitStateConsumed
+		consumption.Quantity = 1
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE bottle_units SET state = ?, remaining_ml = ?, opened_at = COALESCE(opened_at, ?),
+		state_changed_at = CASE WHEN state <> ? THEN ? ELSE state_changed_at END
+	WHERE id = ?`, state, left, time.Now(), state, time.Now(), unit.ID); err != nil {
+		return 0, fmt.Errorf("failed to pour from bottle unit: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+	INSERT INTO consumption_history (wine_id, unit_id, quantity, volume_ml, glasses, glass_ml, rating, comment, reason, date)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		consumption.WineID, consumption.UnitID, consumption.Quantity, consumption.VolumeML, consumption.Glasses,
+		consumption.GlassML, consumption.Rating, consumption.Comment, consumption.Reason, consumption.Date)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record consumption: %w", err)
+	}
+	consumptionID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get consumption id: %w", err)
+	}
+
+	// Une bouteille ouverte sort du stock ; vidée, elle compte parmi les bouteilles bues
+	if unit.State == domain.UnitStateInStock {
+		if err := refreshWineQuantity(ctx, tx, consumption.WineID); err != nil {
+			return 0, err
+		}
+	}
+	if consumption.Quantity > 0 {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE wines SET consumed = consumed + ?, version = version + 1 WHERE id = ?`,
+			consumption.Quantity, consumption.WineID); err != nil {
+			return 0, fmt.Errorf("failed to update wine consumption: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return consumptionID, nil
+}
+
+// GetInventory agrège les bouteilles en stock et ouvertes par type et format,
+// en bouteilles, en litres et en valeur (hors cigares et corbeille).
+// Une bouteille ouverte est valorisée au prorata de son reste.
+func (s *Store) GetInventory(ctx context.Context) (*domain.Inventory, error) {
+	rows, err := s.Db.QueryContext(ctx, `
+	WITH stock AS (
+		SELECT w.id AS wine_id, w.type, w.format, w.volume_ml, u.state,
+			COALESCE(u.remaining_ml, w.volume_ml) AS left_ml,
+			CASE WHEN u.state = 'in_stock' THEN 1.0
+				ELSE COALESCE(u.remaining_ml, w.volume_ml) * 1.0 / MAX(w.volume_ml, 1) END AS share,
+			COALESCE(u.purchase_price, w.price, 0) AS price,
+			COALESCE(w.current_value, u.purchase_price, w.price, 0) AS value
+		FROM bottle_units u JOIN wines w ON w.id = u.wine_id
+		WHERE w.deleted_at IS NULL AND w.type <> 'cigar' AND u.state IN ('in_stock', 'opened')
+	)
+	SELECT type, format, volume_ml, COUNT(DISTINCT wine_id),
+		SUM(state = 'in_stock'), SUM(state = 'opened'),
+		SUM(CASE WHEN state = 'opened' THEN left_ml ELSE 0 END),
+		SUM(share * price), SUM(share * value)
+	FROM stock
+	GROUP BY type, format, volume_ml
+	ORDER BY type, volume_ml, format`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inventory: %w", err)
+	}
+	defer rows.Close()
+
+	inv := &domain.Inventory{Lines: make([]*domain.InventoryLine, 0)}
+	for rows.Next() {
+		line := &domain.InventoryLine{}
+		var openedML int
+		if err := rows.Scan(&line.BottleType, &line.Format, &line.VolumeML, &line.Wines,
+			&line.Bottles, &line.OpenedBottles, &openedML, &line.PurchaseValue, &line.CurrentValue); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory row: %w", err)
+		}
+		line.Liters = roundTo(float64(line.Bottles*line.VolumeML)/1000, 3)
+		line.OpenedLiters = roundTo(float64(openedML)/1000, 3)
+		line.PurchaseValue = roundTo(line.PurchaseValue, 2)
+		line.CurrentValue = roundTo(line.CurrentValue, 2)
+
+		inv.Bottles += line.Bottles
+		inv.OpenedBottles += line.OpenedBottles
+		inv.Liters += line.Liters
+		inv.OpenedLiters += line.OpenedLiters
+		inv.PurchaseValue += line.PurchaseValue
+		inv.CurrentValue += line.CurrentValue
+		inv.Lines = append(inv.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating inventory rows: %w", err)
+	}
+
+	inv.Liters = roundTo(inv.Liters, 3)
+	inv.OpenedLiters = roundTo(inv.OpenedLiters, 3)
+	inv.PurchaseValue = roundTo(inv.PurchaseValue, 2)
+	inv.CurrentValue = roundTo(inv.CurrentValue, 2)
+	if liters := inv.Liters + inv.OpenedLiters; liters > 0 {
+		perLiter := roundTo(inv.CurrentValue/liters, 2)
+		inv.ValuePerLiter = &perLiter
+	}
+	return inv, nil
+}
+
+// roundTo arrondit à n décimales
+func roundTo(x float64, n int) float64 {
+	p := math.Pow(10, float64(n))
+	return math.Round(x*p) / p
+}
@@ -0,0 +1,406 @@
+package store
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// Référentiel géographique intégré : pays, régions viticoles et appellations avec
+// leurs coordonnées (approximatives, centre de la zone). Les vins s'y rattachent par
+// appellation_id ; à défaut, leur région en texte libre est rapprochée des noms connus.
+//
+//go:embed catalog/geo.json
+var gazetteerJSON []byte
+
+// gazetteer est le référentiel décodé et indexé
+type gazetteer struct {
+	countries    []*domain.Country
+	regions      map[string]*domain.GeoRegion
+	appellations map[string]*domain.Appellation
+	// Clés normalisées (domain.NormalizePlaceName) des noms et alias
+	regionsByKey      map[string]*domain.GeoRegion
+	appellationsByKey map[string]*domain.Appellation
+	countriesByKey    map[string]*domain.Country
+}
+
+var (
+	gazetteerOnce sync.Once
+	gazetteerData *gazetteer
+	gazetteerErr  error
+)
+
+// loadGazetteer décode le référentiel intégré (une seule fois)
+func loadGazetteer() (*gazetteer, error) {
+	gazetteerOnce.Do(func() {
+		var countries []*domain.Country
+		if err := json.Unmarshal(gazetteerJSON, &countries); err != nil {
+			gazetteerErr = fmt.Errorf("invalid built-in gazetteer: %w", err)
+			return
+		}
+		g := &gazetteer{
+			countries:         countries,
+			regions:           make(map[string]*domain.GeoRegion),
+			appellations:      make(map[string]*domain.Appellation),
+			regionsByKey:      make(map[string]*domain.GeoRegion),
+			appellationsByKey: make(map[string]*domain.Appellation),
+			countriesByKey:    make(map[string]*domain.Country),
+		}
+		for _, c := range countries {
+			g.countriesByKey[domain.NormalizePlaceName(c.Name)] = c
+			for _, r := range c.Regions {
+				r.CountryCode, r.Country = c.Code, c.Name
+				r.AppellationCount = len(r.Appellations)
+				g.regions[r.ID] = r
+				seen := map[string]bool{}
+				for _, a := range r.Appellations {
+					a.RegionID, a.Region = r.ID, r.Name
+					a.CountryCode, a.Country = c.Code, c.Name
+					if a.SubRegion != "" && !seen[a.SubRegion] {
+						seen[a.SubRegion] = true
+						r.SubRegions = append(r.SubRegions, a.SubRegion)
+					}
+					g.appellations[a.ID] = a
+					for _, name := range append([]string{a.Name}, a.Aliases...) {
+						if key := domain.NormalizePlaceName(name); g.appellationsByKey[key] == nil {
+							g.appellationsByKey[key] = a
+						}
+					}
+				}
+				for _, name := range append([]string{r.Name}, r.Aliases...) {
+					if key := domain.NormalizePlaceName(name); g.regionsByKey[key] == nil {
+						g.regionsByKey[key] = r
+					}
+				}
+			}
+		}
+		gazetteerData = g
+	})
+	return gazetteerData, gazetteerErr
+}
+
+// findAppellation retourne une appellation du référentiel par son identifiant
+func findAppellation(id string) (*domain.Appellation, error) {
+	g, err := loadGazetteer()
+	if err != nil {
+		return nil, err
+	}
+	a, ok := g.appellations[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: appellation %s", ErrNotFound, id)
+	}
+	return a, nil
+}
+
+// resolveAppellation vérifie l'appellation d'un vin et en déduit la région si elle est vide
+func resolveAppellation(wine *domain.Wine) error {
+	if wine.AppellationID == nil {
+		return nil
+	}
+	id := strings.TrimSpace(*wine.AppellationID)
+	if id == "" {
+		wine.AppellationID = nil
+		return nil
+	}
+	a, err := findAppellation(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return NewValidationError("appellation_id", "unknown appellation: "+id)
+		}
+		return err
+	}
+	wine.AppellationID = &a.ID
+	if strings.TrimSpace(wine.Region) == "" {
+		wine.Region = a.Region
+	}
+	return nil
+}
+
+// knownAppellation retourne l'identifiant s'il existe dans le référentiel, nil sinon (import)
+func knownAppellation(id *string) *string {
+	if id == nil {
+		return nil
+	}
+	if _, err := findAppellation(*id); err != nil {
+		return nil
+	}
+	return id
+}
+
+// GetCountries retourne les pays du référentiel avec leurs régions (sans les appellations)
+func (s *Store) GetCountries(ctx context.Context) ([]*domain.Country, error) {
+	g, err := loadGazetteer()
+	if err != nil {
+		return nil, err
+	}
+	countries := make([]*domain.Country, 0, len(g.countries))
+	for _, c := range g.countries {
+		country := *c
+		country.Regions = make([]*domain.GeoRegion, 0, len(c.Regions))
+		for _, r := range c.Regions {
+			region := *r
+			region.Appellations = nil
+			country.Regions = append(country.Regions, &region)
+		}
+		countries = append(countries, &country)
+	}
+	return countries, nil
+}
+
+// GetAppellation retourne une appellation du référentiel
+func (s *Store) GetAppellation(ctx context.Context, id string) (*domain.Appellation, error) {
+	a, err := findAppellation(id)
+	if err != nil {
+		return nil, err
+	}
+	copied := *a
+	return &copied, nil
+}
+
+// placeRank classe un nom par rapport à une recherche normalisée :
+// 0 égal, 1 préfixe, 2 préfixe d'un mot, 3 contenu, -1 sans rapport
+func placeRank(key, query string) int {
+	switch {
+	case query == "":
+		return 3
+	case key == query:
+		return 0
+	case strings.HasPrefix(key, query):
+		return 1
+	case strings.Contains(key, " "+query):
+		return 2
+	case strings.Contains(key, query):
+		return 3
+	}
+	return -1
+}
+
+// bestPlaceRank retourne le meilleur rang parmi un nom et ses alias
+func bestPlaceRank(query, name string, aliases []string) int {
+	best := -1
+	for _, n := range append([]string{name}, aliases...) {
+		if rank := placeRank(domain.NormalizePlaceName(n), query); rank >= 0 && (best < 0 || rank < best) {
+			best = rank
+		}
+	}
+	return best
+}
+
+// SearchAppellations recherche des appellations par nom, sans tenir compte des accents,
+// éventuellement limitées à un pays (code) ou une région (identifiant).
+// Les noms commençant par la recherche passent en premier.
+func (s *Store) SearchAppellations(ctx context.Context, query, country, region string, limit int) ([]*domain.Appellation, error) {
+	g, err := loadGazetteer()
+	if err != nil {
+		return nil, err
+	}
+	q := domain.NormalizePlaceName(query)
+
+	type ranked struct {
+		a    *domain.Appellation
+		rank int
+	}
+	var found []ranked
+	for _, c := range g.countries {
+		if country != "" && !strings.EqualFold(c.Code, country) {
+			continue
+		}
+		for _, r := range c.Regions {
+			if region != "" && r.ID != region {
+				continue
+			}
+			for _, a := range r.Appellations {
+				if rank := bestPlaceRank(q, a.Name, a.Aliases); rank >= 0 {
+					found = append(found, ranked{a, rank})
+				}
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].rank != found[j].rank {
+			return found[i].rank < found[j].rank
+		}
+		return domain.NormalizePlaceName(found[i].a.Name) < domain.NormalizePlaceName(found[j].a.Name)
+	})
+
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	appellations := make([]*domain.Appellation, 0, len(found))
+	for _, f := range found {
+		copied := *f.a
+		appellations = append(appellations, &copied)
+	}
+	return appellations, nil
+}
+
+// SearchPlaces recherche pays, régions et appellations par nom (géocodage hors ligne).
+// À rang égal, les zones les plus larges passent en premier.
+func (s *Store) SearchPlaces(ctx context.Context, query string, limit int) ([]*domain.Place, error) {
+	g, err := loadGazetteer()
+	if err != nil {
+		return nil, err
+	}
+	q := domain.NormalizePlaceName(query)
+	if q == "" {
+		return []*domain.Place{}, nil
+	}
+
+	type ranked struct {
+		p    *domain.Place
+		rank int
+	}
+	var found []ranked
+	add := func(p *domain.Place, rank int) {
+		if rank >= 0 {
+			found = append(found, ranked{p, rank})
+		}
+	}
+	for _, c := range g.countries {
+		add(&domain.Place{Kind: "country", ID: c.Code, Name: c.Name, CountryCode: c.Code, Country: c.Name,
+			Lat: c.Lat, Lon: c.Lon}, bestPlaceRank(q, c.Name, nil))
+		for _, r := range c.Regions {
+			add(&domain.Place{Kind: "region", ID: r.ID, Name: r.Name, Region: r.Name, CountryCode: c.Code, Country: c.Name,
+				Lat: r.Lat, Lon: r.Lon}, bestPlaceRank(q, r.Name, r.Aliases))
+			for _, a := range r.Appellations {
+				add(&domain.Place{Kind: "appellation", ID: a.ID, Name: a.Name, Region: r.Name, CountryCode: c.Code, Country: c.Name,
+					Lat: a.Lat, Lon: a.Lon}, bestPlaceRank(q, a.Name, a.Aliases))
+			}
+		}
+	}
+	// Le tri stable conserve l'ordre pays, région, appellation à rang égal
+	sort.SliceStable(found, func(i, j int) bool { return found[i].rank < found[j].rank })
+
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	places := make([]*domain.Place, 0, len(found))
+	for _, f := range found {
+		places = append(places, f.p)
+	}
+	return places, nil
+}
+
+// locateRegion rapproche un vin d'une région du référentiel : par son appellation,
+// sinon par sa région en texte libre (nom ou alias d'une région, puis d'une appellation).
+// Le pays seul est retourné quand la région désigne un pays.
+func (g *gazetteer) locateRegion(appellationID *string, region string) (*domain.Country, *domain.GeoRegion) {
+	if appellationID != nil {
+		if a, ok := g.appellations[*appellationID]; ok {
+			return g.country(a.CountryCode), g.regions[a.RegionID]
+		}
+	}
+	// "Pauillac, Bordeaux" ou "Bordeaux (France)" : chaque partie est essayée
+	candidates := append([]string{region}, strings.FieldsFunc(region, func(r rune) bool {
+		return strings.ContainsRune(",;/()", r)
+	})...)
+	for _, candidate := range candidates {
+		key := domain.NormalizePlaceName(candidate)
+		if r, ok := g.regionsByKey[key]; ok {
+			return g.country(r.CountryCode), r
+		}
+		if a, ok := g.appellationsByKey[key]; ok {
+			return g.country(a.CountryCode), g.regions[a.RegionID]
+		}
+	}
+	for _, candidate := range candidates {
+		if c, ok := g.countriesByKey[domain.NormalizePlaceName(candidate)]; ok {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+// country retourne un pays du référentiel par son code
+func (g *gazetteer) country(code string) *domain.Country {
+	for _, c := range g.countries {
+		if c.Code == code {
+			return c
+		}
+	}
+	return nil
+}
+
+// GetGeoBreakdown agrège le stock (vins en stock hors corbeille) par pays ("country")
+// ou par région ("region"), éventuellement pour un seul pays (code)
+func (s *Store) GetGeoBreakdown(ctx context.Context, level, countryCode string) (*domain.GeoBreakdown, error) {
+	g, err := loadGazetteer()
+	if err != nil {
+		return nil, err
+	}
+	if level != "country" && level != "region" {
+		return nil, NewValidationError("level", "level must be country or region")
+	}
+	if countryCode != "" && g.country(strings.ToUpper(countryCode)) == nil {
+		return nil, NewValidationError("country", "unknown country: "+countryCode)
+	}
+	countryCode = strings.ToUpper(countryCode)
+
+	rows, err := s.Db.QueryContext(ctx,
+		`SELECT region, appellation_id, type, quantity FROM wines WHERE deleted_at IS NULL AND quantity > 0`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wines: %w", err)
+	}
+	defer rows.Close()
+
+	breakdown := &domain.GeoBreakdown{Level: level, Areas: []*domain.GeoArea{}}
+	areas := map[string]*domain.GeoArea{}
+	for rows.Next() {
+		var region, bottleType string
+		var appellationID *string
+		var quantity int
+		if err := rows.Scan(&region, &appellationID, &bottleType, &quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan wine: %w", err)
+		}
+
+		country, geoRegion := g.locateRegion(appellationID, region)
+		if country != nil && countryCode != "" && country.Code != countryCode {
+			continue
+		}
+		var area *domain.GeoArea
+		switch {
+		case level == "country" && country != nil:
+			if area = areas[country.Code]; area == nil {
+				area = &domain.GeoArea{ID: country.Code, Name: country.Name, Lat: country.Lat, Lon: country.Lon}
+			}
+			areas[country.Code] = area
+		case level == "region" && geoRegion != nil:
+			if area = areas[geoRegion.ID]; area == nil {
+				area = &domain.GeoArea{ID: geoRegion.ID, Name: geoRegion.Name, Country: geoRegion.Country,
+					Lat: geoRegion.Lat, Lon: geoRegion.Lon}
+			}
+			areas[geoRegion.ID] = area
+		default:
+			breakdown.UnlocatedWines++
+			breakdown.UnlocatedBottles += quantity
+			continue
+		}
+		if area.ByType == nil {
+			area.ByType = map[string]int{}
+		}
+		area.Wines++
+		area.Bottles += quantity
+		area.ByType[bottleType] += quantity
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate wines: %w", err)
+	}
+
+	for _, area := range areas {
+		breakdown.Areas = append(breakdown.Areas, area)
+	}
+	sort.Slice(breakdown.Areas, func(i, j int) bool {
+		if breakdown.Areas[i].Bottles != breakdown.Areas[j].Bottles {
+			return breakdown.Areas[i].Bottles > breakdown.Areas[j].Bottles
+		}
+		return breakdown.Areas[i].Name < breakdown.Areas[j].Name
+	})
+	return breakdown, nil
+}
@@ -0,0 +1,504 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// Les cépages sont reconnus par leur nom ou un synonyme normalisé (domain.NormalizeGrapeName).
+// Un cépage inconnu saisi dans un assemblage est ajouté au référentiel.
+
+// blendChunk borne le nombre de vins par requête de chargement des assemblages
+const blendChunk = 500
+
+// grapeColumns lit un cépage avec son nombre de vins (alias g)
+const grapeColumns = `g.id, g.name, g.color, g.synonyms,
+	(SELECT COUNT(*) FROM wine_grapes wg JOIN wines w ON w.id = wg.wine_id
+	 WHERE wg.grape_id = g.id AND w.deleted_at IS NULL), g.created_at
+	FROM grape_varieties g`
+
+// scanGrape lit une ligne sélectionnée avec grapeColumns
+func scanGrape(row rowScanner) (*domain.GrapeVariety, error) {
+	g := &domain.GrapeVariety{}
+	var synonyms string
+	if err := row.Scan(&g.ID, &g.Name, &g.Color, &synonyms, &g.WineCount, &g.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(synonyms), &g.Synonyms); err != nil || g.Synonyms == nil {
+		g.Synonyms = []string{}
+	}
+	return g, nil
+}
+
+// encodeSynonyms sérialise les synonymes en retirant les doublons et ceux égaux au nom
+func encodeSynonyms(name string, synonyms []string) string {
+	seen := map[string]bool{domain.NormalizeGrapeName(name): true}
+	kept := make([]string, 0, len(synonyms))
+	for _, synonym := range synonyms {
+		synonym = strings.TrimSpace(synonym)
+		key := domain.NormalizeGrapeName(synonym)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, synonym)
+	}
+	data, _ := json.Marshal(kept)
+	return string(data)
+}
+
+// queryGrapes exécute une requête de cépages (colonnes grapeColumns)
+func queryGrapes(ctx context.Context, db queryer, query string, args ...interface{}) ([]*domain.GrapeVariety, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query grape varieties: %w", err)
+	}
+	defer rows.Close()
+
+	grapes := make([]*domain.GrapeVariety, 0)
+	for rows.Next() {
+		g, err := scanGrape(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan grape variety: %w", err)
+		}
+		grapes = append(grapes, g)
+	}
+	return grapes, rows.Err()
+}
+
+// GetGrapes retourne le référentiel des cépages par ordre alphabétique
+func (s *Store) GetGrapes(ctx context.Context) ([]*domain.GrapeVariety, error) {
+	return queryGrapes(ctx, s.Db, `SELECT `+grapeColumns+` ORDER BY g.name COLLATE NOCASE`)
+}
+
+// GetGrape retourne un cépage par son ID
+func (s *Store) GetGrape(ctx context.Context, id int64) (*domain.GrapeVariety, error) {
+	return getGrape(ctx, s.Db, id)
+}
+
+// getGrape lit un cépage dans la base ou la transaction
+func getGrape(ctx context.Context, db queryRower, id int64) (*domain.GrapeVariety, error) {
+	g, err := scanGrape(db.QueryRowContext(ctx, `SELECT `+grapeColumns+` WHERE g.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, notFound("grape variety", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query grape variety: %w", err)
+	}
+	return g, nil
+}
+
+// findGrape retourne le cépage dont le nom ou un synonyme correspond, nil s'il est inconnu
+func findGrape(ctx context.Context, db queryer, name string) (*domain.GrapeVariety, error) {
+	key := domain.NormalizeGrapeName(name)
+	if key == "" {
+		return nil, nil
+	}
+	grapes, err := queryGrapes(ctx, db, `SELECT `+grapeColumns)
+	if err != nil {
+		return nil, err
+	}
+	// Le nom l'emporte sur un synonyme d'un autre cépage
+	for _, g := range grapes {
+		if domain.NormalizeGrapeName(g.Name) == key {
+			return g, nil
+		}
+	}
+	for _, g := range grapes {
+		for _, synonym := range g.Synonyms {
+			if domain.NormalizeGrapeName(synonym) == key {
+				return g, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// checkGrapeNames refuse (ErrConflict) un nom ou un synonyme désignant déjà un autre cépage
+func checkGrapeNames(ctx context.Context, db queryer, g *domain.GrapeVariety) error {
+	for _, name := range append([]string{g.Name}, g.Synonyms...) {
+		existing, err := findGrape(ctx, db, name)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.ID != g.ID {
+			return fmt.Errorf("%w: %q already names grape variety %q", ErrConflict, name, existing.Name)
+		}
+	}
+	return nil
+}
+
+// MatchGrapes autocomplète les cépages par nom ou synonyme (préfixes d'abord)
+func (s *Store) MatchGrapes(ctx context.Context, name string, limit int) ([]*domain.GrapeVariety, error) {
+	grapes, err := queryGrapes(ctx, s.Db, `SELECT `+grapeColumns)
+	if err != nil {
+		return nil, err
+	}
+	q := domain.NormalizeGrapeName(name)
+
+	type ranked struct {
+		g    *domain.GrapeVariety
+		rank int
+	}
+	var found []ranked
+	for _, g := range grapes {
+		if rank := bestPlaceRank(q, g.Name, g.Synonyms); rank >= 0 {
+			found = append(found, ranked{g, rank})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].rank != found[j].rank {
+			return found[i].rank < found[j].rank
+		}
+		return domain.NormalizeGrapeName(found[i].g.Name) < domain.NormalizeGrapeName(found[j].g.Name)
+	})
+
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	matches := make([]*domain.GrapeVariety, 0, len(found))
+	for _, f := range found {
+		matches = append(matches, f.g)
+	}
+	return matches, nil
+}
+
+// CreateGrape ajoute un cépage au référentiel
+func (s *Store) CreateGrape(ctx context.Context, g *domain.GrapeVariety) (int64, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkGrapeNames(ctx, tx, g); err != nil {
+		return 0, err
+	}
+	id, err := insertGrape(ctx, tx, g)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return id, nil
+}
+
+// insertGrape insère un cépage dans la transaction
+func insertGrape(ctx context.Context, tx *sql.Tx, g *domain.GrapeVariety) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO grape_varieties (name, color, synonyms, created_at) VALUES (?, ?, ?, ?)`,
+		g.Name, g.Color, encodeSynonyms(g.Name, g.Synonyms), time.Now())
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%w: grape variety %q already exists", ErrConflict, g.Name)
+		}
+		return 0, fmt.Errorf("failed to create grape variety: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateGrape met à jour un cépage ; les assemblages le référencent par son ID
+func (s *Store) UpdateGrape(ctx context.Context, g *domain.GrapeVariety) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkGrapeNames(ctx, tx, g); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx,
+		`UPDATE grape_varieties SET name = ?, color = ?, synonyms = ? WHERE id = ?`,
+		g.Name, g.Color, encodeSynonyms(g.Name, g.Synonyms), g.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: grape variety %q already exists", ErrConflict, g.Name)
+		}
+		return fmt.Errorf("failed to update grape variety: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return notFound("grape variety", g.ID)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteGrape supprime un cépage absent de tout assemblage (corbeille comprise)
+func (s *Store) DeleteGrape(ctx context.Context, id int64) error {
+	var used int
+	if err := s.Db.QueryRowContext(ctx, `SELECT COUNT(*) FROM wine_grapes WHERE grape_id = ?`, id).Scan(&used); err != nil {
+		return fmt.Errorf("failed to count grape variety wines: %w", err)
+	}
+	if used > 0 {
+		return fmt.Errorf("%w: grape variety %d is used by %d wine(s)", ErrConflict, id, used)
+	}
+
+	result, err := s.Db.ExecContext(ctx, `DELETE FROM grape_varieties WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete grape variety: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return notFound("grape variety", id)
+	}
+	return nil
+}
+
+// GetGrapeWines liste les vins contenant un cépage
+func (s *Store) GetGrapeWines(ctx context.Context, id int64) ([]*domain.Wine, error) {
+	if _, err := getGrape(ctx, s.Db, id); err != nil {
+		return nil, err
+	}
+	return s.queryWines(ctx, `SELECT `+wineColumns+` FROM wines
+	WHERE deleted_at IS NULL AND id IN (SELECT wine_id FROM wine_grapes WHERE grape_id = ?)
+	ORDER BY name COLLATE NOCASE, vintage`, id)
+}
+
+// saveBlend remplace l'assemblage d'un vin ; un assemblage nil est laissé tel quel.
+// Les cépages sont désignés par ID, nom ou synonyme ; un nom inconnu crée le cépage.
+func saveBlend(ctx context.Context, tx *sql.Tx, wineID int64, blend []*domain.BlendComponent) error {
+	if blend == nil {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM wine_grapes WHERE wine_id = ?`, wineID); err != nil {
+		return fmt.Errorf("failed to clear wine blend: %w", err)
+	}
+
+	verr := &ValidationError{}
+	seen := map[int64]bool{}
+	for i, c := range blend {
+		field := fmt.Sprintf("blend[%d]", i)
+		var grape *domain.GrapeVariety
+		var err error
+		if c.GrapeID != 0 {
+			if grape, err = getGrape(ctx, tx, c.GrapeID); err != nil {
+				if !errors.Is(err, ErrNotFound) {
+					return err
+				}
+				verr.Add(field+".grape_id", fmt.Sprintf("unknown grape variety: %d", c.GrapeID))
+				continue
+			}
+		} else {
+			if grape, err = findGrape(ctx, tx, c.Grape); err != nil {
+				return err
+			}
+			if grape == nil {
+				created := &domain.GrapeVariety{Name: strings.TrimSpace(c.Grape), Synonyms: []string{}}
+				if created.ID, err = insertGrape(ctx, tx, created); err != nil {
+					return err
+				}
+				grape = created
+			}
+		}
+		if seen[grape.ID] {
+			verr.Add(field+".grape", fmt.Sprintf("%s appears twice in the blend", grape.Name))
+			continue
+		}
+		seen[grape.ID] = true
+		c.GrapeID, c.Grape = grape.ID, grape.Name
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO wine_grapes (wine_id, grape_id, percentage, position) VALUES (?, ?, ?, ?)`,
+			wineID, grape.ID, c.Percentage, i); err != nil {
+			return fmt.Errorf("failed to save wine blend: %w", err)
+		}
+	}
+	return verr.Err()
+}
+
+// loadBlends charge l'assemblage des vins (tableau vide sans cépage)
+func loadBlends(ctx context.Context, db queryer, wines []*domain.Wine) error {
+	byID := make(map[int64]*domain.Wine, len(wines))
+	ids := make([]interface{}, 0, len(wines))
+	for _, wine := range wines {
+		wine.Blend = []*domain.BlendComponent{}
+		byID[wine.ID] = wine
+		ids = append(ids, wine.ID)
+	}
+
+	for start := 0; start < len(ids); start += blendChunk {
+		chunk := ids[start:min(start+blendChunk, len(ids))]
+		rows, err := db.QueryContext(ctx, `
+		SELECT wg.wine_id, wg.grape_id, g.name, wg.percentage
+		FROM wine_grapes wg JOIN grape_varieties g ON g.id = wg.grape_id
+		WHERE wg.wine_id IN (?`+strings.Repeat(", ?", len(chunk)-1)+`)
+		ORDER BY wg.wine_id, wg.position`, chunk...)
+		if err != nil {
+			return fmt.Errorf("failed to query wine blends: %w", err)
+		}
+		for rows.Next() {
+			var wineID int64
+			c := &domain.BlendComponent{}
+			if err := rows.Scan(&wineID, &c.GrapeID, &c.Grape, &c.Percentage); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan wine blend: %w", err)
+			}
+			byID[wineID].Blend = append(byID[wineID].Blend, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate wine blends: %w", err)
+		}
+	}
+	return nil
+}
+
+// formatBlend décrit un assemblage pour l'export CSV : "Syrah 60%; Grenache 40%"
+func formatBlend(blend []*domain.BlendComponent) string {
+	parts := make([]string, 0, len(blend))
+	for _, c := range blend {
+		if c.Percentage != nil {
+			parts = append(parts, fmt.Sprintf("%s %g%%", c.Grape, *c.Percentage))
+		} else {
+			parts = append(parts, c.Grape)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// GetGrapeStats agrège les vins (hors corbeille) par cépage, les plus représentés d'abord
+func (s *Store) GetGrapeStats(ctx context.Context) ([]*domain.GrapeStat, error) {
+	rows, err := s.Db.QueryContext(ctx, `
+	SELECT g.id, g.name, g.color, COUNT(w.id),
+		SUM(CASE WHEN (SELECT COUNT(*) FROM wine_grapes x WHERE x.wine_id = w.id) = 1 THEN 1 ELSE 0 END),
+		COALESCE(SUM(w.quantity), 0), COUNT(w.rating), AVG(w.rating)
+	FROM grape_varieties g
+	JOIN wine_grapes wg ON wg.grape_id = g.id
+	JOIN wines w ON w.id = wg.wine_id AND w.deleted_at IS NULL
+	GROUP BY g.id
+	ORDER BY COUNT(w.id) DESC, g.name COLLATE NOCASE`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query grape stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]*domain.GrapeStat, 0)
+	for rows.Next() {
+		st := &domain.GrapeStat{}
+		if err := rows.Scan(&st.GrapeID, &st.Grape, &st.Color, &st.Wines, &st.SingleVariety,
+			&st.Bottles, &st.RatedWines, &st.AverageRating); err != nil {
+			return nil, fmt.Errorf("failed to scan grape stats: %w", err)
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// GetBlendStats agrège les vins d'assemblage (au moins deux cépages) par combinaison de cépages,
+// les mieux notées d'abord. grape, s'il est fourni, limite aux assemblages contenant ce cépage.
+func (s *Store) GetBlendStats(ctx context.Context, grape string) ([]*domain.BlendStat, error) {
+	var filterID int64
+	if strings.TrimSpace(grape) != "" {
+		g, err := findGrape(ctx, s.Db, grape)
+		if err != nil {
+			return nil, err
+		}
+		if g == nil {
+			return []*domain.BlendStat{}, nil
+		}
+		filterID = g.ID
+	}
+
+	rows, err := s.Db.QueryContext(ctx, `
+	SELECT w.id, w.quantity, w.rating, wg.grape_id, g.name
+	FROM wine_grapes wg
+	JOIN wines w ON w.id = wg.wine_id AND w.deleted_at IS NULL
+	JOIN grape_varieties g ON g.id = wg.grape_id
+	ORDER BY w.id, wg.position`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wine blends: %w", err)
+	}
+	defer rows.Close()
+
+	type blendedWine struct {
+		quantity int
+		rating   *float32
+		grapeIDs []int64
+		grapes   []string
+	}
+	var wines []*blendedWine
+	var current *blendedWine
+	var currentID int64
+	for rows.Next() {
+		var wineID, grapeID int64
+		var quantity int
+		var rating *float32
+		var name string
+		if err := rows.Scan(&wineID, &quantity, &rating, &grapeID, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan wine blend: %w", err)
+		}
+		if current == nil || wineID != currentID {
+			current = &blendedWine{quantity: quantity, rating: rating}
+			currentID = wineID
+			wines = append(wines, current)
+		}
+		current.grapeIDs = append(current.grapeIDs, grapeID)
+		current.grapes = append(current.grapes, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate wine blends: %w", err)
+	}
+
+	// Une combinaison est identifiée par ses cépages, quel que soit leur ordre
+	groups := map[string]*domain.BlendStat{}
+	ratingSums := map[string]float64{}
+	var order []string
+	for _, w := range wines {
+		if len(w.grapeIDs) < 2 || (filterID != 0 && !slices.Contains(w.grapeIDs, filterID)) {
+			continue
+		}
+		ids := append([]int64(nil), w.grapeIDs...)
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		key := fmt.Sprint(ids)
+
+		st, ok := groups[key]
+		if !ok {
+			st = &domain.BlendStat{Grapes: w.grapes}
+			groups[key] = st
+			order = append(order, key)
+		}
+		st.Wines++
+		st.Bottles += w.quantity
+		if w.rating != nil {
+			st.RatedWines++
+			ratingSums[key] += float64(*w.rating)
+			if st.BestRating == nil || *w.rating > *st.BestRating {
+				best := *w.rating
+				st.BestRating = &best
+			}
+		}
+	}
+
+	stats := make([]*domain.BlendStat, 0, len(order))
+	for _, key := range order {
+		st := groups[key]
+		if st.RatedWines > 0 {
+			avg := ratingSums[key] / float64(st.RatedWines)
+			st.AverageRating = &avg
+		}
+		stats = append(stats, st)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		a, b := stats[i].AverageRating, stats[j].AverageRating
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		if a != nil && *a != *b {
+			return *a > *b
+		}
+		return stats[i].Wines > stats[j].Wines
+	})
+	return stats, nil
+}
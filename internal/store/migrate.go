@@ -0,0 +1,361 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles contient les migrations SQL numérotées (NNNN_nom.up.sql / NNNN_nom.down.sql)
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFunc applique une étape de migration dans une transaction
+type migrationFunc func(ctx context.Context, tx *sql.Tx) error
+
+// Migration décrit une version du schéma
+type Migration struct {
+	Version int
+	Name    string
+	up      migrationFunc
+	down    migrationFunc
+}
+
+// MigrationStatus indique si une migration a été appliquée
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// goMigrations regroupe les migrations qui ne peuvent pas s'exprimer en SQL pur
+// (SQLite ne supporte pas "ADD COLUMN IF NOT EXISTS" ; normalisation des noms de producteurs)
+var goMigrations = []Migration{
+	{Version: 2, Name: "legacy_current_value_and_tobacco_alerts", up: migrateLegacyCurrentValue, down: noopMigration},
+	{Version: 14, Name: "producers", up: migrateProducers, down: migrateProducersDown},
+}
+
+// loadMigrations lit les fichiers embarqués et les migrations Go, triés par version
+func loadMigrations() ([]Migration, error) {
+	return readMigrations(migrationFiles, goMigrations)
+}
+
+// readMigrations assemble les fichiers SQL du répertoire "migrations" de fsys
+// et les migrations Go, triés par version
+func readMigrations(fsys fs.FS, goMigrations []Migration) ([]Migration, error) {
+	byVersion := make(map[int]*Migration)
+
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join("migrations", fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", fileName, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.up = sqlMigration(string(content))
+		} else {
+			m.down = sqlMigration(string(content))
+		}
+	}
+
+	for _, gm := range goMigrations {
+		if _, exists := byVersion[gm.Version]; exists {
+			return nil, fmt.Errorf("migration %d is defined twice", gm.Version)
+		}
+		m := gm
+		byVersion[gm.Version] = &m
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == nil {
+			return nil, fmt.Errorf("migration %d (%s) has no up step", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// sqlMigration exécute un script SQL (plusieurs instructions autorisées)
+func sqlMigration(script string) migrationFunc {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, script)
+		return err
+	}
+}
+
+// noopMigration sert d'étape "down" aux migrations de rattrapage
+func noopMigration(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+// LatestSchemaVersion retourne la version de schéma la plus récente connue du binaire
+func LatestSchemaVersion() (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// ensureMigrationsTable crée la table de suivi des migrations
+func (s *Store) ensureMigrationsTable(ctx context.Context) error {
+	_, err := s.Db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations retourne les versions appliquées et leur date
+func (s *Store) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := s.Db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema migration: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// SchemaVersion retourne la version de schéma la plus haute appliquée (0 si aucune)
+func (s *Store) SchemaVersion(ctx context.Context) (int, error) {
+	if err := s.ensureMigrationsTable(ctx); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	if err := s.Db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// MigrationStatus liste les migrations connues et leur état
+func (s *Store) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Migrate applique toutes les migrations en attente, chacune dans sa transaction.
+// Elle retourne les migrations appliquées.
+func (s *Store) Migrate(ctx context.Context) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuser une base créée par un binaire plus récent
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+	}
+	for version := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("database schema version %d is unknown to this binary (upgrade glou-server)", version)
+		}
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := s.runMigration(ctx, m, true); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// MigrateDown annule les `steps` dernières migrations appliquées.
+// Elle retourne les migrations annulées.
+func (s *Store) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.down == nil {
+			return done, fmt.Errorf("migration %04d_%s cannot be reverted", m.Version, m.Name)
+		}
+		if err := s.runMigration(ctx, m, false); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// runMigration exécute une étape et met à jour schema_migrations dans la même transaction
+func (s *Store) runMigration(ctx context.Context, m Migration, up bool) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if up {
+		if err := m.up(ctx, tx); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now(),
+		); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+	} else {
+		if err := m.down(ctx, tx); err != nil {
+			return fmt.Errorf("revert of migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, m.Version); err != nil {
+			return fmt.Errorf("failed to unrecord migration %d: %w", m.Version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+	}
+
+	return nil
+}
+
+// columnExists vérifie la présence d'une colonne dans une table
+func columnExists(ctx context.Context, tx *sql.Tx, table, column string) (bool, error) {
+	var count int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+// migrateLegacyCurrentValue reprend l'ancien script manuel migrations/001 :
+// les bases créées avant l'ajout de wines.current_value n'ont pas la colonne.
+func migrateLegacyCurrentValue(ctx context.Context, tx *sql.Tx) error {
+	exists, err := columnExists(ctx, tx, "wines", "current_value")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE wines ADD COLUMN current_value REAL`); err != nil {
+			return fmt.Errorf("failed to add wines.current_value: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE INDEX IF NOT EXISTS idx_tobacco_alerts_status ON tobacco_alerts(status);
+	CREATE INDEX IF NOT EXISTS idx_tobacco_alerts_tobacco_id ON tobacco_alerts(tobacco_id);
+	`)
+	return err
+}
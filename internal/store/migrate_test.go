@@ -0,0 +1,57 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadMigrationsSortsByVersion(t *testing.T) {
+	sql := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	files := fstest.MapFS{
+		"migrations/0010_later.up.sql":  sql,
+		"migrations/0001_init.up.sql":   sql,
+		"migrations/0001_init.down.sql": sql,
+		"migrations/0003_next.up.sql":   sql,
+	}
+	goMigs := []Migration{{Version: 2, Name: "go_step", up: noopMigration, down: noopMigration}}
+
+	migrations, err := readMigrations(files, goMigs)
+	if err != nil {
+		t.Fatalf("readMigrations: %v", err)
+	}
+	var versions []int
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+	if len(versions) != 4 || versions[0] != 1 || versions[1] != 2 || versions[2] != 3 || versions[3] != 10 {
+		t.Errorf("expected versions [1 2 3 10], got %v", versions)
+	}
+	if migrations[0].down == nil {
+		t.Error("expected migration 1 to have a down step")
+	}
+}
+
+func TestReadMigrationsRejectsDuplicateVersion(t *testing.T) {
+	files := fstest.MapFS{
+		"migrations/0002_sql_step.up.sql": &fstest.MapFile{Data: []byte("SELECT 1;")},
+	}
+	goMigs := []Migration{{Version: 2, Name: "go_step", up: noopMigration, down: noopMigration}}
+
+	_, err := readMigrations(files, goMigs)
+	if err == nil || !strings.Contains(err.Error(), "migration 2 is defined twice") {
+		t.Fatalf("expected duplicate version error, got %v", err)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("migration %d is not after %d", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS tobaccos;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS encrypted_credentials;
+DROP TABLE IF EXISTS setup_wizard;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS activity_log;
+DROP TABLE IF EXISTS settings;
+DROP TABLE IF EXISTS consumption_history;
+DROP TABLE IF EXISTS tobacco_alerts;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS wines;
+DROP TABLE IF EXISTS cells;
+DROP TABLE IF EXISTS caves;
@@ -0,0 +1,180 @@
+-- Schéma initial de Glou (reprend l'ancien initSchema).
+-- Toutes les instructions sont idempotentes afin d'adopter les bases existantes.
+
+CREATE TABLE IF NOT EXISTS caves (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	model TEXT,
+	location TEXT NOT NULL DEFAULT 'Principale',
+	capacity INTEGER NOT NULL DEFAULT 100,
+	current INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS cells (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	cave_id INTEGER NOT NULL,
+	location TEXT NOT NULL,
+	capacity INTEGER NOT NULL DEFAULT 1,
+	current INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (cave_id) REFERENCES caves(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS wines (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	region TEXT NOT NULL,
+	vintage INTEGER NOT NULL,
+	type TEXT NOT NULL,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	cell_id INTEGER,
+	producer TEXT,
+	alcohol_level REAL,
+	price REAL,
+	current_value REAL,
+	rating REAL,
+	comments TEXT,
+	consumed INTEGER NOT NULL DEFAULT 0,
+	min_apogee_date DATE,
+	max_apogee_date DATE,
+	consumption_date DATE,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (cell_id) REFERENCES cells(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	wine_id INTEGER NOT NULL,
+	alert_type TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'active',
+	dismissed_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (wine_id) REFERENCES wines(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tobacco_alerts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tobacco_id INTEGER NOT NULL,
+	alert_type TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'active',
+	dismissed_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (tobacco_id) REFERENCES tobaccos(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS consumption_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	wine_id INTEGER NOT NULL,
+	quantity INTEGER NOT NULL,
+	rating REAL,
+	comment TEXT,
+	reason TEXT,
+	date DATE NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (wine_id) REFERENCES wines(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS settings (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	app_title TEXT DEFAULT 'Glou',
+	app_slogan TEXT DEFAULT 'Your personal cellar',
+	logo_url TEXT,
+	favicon_url TEXT,
+	support_email TEXT,
+	theme_color TEXT DEFAULT '#007bff',
+	secondary_color TEXT DEFAULT '#6c757d',
+	accent_color TEXT DEFAULT '#28a745',
+	dark_mode_default INTEGER DEFAULT 0,
+	public_domain TEXT,
+	public_protocol TEXT DEFAULT 'http',
+	proxy_mode INTEGER DEFAULT 0,
+	proxy_headers INTEGER DEFAULT 0,
+	allow_registration INTEGER DEFAULT 0,
+	require_approval INTEGER DEFAULT 0,
+	enable_notifications INTEGER DEFAULT 1,
+	maintenance_mode INTEGER DEFAULT 0,
+	rows_per_page INTEGER DEFAULT 10,
+	date_format TEXT DEFAULT 'YYYY-MM-DD',
+	language TEXT DEFAULT 'en',
+	max_request_body_size INTEGER DEFAULT 1048576,
+	session_timeout INTEGER DEFAULT 1440,
+	smtp_configured INTEGER DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS activity_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	entity_type TEXT NOT NULL,
+	entity_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	details TEXT,
+	ip_address TEXT,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL UNIQUE,
+	email TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'user',
+	is_active INTEGER NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS setup_wizard (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	completed INTEGER NOT NULL DEFAULT 0,
+	completed_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS encrypted_credentials (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	service_name TEXT NOT NULL UNIQUE,
+	credential_type TEXT NOT NULL,
+	encrypted_value TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	token TEXT NOT NULL UNIQUE,
+	expires_at DATETIME NOT NULL,
+	used INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tobaccos (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	brand TEXT,
+	purchase_date DATE,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	purchase_price REAL,
+	current_value REAL,
+	cave_id INTEGER,
+	cell_id INTEGER,
+	notes TEXT,
+	origin_country TEXT,
+	format TEXT,
+	wrapper TEXT,
+	binder TEXT,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (cave_id) REFERENCES caves(id) ON DELETE SET NULL,
+	FOREIGN KEY (cell_id) REFERENCES cells(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_log_entity ON activity_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_activity_log_created ON activity_log(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_encrypted_credentials_service ON encrypted_credentials(service_name);
+CREATE INDEX IF NOT EXISTS idx_password_reset_token ON password_reset_tokens(token);
+CREATE INDEX IF NOT EXISTS idx_password_reset_expires ON password_reset_tokens(expires_at);
@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS idx_wines_bar_code;
+DROP INDEX IF EXISTS idx_wines_cave_id;
+
+UPDATE wines SET type = CASE type
+	WHEN 'red_wine' THEN 'Red'
+	WHEN 'white_wine' THEN 'White'
+	WHEN 'rose_wine' THEN 'Rosé'
+	WHEN 'sparkling_wine' THEN 'Sparkling'
+	WHEN 'beer' THEN 'Beer'
+	WHEN 'spirit' THEN 'Spirit'
+	ELSE type
+END;
+
+ALTER TABLE wines DROP COLUMN external_id;
+ALTER TABLE wines DROP COLUMN image;
+ALTER TABLE wines DROP COLUMN bar_code;
+ALTER TABLE wines DROP COLUMN user_id;
+ALTER TABLE wines DROP COLUMN cave_id;
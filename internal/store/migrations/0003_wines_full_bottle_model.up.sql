@@ -0,0 +1,27 @@
+-- Stocke tous les champs de domain.Bottle et unifie le vocabulaire des types.
+
+ALTER TABLE wines ADD COLUMN cave_id INTEGER;
+ALTER TABLE wines ADD COLUMN user_id TEXT;
+ALTER TABLE wines ADD COLUMN bar_code TEXT NOT NULL DEFAULT '';
+ALTER TABLE wines ADD COLUMN image TEXT NOT NULL DEFAULT '';
+ALTER TABLE wines ADD COLUMN external_id TEXT NOT NULL DEFAULT '';
+
+-- Anciens libellés de l'API ("Red", "Rosé"...) vers les types canoniques de domain.BottleTypes()
+UPDATE wines SET type = CASE lower(trim(type))
+	WHEN 'red' THEN 'red_wine'
+	WHEN 'white' THEN 'white_wine'
+	WHEN 'rosé' THEN 'rose_wine'
+	WHEN 'rose' THEN 'rose_wine'
+	WHEN 'sparkling' THEN 'sparkling_wine'
+	WHEN 'beer' THEN 'beer'
+	WHEN 'spirit' THEN 'spirit'
+	WHEN 'cigar' THEN 'cigar'
+	ELSE type
+END;
+
+-- La cave se déduit de l'emplacement pour les lignes existantes
+UPDATE wines SET cave_id = (SELECT cells.cave_id FROM cells WHERE cells.id = wines.cell_id)
+WHERE cell_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_wines_cave_id ON wines(cave_id);
+CREATE INDEX IF NOT EXISTS idx_wines_bar_code ON wines(bar_code);
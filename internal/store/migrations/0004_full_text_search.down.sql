@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS consumption_search_delete;
+DROP TRIGGER IF EXISTS consumption_search_update;
+DROP TRIGGER IF EXISTS consumption_search_insert;
+DROP TRIGGER IF EXISTS tobaccos_search_delete;
+DROP TRIGGER IF EXISTS tobaccos_search_update;
+DROP TRIGGER IF EXISTS tobaccos_search_insert;
+DROP TRIGGER IF EXISTS wines_search_delete;
+DROP TRIGGER IF EXISTS wines_search_update;
+DROP TRIGGER IF EXISTS wines_search_insert;
+DROP TABLE IF EXISTS search_index;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_wines_status;
+ALTER TABLE wines DROP COLUMN finished_at;
+ALTER TABLE wines DROP COLUMN status;
@@ -0,0 +1,9 @@
+-- Les vins terminés sont archivés (status = 'finished') au lieu d'être supprimés,
+-- afin de conserver leur historique de dégustation.
+ALTER TABLE wines ADD COLUMN status TEXT NOT NULL DEFAULT 'in_stock';
+ALTER TABLE wines ADD COLUMN finished_at DATETIME;
+
+UPDATE wines SET status = 'finished', finished_at = COALESCE(consumption_date, CURRENT_TIMESTAMP), quantity = 0
+WHERE quantity <= 0;
+
+CREATE INDEX IF NOT EXISTS idx_wines_status ON wines(status);
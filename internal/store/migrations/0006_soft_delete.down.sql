@@ -0,0 +1,20 @@
+-- Les éléments encore dans la corbeille sont supprimés définitivement
+DELETE FROM consumption_history WHERE wine_id IN (SELECT id FROM wines WHERE deleted_at IS NOT NULL);
+DELETE FROM alerts WHERE wine_id IN (SELECT id FROM wines WHERE deleted_at IS NOT NULL);
+DELETE FROM tobacco_alerts WHERE tobacco_id IN (SELECT id FROM tobaccos WHERE deleted_at IS NOT NULL);
+DELETE FROM wines WHERE deleted_at IS NOT NULL;
+DELETE FROM tobaccos WHERE deleted_at IS NOT NULL;
+DELETE FROM cells WHERE deleted_at IS NOT NULL;
+DELETE FROM caves WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE settings DROP COLUMN trash_retention_days;
+
+DROP INDEX IF EXISTS idx_cells_deleted_at;
+DROP INDEX IF EXISTS idx_caves_deleted_at;
+DROP INDEX IF EXISTS idx_tobaccos_deleted_at;
+DROP INDEX IF EXISTS idx_wines_deleted_at;
+
+ALTER TABLE cells DROP COLUMN deleted_at;
+ALTER TABLE caves DROP COLUMN deleted_at;
+ALTER TABLE tobaccos DROP COLUMN deleted_at;
+ALTER TABLE wines DROP COLUMN deleted_at;
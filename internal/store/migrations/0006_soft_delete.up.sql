@@ -0,0 +1,14 @@
+-- Suppression logique : les éléments supprimés vont dans la corbeille
+-- et sont purgés définitivement après la durée de rétention.
+ALTER TABLE wines ADD COLUMN deleted_at DATETIME;
+ALTER TABLE tobaccos ADD COLUMN deleted_at DATETIME;
+ALTER TABLE caves ADD COLUMN deleted_at DATETIME;
+ALTER TABLE cells ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_wines_deleted_at ON wines(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_tobaccos_deleted_at ON tobaccos(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_caves_deleted_at ON caves(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_cells_deleted_at ON cells(deleted_at);
+
+-- Durée de conservation de la corbeille (jours)
+ALTER TABLE settings ADD COLUMN trash_retention_days INTEGER NOT NULL DEFAULT 30;
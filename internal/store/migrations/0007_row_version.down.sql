@@ -0,0 +1,3 @@
+ALTER TABLE caves DROP COLUMN version;
+ALTER TABLE tobaccos DROP COLUMN version;
+ALTER TABLE wines DROP COLUMN version;
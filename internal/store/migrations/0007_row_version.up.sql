@@ -0,0 +1,6 @@
+-- Version de ligne pour le contrôle de concurrence optimiste (ETag / If-Match).
+-- Chaque écriture incrémente la version ; une mise à jour conditionnelle
+-- échoue si la version a changé depuis la lecture.
+ALTER TABLE wines ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE tobaccos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE caves ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS tobaccos_occupancy_delete;
+DROP TRIGGER IF EXISTS tobaccos_occupancy_update;
+DROP TRIGGER IF EXISTS tobaccos_occupancy_insert;
+DROP TRIGGER IF EXISTS wines_occupancy_delete;
+DROP TRIGGER IF EXISTS wines_occupancy_update;
+DROP TRIGGER IF EXISTS wines_occupancy_insert;
+DROP VIEW IF EXISTS stored_items;
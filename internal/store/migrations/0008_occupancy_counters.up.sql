@@ -0,0 +1,59 @@
+-- Occupation des caves et emplacements (colonnes current) maintenue par triggers.
+-- Un vin ou un tabac compte pour sa quantité tant qu'il n'est pas dans la corbeille.
+
+-- Un tabac rangé dans un emplacement appartient à la cave de cet emplacement
+UPDATE tobaccos SET cave_id = (SELECT cave_id FROM cells WHERE cells.id = tobaccos.cell_id)
+WHERE cave_id IS NULL AND cell_id IS NOT NULL;
+
+CREATE VIEW IF NOT EXISTS stored_items AS
+	SELECT cell_id, cave_id, quantity FROM wines WHERE deleted_at IS NULL
+	UNION ALL
+	SELECT cell_id, cave_id, quantity FROM tobaccos WHERE deleted_at IS NULL;
+
+-- Vins
+CREATE TRIGGER IF NOT EXISTS wines_occupancy_insert AFTER INSERT ON wines BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id = new.cell_id;
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id = new.cave_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS wines_occupancy_update AFTER UPDATE OF quantity, cell_id, cave_id, deleted_at ON wines BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id IN (old.cell_id, new.cell_id);
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id IN (old.cave_id, new.cave_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS wines_occupancy_delete AFTER DELETE ON wines BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id = old.cell_id;
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id = old.cave_id;
+END;
+
+-- Tabacs
+CREATE TRIGGER IF NOT EXISTS tobaccos_occupancy_insert AFTER INSERT ON tobaccos BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id = new.cell_id;
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id = new.cave_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS tobaccos_occupancy_update AFTER UPDATE OF quantity, cell_id, cave_id, deleted_at ON tobaccos BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id IN (old.cell_id, new.cell_id);
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id IN (old.cave_id, new.cave_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS tobaccos_occupancy_delete AFTER DELETE ON tobaccos BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id = old.cell_id;
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id = old.cave_id;
+END;
+
+-- Rattrapage des compteurs existants (toujours à 0 jusqu'ici)
+UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id);
+UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id);
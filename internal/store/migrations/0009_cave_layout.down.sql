@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_cells_position;
+ALTER TABLE cells DROP COLUMN column_no;
+ALTER TABLE cells DROP COLUMN row_no;
+ALTER TABLE cells DROP COLUMN rack;
+ALTER TABLE cells DROP COLUMN zone;
+ALTER TABLE caves DROP COLUMN layout;
@@ -0,0 +1,11 @@
+-- Agencement des caves (zones, clayettes, rangées, colonnes, profondeur).
+-- Les emplacements générés depuis l'agencement portent leur position.
+ALTER TABLE caves ADD COLUMN layout TEXT;
+
+ALTER TABLE cells ADD COLUMN zone TEXT;
+ALTER TABLE cells ADD COLUMN rack INTEGER;
+ALTER TABLE cells ADD COLUMN row_no INTEGER;
+ALTER TABLE cells ADD COLUMN column_no INTEGER;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cells_position ON cells(cave_id, zone, rack, row_no, column_no)
+WHERE zone IS NOT NULL AND deleted_at IS NULL;
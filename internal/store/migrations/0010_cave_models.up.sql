@@ -0,0 +1,8 @@
+-- Modèles de caves ajoutés par les administrateurs, en complément du catalogue intégré.
+CREATE TABLE IF NOT EXISTS cave_models (
+    id TEXT PRIMARY KEY,
+    brand TEXT NOT NULL,
+    name TEXT NOT NULL,
+    layout TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_bottle_movements_target;
+DROP INDEX IF EXISTS idx_bottle_movements_wine;
+DROP TABLE IF EXISTS bottle_movements;
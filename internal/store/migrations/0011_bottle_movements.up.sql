@@ -0,0 +1,18 @@
+-- Historique des déplacements de bouteilles entre caves et emplacements.
+-- wine_id est l'entrée d'origine ; target_wine_id celle qui reçoit les bouteilles
+-- (la même pour un déplacement complet, une nouvelle entrée pour un déplacement partiel).
+CREATE TABLE IF NOT EXISTS bottle_movements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    wine_id INTEGER NOT NULL,
+    target_wine_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    from_cave_id INTEGER,
+    from_cell_id INTEGER,
+    to_cave_id INTEGER,
+    to_cell_id INTEGER,
+    note TEXT NOT NULL DEFAULT '',
+    moved_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_bottle_movements_wine ON bottle_movements(wine_id);
+CREATE INDEX IF NOT EXISTS idx_bottle_movements_target ON bottle_movements(target_wine_id);
@@ -0,0 +1,48 @@
+DROP TRIGGER IF EXISTS bottle_units_occupancy_delete;
+DROP TRIGGER IF EXISTS bottle_units_occupancy_update;
+DROP TRIGGER IF EXISTS bottle_units_occupancy_insert;
+DROP TRIGGER IF EXISTS wines_units_delete;
+DROP TRIGGER IF EXISTS wines_occupancy_update;
+DROP TRIGGER IF EXISTS wines_units_position;
+DROP TRIGGER IF EXISTS wines_units_quantity;
+DROP TRIGGER IF EXISTS wines_units_insert;
+
+-- Retour à l'occupation au compteur (migration 0008)
+DROP VIEW IF EXISTS stored_items;
+CREATE VIEW stored_items AS
+	SELECT cell_id, cave_id, quantity FROM wines WHERE deleted_at IS NULL
+	UNION ALL
+	SELECT cell_id, cave_id, quantity FROM tobaccos WHERE deleted_at IS NULL;
+
+CREATE TRIGGER IF NOT EXISTS wines_occupancy_insert AFTER INSERT ON wines BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id = new.cell_id;
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id = new.cave_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS wines_occupancy_update AFTER UPDATE OF quantity, cell_id, cave_id, deleted_at ON wines BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id IN (old.cell_id, new.cell_id);
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id IN (old.cave_id, new.cave_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS wines_occupancy_delete AFTER DELETE ON wines BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id = old.cell_id;
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id = old.cave_id;
+END;
+
+UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id);
+UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id);
+
+DROP VIEW IF EXISTS unit_sequence;
+ALTER TABLE consumption_history DROP COLUMN unit_id;
+ALTER TABLE bottle_movements DROP COLUMN unit_id;
+DROP INDEX IF EXISTS idx_bottle_units_barcode;
+DROP INDEX IF EXISTS idx_bottle_units_cave;
+DROP INDEX IF EXISTS idx_bottle_units_cell;
+DROP INDEX IF EXISTS idx_bottle_units_wine;
+DROP TABLE IF EXISTS bottle_units;
@@ -0,0 +1,113 @@
+-- Suivi à la bouteille : chaque bouteille physique d'une entrée de vin a sa propre ligne
+-- (position, lot, prix d'achat, niveau, état, code-barres).
+-- wines.quantity reste le compteur agrégé : le nombre de bouteilles en stock de l'entrée.
+-- Les écritures sur le compteur créent ou consomment des bouteilles (triggers) ;
+-- les opérations à la bouteille recalculent le compteur.
+CREATE TABLE IF NOT EXISTS bottle_units (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    wine_id INTEGER NOT NULL,
+    cell_id INTEGER,
+    cave_id INTEGER,
+    lot TEXT NOT NULL DEFAULT '',
+    purchase_price REAL,
+    fill_level TEXT NOT NULL DEFAULT '',
+    state TEXT NOT NULL DEFAULT 'in_stock',
+    barcode TEXT,
+    state_changed_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_bottle_units_wine ON bottle_units(wine_id, state);
+CREATE INDEX IF NOT EXISTS idx_bottle_units_cell ON bottle_units(cell_id);
+CREATE INDEX IF NOT EXISTS idx_bottle_units_cave ON bottle_units(cave_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bottle_units_barcode ON bottle_units(barcode) WHERE barcode IS NOT NULL;
+
+-- Bouteille concernée par un déplacement ou une dégustation (NULL : au compteur)
+ALTER TABLE bottle_movements ADD COLUMN unit_id INTEGER;
+ALTER TABLE consumption_history ADD COLUMN unit_id INTEGER;
+
+-- Suite d'entiers pour créer n bouteilles dans un trigger (à borner par LIMIT)
+CREATE VIEW IF NOT EXISTS unit_sequence AS
+	WITH RECURSIVE seq(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq)
+	SELECT n FROM seq;
+
+-- Rattrapage : une bouteille en stock par unité des entrées existantes
+INSERT INTO bottle_units (wine_id, cell_id, cave_id, purchase_price, state, created_at)
+SELECT w.id, w.cell_id, w.cave_id, w.price, 'in_stock', w.created_at
+FROM wines w
+JOIN (SELECT n FROM unit_sequence LIMIT (SELECT COALESCE(MAX(quantity), 0) FROM wines)) s ON s.n <= w.quantity
+ORDER BY w.id, s.n;
+
+-- L'occupation des vins se compte désormais à la bouteille
+DROP TRIGGER IF EXISTS wines_occupancy_insert;
+DROP TRIGGER IF EXISTS wines_occupancy_update;
+DROP TRIGGER IF EXISTS wines_occupancy_delete;
+DROP VIEW IF EXISTS stored_items;
+CREATE VIEW stored_items AS
+	SELECT u.cell_id, u.cave_id, 1 AS quantity FROM bottle_units u
+	JOIN wines w ON w.id = u.wine_id
+	WHERE u.state = 'in_stock' AND w.deleted_at IS NULL
+	UNION ALL
+	SELECT cell_id, cave_id, quantity FROM tobaccos WHERE deleted_at IS NULL;
+
+-- Compteur -> bouteilles : créer les bouteilles manquantes, consommer les plus récentes en trop
+CREATE TRIGGER IF NOT EXISTS wines_units_insert AFTER INSERT ON wines BEGIN
+	INSERT INTO bottle_units (wine_id, cell_id, cave_id, purchase_price)
+	SELECT new.id, new.cell_id, new.cave_id, new.price FROM unit_sequence
+	LIMIT MAX(new.quantity, 0);
+END;
+
+CREATE TRIGGER IF NOT EXISTS wines_units_quantity AFTER UPDATE OF quantity ON wines BEGIN
+	INSERT INTO bottle_units (wine_id, cell_id, cave_id, purchase_price)
+	SELECT new.id, new.cell_id, new.cave_id, new.price FROM unit_sequence
+	LIMIT MAX(new.quantity - (SELECT COUNT(*) FROM bottle_units WHERE wine_id = new.id AND state = 'in_stock'), 0);
+	UPDATE bottle_units SET state = 'consumed', state_changed_at = CURRENT_TIMESTAMP
+	WHERE id IN (
+		SELECT id FROM bottle_units WHERE wine_id = new.id AND state = 'in_stock' ORDER BY id DESC
+		LIMIT MAX((SELECT COUNT(*) FROM bottle_units WHERE wine_id = new.id AND state = 'in_stock') - new.quantity, 0)
+	);
+END;
+
+-- Déplacer une entrée déplace ses bouteilles en stock
+CREATE TRIGGER IF NOT EXISTS wines_units_position AFTER UPDATE OF cell_id, cave_id ON wines
+WHEN old.cell_id IS NOT new.cell_id OR old.cave_id IS NOT new.cave_id BEGIN
+	UPDATE bottle_units SET cell_id = new.cell_id, cave_id = new.cave_id
+	WHERE wine_id = new.id AND state = 'in_stock';
+END;
+
+-- Corbeille : les bouteilles de l'entrée sortent ou reviennent dans leurs contenants
+CREATE TRIGGER IF NOT EXISTS wines_occupancy_update AFTER UPDATE OF deleted_at ON wines BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id IN (SELECT cell_id FROM bottle_units WHERE wine_id = new.id);
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id IN (SELECT cave_id FROM bottle_units WHERE wine_id = new.id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS wines_units_delete AFTER DELETE ON wines BEGIN
+	DELETE FROM bottle_units WHERE wine_id = old.id;
+END;
+
+-- Occupation à la bouteille
+CREATE TRIGGER IF NOT EXISTS bottle_units_occupancy_insert AFTER INSERT ON bottle_units BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id = new.cell_id;
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id = new.cave_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS bottle_units_occupancy_update AFTER UPDATE OF wine_id, cell_id, cave_id, state ON bottle_units BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id IN (old.cell_id, new.cell_id);
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id IN (old.cave_id, new.cave_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS bottle_units_occupancy_delete AFTER DELETE ON bottle_units BEGIN
+	UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id)
+	WHERE id = old.cell_id;
+	UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id)
+	WHERE id = old.cave_id;
+END;
+
+UPDATE cells SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id);
+UPDATE caves SET current = (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id);
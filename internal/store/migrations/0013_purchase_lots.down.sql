@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_purchase_lots_merchant;
+DROP INDEX IF EXISTS idx_purchase_lots_item;
+DROP TABLE IF EXISTS purchase_lots;
+DROP TABLE IF EXISTS merchants;
@@ -0,0 +1,39 @@
+-- Marchands (cavistes, domaines, sites) auprès desquels les achats sont faits.
+CREATE TABLE IF NOT EXISTS merchants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    website TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT '',
+    phone TEXT NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Lots d'achat d'un vin ou d'un tabac (item_type 'wine' ou 'tobacco').
+-- Le coût moyen et le coût du stock restant sont calculés depuis les lots.
+CREATE TABLE IF NOT EXISTS purchase_lots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_type TEXT NOT NULL,
+    item_id INTEGER NOT NULL,
+    merchant_id INTEGER,
+    purchase_date DATETIME NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price REAL NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'EUR',
+    invoice_ref TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_lots_item ON purchase_lots(item_type, item_id);
+CREATE INDEX IF NOT EXISTS idx_purchase_lots_merchant ON purchase_lots(merchant_id);
+
+-- Reprise des prix existants : un lot par article ayant un prix d'achat
+INSERT INTO purchase_lots (item_type, item_id, purchase_date, quantity, unit_price, notes)
+SELECT 'wine', id, created_at, quantity + consumed, price, 'imported from wine price'
+FROM wines WHERE price IS NOT NULL AND quantity + consumed > 0;
+
+INSERT INTO purchase_lots (item_type, item_id, purchase_date, quantity, unit_price, notes)
+SELECT 'tobacco', id, COALESCE(purchase_date, created_at), quantity, purchase_price, 'imported from tobacco purchase price'
+FROM tobaccos WHERE purchase_price IS NOT NULL AND quantity > 0;
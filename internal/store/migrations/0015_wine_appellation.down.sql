@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_wines_appellation;
+ALTER TABLE wines DROP COLUMN appellation_id;
@@ -0,0 +1,4 @@
+-- Rattachement des vins à une appellation du référentiel géographique intégré
+-- (internal/store/catalog/geo.json). La région en texte libre est conservée.
+ALTER TABLE wines ADD COLUMN appellation_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_wines_appellation ON wines(appellation_id);
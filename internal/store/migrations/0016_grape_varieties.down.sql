@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_wine_grapes_grape;
+DROP TABLE IF EXISTS wine_grapes;
+DROP TABLE IF EXISTS grape_varieties;
@@ -0,0 +1,97 @@
+-- Référentiel des cépages. Les synonymes (JSON) sont reconnus comme le nom :
+-- un vin saisi avec "Shiraz" est rattaché à la Syrah. Les cépages inconnus sont
+-- ajoutés à la volée lors de la saisie d'un assemblage.
+CREATE TABLE IF NOT EXISTS grape_varieties (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    color TEXT NOT NULL DEFAULT '',
+    synonyms TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Assemblage d'un vin : un cépage par ligne, pourcentage facultatif.
+-- position conserve l'ordre de saisie (cépage dominant en premier).
+CREATE TABLE IF NOT EXISTS wine_grapes (
+    wine_id INTEGER NOT NULL,
+    grape_id INTEGER NOT NULL,
+    percentage REAL,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (wine_id, grape_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wine_grapes_grape ON wine_grapes(grape_id);
+
+INSERT OR IGNORE INTO grape_varieties (name, color, synonyms) VALUES
+    ('Cabernet Sauvignon', 'red', '[]'),
+    ('Merlot', 'red', '[]'),
+    ('Cabernet Franc', 'red', '["Bouchet","Breton"]'),
+    ('Syrah', 'red', '["Shiraz"]'),
+    ('Grenache', 'red', '["Garnacha","Grenache Noir","Cannonau","Garnatxa"]'),
+    ('Mourvèdre', 'red', '["Monastrell","Mataro"]'),
+    ('Carignan', 'red', '["Cariñena","Mazuelo","Carignano","Samsó"]'),
+    ('Cinsault', 'red', '["Cinsaut"]'),
+    ('Pinot Noir', 'red', '["Spätburgunder","Pinot Nero","Blauburgunder"]'),
+    ('Pinot Meunier', 'red', '["Meunier","Schwarzriesling"]'),
+    ('Gamay', 'red', '["Gamay Noir"]'),
+    ('Malbec', 'red', '["Côt","Cot"]'),
+    ('Petit Verdot', 'red', '[]'),
+    ('Carmenère', 'red', '[]'),
+    ('Tannat', 'red', '[]'),
+    ('Tempranillo', 'red', '["Tinta Roriz","Aragonez","Tinto Fino","Cencibel","Tinta del País","Ull de Llebre"]'),
+    ('Sangiovese', 'red', '["Brunello","Morellino","Prugnolo Gentile","Nielluccio"]'),
+    ('Nebbiolo', 'red', '["Spanna","Chiavennasca"]'),
+    ('Barbera', 'red', '[]'),
+    ('Dolcetto', 'red', '[]'),
+    ('Zinfandel', 'red', '["Primitivo","Tribidrag"]'),
+    ('Montepulciano', 'red', '[]'),
+    ('Nero d''Avola', 'red', '["Calabrese"]'),
+    ('Aglianico', 'red', '[]'),
+    ('Corvina', 'red', '[]'),
+    ('Touriga Nacional', 'red', '[]'),
+    ('Pinotage', 'red', '[]'),
+    ('Blaufränkisch', 'red', '["Lemberger","Kékfrankos"]'),
+    ('Zweigelt', 'red', '[]'),
+    ('Mencía', 'red', '["Jaen"]'),
+    ('Poulsard', 'red', '["Ploussard"]'),
+    ('Trousseau', 'red', '["Bastardo"]'),
+    ('Négrette', 'red', '[]'),
+    ('Xinomavro', 'red', '[]'),
+    ('Saperavi', 'red', '[]'),
+    ('Chardonnay', 'white', '["Morillon"]'),
+    ('Sauvignon Blanc', 'white', '["Sauvignon","Fumé Blanc"]'),
+    ('Riesling', 'white', '[]'),
+    ('Chenin Blanc', 'white', '["Pineau de la Loire","Steen"]'),
+    ('Sémillon', 'white', '[]'),
+    ('Viognier', 'white', '[]'),
+    ('Marsanne', 'white', '[]'),
+    ('Roussanne', 'white', '[]'),
+    ('Gewurztraminer', 'white', '["Gewürztraminer","Traminer"]'),
+    ('Pinot Gris', 'white', '["Pinot Grigio","Grauburgunder","Ruländer"]'),
+    ('Pinot Blanc', 'white', '["Pinot Bianco","Weissburgunder"]'),
+    ('Muscat', 'white', '["Moscato","Moscatel","Muskateller","Muscat Blanc à Petits Grains"]'),
+    ('Melon de Bourgogne', 'white', '["Melon","Muscadet"]'),
+    ('Grüner Veltliner', 'white', '[]'),
+    ('Albariño', 'white', '["Alvarinho"]'),
+    ('Vermentino', 'white', '["Rolle","Pigato","Favorita"]'),
+    ('Verdejo', 'white', '[]'),
+    ('Godello', 'white', '[]'),
+    ('Garganega', 'white', '[]'),
+    ('Glera', 'white', '["Prosecco"]'),
+    ('Trebbiano', 'white', '["Ugni Blanc"]'),
+    ('Macabeo', 'white', '["Viura","Macabeu"]'),
+    ('Xarel·lo', 'white', '["Xarello"]'),
+    ('Parellada', 'white', '[]'),
+    ('Aligoté', 'white', '[]'),
+    ('Savagnin', 'white', '[]'),
+    ('Furmint', 'white', '[]'),
+    ('Muscadelle', 'white', '[]'),
+    ('Palomino', 'white', '["Listán"]'),
+    ('Pedro Ximénez', 'white', '[]'),
+    ('Colombard', 'white', '[]'),
+    ('Petit Manseng', 'white', '[]'),
+    ('Gros Manseng', 'white', '[]'),
+    ('Assyrtiko', 'white', '[]'),
+    ('Silvaner', 'white', '["Sylvaner"]'),
+    ('Torrontés', 'white', '[]'),
+    ('Fiano', 'white', '[]'),
+    ('Chasselas', 'white', '["Fendant","Gutedel"]');
@@ -0,0 +1,6 @@
+ALTER TABLE consumption_history DROP COLUMN glass_ml;
+ALTER TABLE consumption_history DROP COLUMN glasses;
+ALTER TABLE consumption_history DROP COLUMN volume_ml;
+ALTER TABLE bottle_units DROP COLUMN remaining_ml;
+ALTER TABLE wines DROP COLUMN volume_ml;
+ALTER TABLE wines DROP COLUMN format;
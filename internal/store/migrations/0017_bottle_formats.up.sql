@@ -0,0 +1,15 @@
+-- Formats de bouteille : chaque entrée a un format (voir domain.BottleFormats) et un volume en ml.
+-- Les dégustations peuvent se faire au verre ou en ml depuis une bouteille ouverte.
+ALTER TABLE wines ADD COLUMN format TEXT NOT NULL DEFAULT 'standard';
+ALTER TABLE wines ADD COLUMN volume_ml INTEGER NOT NULL DEFAULT 750;
+
+UPDATE wines SET format = 'beer_bottle_330', volume_ml = 330 WHERE type = 'beer';
+UPDATE wines SET format = 'spirit_700', volume_ml = 700 WHERE type = 'spirit';
+
+-- Reste d'une bouteille ouverte (NULL : pleine)
+ALTER TABLE bottle_units ADD COLUMN remaining_ml INTEGER;
+
+-- Volume servi par dégustation (NULL pour les anciennes lignes : quantity bouteilles entières)
+ALTER TABLE consumption_history ADD COLUMN volume_ml INTEGER;
+ALTER TABLE consumption_history ADD COLUMN glasses REAL;
+ALTER TABLE consumption_history ADD COLUMN glass_ml INTEGER;
@@ -0,0 +1,2 @@
+ALTER TABLE bottle_units DROP COLUMN preservation;
+ALTER TABLE bottle_units DROP COLUMN opened_at;
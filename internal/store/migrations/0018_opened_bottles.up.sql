@@ -0,0 +1,6 @@
+-- Bouteilles ouvertes : date d'ouverture et méthode de conservation (bouchon, vide d'air,
+-- gaz inerte, Coravin...). La durée de conservation en découle (domain.ShelfLifeDays).
+ALTER TABLE bottle_units ADD COLUMN opened_at DATETIME;
+ALTER TABLE bottle_units ADD COLUMN preservation TEXT NOT NULL DEFAULT '';
+
+UPDATE bottle_units SET opened_at = COALESCE(state_changed_at, created_at) WHERE state = 'opened';
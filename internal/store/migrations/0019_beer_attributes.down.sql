@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_wines_best_before;
+ALTER TABLE wines DROP COLUMN best_before;
+ALTER TABLE wines DROP COLUMN packaged_at;
+ALTER TABLE wines DROP COLUMN ebc;
+ALTER TABLE wines DROP COLUMN ibu;
+ALTER TABLE wines DROP COLUMN style;
@@ -0,0 +1,9 @@
+-- Attributs des bières : style, amertume (IBU), couleur (EBC), date de conditionnement
+-- et date de durabilité minimale (alerte best_before_approaching). La brasserie est le producteur.
+ALTER TABLE wines ADD COLUMN style TEXT NOT NULL DEFAULT '';
+ALTER TABLE wines ADD COLUMN ibu INTEGER;
+ALTER TABLE wines ADD COLUMN ebc INTEGER;
+ALTER TABLE wines ADD COLUMN packaged_at DATETIME;
+ALTER TABLE wines ADD COLUMN best_before DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_wines_best_before ON wines(best_before) WHERE best_before IS NOT NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE wines DROP COLUMN independent_bottler;
+ALTER TABLE wines DROP COLUMN cask_strength;
+ALTER TABLE wines DROP COLUMN bottled_year;
+ALTER TABLE wines DROP COLUMN distilled_year;
+ALTER TABLE wines DROP COLUMN cask_number;
+ALTER TABLE wines DROP COLUMN cask_type;
+ALTER TABLE wines DROP COLUMN distillery;
+ALTER TABLE wines DROP COLUMN age_statement;
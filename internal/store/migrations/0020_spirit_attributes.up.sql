@@ -0,0 +1,11 @@
+-- Attributs des spiritueux : compte d'âge (NULL : sans âge), distillerie, fût, années de
+-- distillation et de mise en bouteille, brut de fût et embouteilleur indépendant.
+-- La catégorie (single malt, rhum agricole...) partage la colonne style des bières.
+ALTER TABLE wines ADD COLUMN age_statement INTEGER;
+ALTER TABLE wines ADD COLUMN distillery TEXT NOT NULL DEFAULT '';
+ALTER TABLE wines ADD COLUMN cask_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE wines ADD COLUMN cask_number TEXT NOT NULL DEFAULT '';
+ALTER TABLE wines ADD COLUMN distilled_year INTEGER;
+ALTER TABLE wines ADD COLUMN bottled_year INTEGER;
+ALTER TABLE wines ADD COLUMN cask_strength INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE wines ADD COLUMN independent_bottler TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,96 @@
+DROP TRIGGER IF EXISTS consumption_search_delete;
+DROP TRIGGER IF EXISTS consumption_search_update;
+DROP TRIGGER IF EXISTS consumption_search_insert;
+DROP TRIGGER IF EXISTS tobaccos_search_delete;
+DROP TRIGGER IF EXISTS tobaccos_search_update;
+DROP TRIGGER IF EXISTS tobaccos_search_insert;
+DROP TRIGGER IF EXISTS wines_search_delete;
+DROP TRIGGER IF EXISTS wines_search_update;
+DROP TRIGGER IF EXISTS wines_search_insert;
+DROP TABLE IF EXISTS search_index;
+
+-- Retour à l'index de 0004 (suppression par ref_id)
+-- Index plein texte sur les vins, tabacs et commentaires de dégustation.
+-- remove_diacritics permet à "Rose" de trouver "Rosé".
+CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+	kind UNINDEXED,
+	ref_id UNINDEXED,
+	wine_id UNINDEXED,
+	name,
+	details,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+-- Vins
+CREATE TRIGGER IF NOT EXISTS wines_search_insert AFTER INSERT ON wines BEGIN
+	INSERT INTO search_index (kind, ref_id, wine_id, name, details)
+	VALUES ('wine', new.id, new.id, new.name,
+		COALESCE(new.producer, '') || ' ' || COALESCE(new.region, '') || ' ' || COALESCE(new.vintage, '') || ' ' || COALESCE(new.comments, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS wines_search_update AFTER UPDATE ON wines BEGIN
+	DELETE FROM search_index WHERE kind = 'wine' AND ref_id = old.id;
+	INSERT INTO search_index (kind, ref_id, wine_id, name, details)
+	VALUES ('wine', new.id, new.id, new.name,
+		COALESCE(new.producer, '') || ' ' || COALESCE(new.region, '') || ' ' || COALESCE(new.vintage, '') || ' ' || COALESCE(new.comments, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS wines_search_delete AFTER DELETE ON wines BEGIN
+	DELETE FROM search_index WHERE kind = 'wine' AND ref_id = old.id;
+	DELETE FROM search_index WHERE kind = 'consumption' AND wine_id = old.id;
+END;
+
+-- Tabacs
+CREATE TRIGGER IF NOT EXISTS tobaccos_search_insert AFTER INSERT ON tobaccos BEGIN
+	INSERT INTO search_index (kind, ref_id, wine_id, name, details)
+	VALUES ('tobacco', new.id, NULL, new.name,
+		COALESCE(new.brand, '') || ' ' || COALESCE(new.origin_country, '') || ' ' || COALESCE(new.format, '') || ' ' ||
+		COALESCE(new.wrapper, '') || ' ' || COALESCE(new.binder, '') || ' ' || COALESCE(new.notes, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS tobaccos_search_update AFTER UPDATE ON tobaccos BEGIN
+	DELETE FROM search_index WHERE kind = 'tobacco' AND ref_id = old.id;
+	INSERT INTO search_index (kind, ref_id, wine_id, name, details)
+	VALUES ('tobacco', new.id, NULL, new.name,
+		COALESCE(new.brand, '') || ' ' || COALESCE(new.origin_country, '') || ' ' || COALESCE(new.format, '') || ' ' ||
+		COALESCE(new.wrapper, '') || ' ' || COALESCE(new.binder, '') || ' ' || COALESCE(new.notes, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS tobaccos_search_delete AFTER DELETE ON tobaccos BEGIN
+	DELETE FROM search_index WHERE kind = 'tobacco' AND ref_id = old.id;
+END;
+
+-- Commentaires de dégustation (rattachés au vin)
+CREATE TRIGGER IF NOT EXISTS consumption_search_insert AFTER INSERT ON consumption_history
+WHEN COALESCE(new.comment, '') <> '' BEGIN
+	INSERT INTO search_index (kind, ref_id, wine_id, name, details)
+	VALUES ('consumption', new.id, new.wine_id, '', new.comment);
+END;
+
+CREATE TRIGGER IF NOT EXISTS consumption_search_update AFTER UPDATE ON consumption_history BEGIN
+	DELETE FROM search_index WHERE kind = 'consumption' AND ref_id = old.id;
+	INSERT INTO search_index (kind, ref_id, wine_id, name, details)
+	SELECT 'consumption', new.id, new.wine_id, '', new.comment
+	WHERE COALESCE(new.comment, '') <> '';
+END;
+
+CREATE TRIGGER IF NOT EXISTS consumption_search_delete AFTER DELETE ON consumption_history BEGIN
+	DELETE FROM search_index WHERE kind = 'consumption' AND ref_id = old.id;
+END;
+
+-- Indexation des données existantes
+INSERT INTO search_index (kind, ref_id, wine_id, name, details)
+SELECT 'wine', id, id, name,
+	COALESCE(producer, '') || ' ' || COALESCE(region, '') || ' ' || COALESCE(vintage, '') || ' ' || COALESCE(comments, '')
+FROM wines;
+
+INSERT INTO search_index (kind, ref_id, wine_id, name, details)
+SELECT 'tobacco', id, NULL, name,
+	COALESCE(brand, '') || ' ' || COALESCE(origin_country, '') || ' ' || COALESCE(format, '') || ' ' ||
+	COALESCE(wrapper, '') || ' ' || COALESCE(binder, '') || ' ' || COALESCE(notes, '')
+FROM tobaccos;
+
+INSERT INTO search_index (kind, ref_id, wine_id, name, details)
+SELECT 'consumption', id, wine_id, '', comment
+FROM consumption_history
+WHERE COALESCE(comment, '') <> '';
@@ -0,0 +1,97 @@
+-- Les triggers de 0004 supprimaient par ref_id, colonne UNINDEXED : chaque mise à jour
+-- d'un vin (quantité, version...) parcourait tout l'index. L'index est désormais indexé
+-- par rowid = id * 4 + type (1 : vin, 2 : tabac, 3 : commentaire de dégustation),
+-- et seules les colonnes indexées déclenchent sa mise à jour.
+DROP TRIGGER IF EXISTS consumption_search_delete;
+DROP TRIGGER IF EXISTS consumption_search_update;
+DROP TRIGGER IF EXISTS consumption_search_insert;
+DROP TRIGGER IF EXISTS tobaccos_search_delete;
+DROP TRIGGER IF EXISTS tobaccos_search_update;
+DROP TRIGGER IF EXISTS tobaccos_search_insert;
+DROP TRIGGER IF EXISTS wines_search_delete;
+DROP TRIGGER IF EXISTS wines_search_update;
+DROP TRIGGER IF EXISTS wines_search_insert;
+DROP TABLE IF EXISTS search_index;
+
+CREATE VIRTUAL TABLE search_index USING fts5(
+	kind UNINDEXED,
+	ref_id UNINDEXED,
+	wine_id UNINDEXED,
+	name,
+	details,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+-- Vins
+CREATE TRIGGER wines_search_insert AFTER INSERT ON wines BEGIN
+	INSERT INTO search_index (rowid, kind, ref_id, wine_id, name, details)
+	VALUES (new.id * 4 + 1, 'wine', new.id, new.id, new.name,
+		COALESCE(new.producer, '') || ' ' || COALESCE(new.region, '') || ' ' || COALESCE(new.vintage, '') || ' ' || COALESCE(new.comments, ''));
+END;
+
+CREATE TRIGGER wines_search_update AFTER UPDATE OF name, producer, region, vintage, comments ON wines BEGIN
+	DELETE FROM search_index WHERE rowid = old.id * 4 + 1;
+	INSERT INTO search_index (rowid, kind, ref_id, wine_id, name, details)
+	VALUES (new.id * 4 + 1, 'wine', new.id, new.id, new.name,
+		COALESCE(new.producer, '') || ' ' || COALESCE(new.region, '') || ' ' || COALESCE(new.vintage, '') || ' ' || COALESCE(new.comments, ''));
+END;
+
+CREATE TRIGGER wines_search_delete AFTER DELETE ON wines BEGIN
+	DELETE FROM search_index WHERE rowid = old.id * 4 + 1;
+	DELETE FROM search_index WHERE rowid IN (SELECT id * 4 + 3 FROM consumption_history WHERE wine_id = old.id);
+END;
+
+-- Tabacs
+CREATE TRIGGER tobaccos_search_insert AFTER INSERT ON tobaccos BEGIN
+	INSERT INTO search_index (rowid, kind, ref_id, wine_id, name, details)
+	VALUES (new.id * 4 + 2, 'tobacco', new.id, NULL, new.name,
+		COALESCE(new.brand, '') || ' ' || COALESCE(new.origin_country, '') || ' ' || COALESCE(new.format, '') || ' ' ||
+		COALESCE(new.wrapper, '') || ' ' || COALESCE(new.binder, '') || ' ' || COALESCE(new.notes, ''));
+END;
+
+CREATE TRIGGER tobaccos_search_update AFTER UPDATE OF name, brand, origin_country, format, wrapper, binder, notes ON tobaccos BEGIN
+	DELETE FROM search_index WHERE rowid = old.id * 4 + 2;
+	INSERT INTO search_index (rowid, kind, ref_id, wine_id, name, details)
+	VALUES (new.id * 4 + 2, 'tobacco', new.id, NULL, new.name,
+		COALESCE(new.brand, '') || ' ' || COALESCE(new.origin_country, '') || ' ' || COALESCE(new.format, '') || ' ' ||
+		COALESCE(new.wrapper, '') || ' ' || COALESCE(new.binder, '') || ' ' || COALESCE(new.notes, ''));
+END;
+
+CREATE TRIGGER tobaccos_search_delete AFTER DELETE ON tobaccos BEGIN
+	DELETE FROM search_index WHERE rowid = old.id * 4 + 2;
+END;
+
+-- Commentaires de dégustation (rattachés au vin)
+CREATE TRIGGER consumption_search_insert AFTER INSERT ON consumption_history
+WHEN COALESCE(new.comment, '') <> '' BEGIN
+	INSERT INTO search_index (rowid, kind, ref_id, wine_id, name, details)
+	VALUES (new.id * 4 + 3, 'consumption', new.id, new.wine_id, '', new.comment);
+END;
+
+CREATE TRIGGER consumption_search_update AFTER UPDATE OF wine_id, comment ON consumption_history BEGIN
+	DELETE FROM search_index WHERE rowid = old.id * 4 + 3;
+	INSERT INTO search_index (rowid, kind, ref_id, wine_id, name, details)
+	SELECT new.id * 4 + 3, 'consumption', new.id, new.wine_id, '', new.comment
+	WHERE COALESCE(new.comment, '') <> '';
+END;
+
+CREATE TRIGGER consumption_search_delete AFTER DELETE ON consumption_history BEGIN
+	DELETE FROM search_index WHERE rowid = old.id * 4 + 3;
+END;
+
+-- Réindexation des données existantes
+INSERT INTO search_index (rowid, kind, ref_id, wine_id, name, details)
+SELECT id * 4 + 1, 'wine', id, id, name,
+	COALESCE(producer, '') || ' ' || COALESCE(region, '') || ' ' || COALESCE(vintage, '') || ' ' || COALESCE(comments, '')
+FROM wines;
+
+INSERT INTO search_index (rowid, kind, ref_id, wine_id, name, details)
+SELECT id * 4 + 2, 'tobacco', id, NULL, name,
+	COALESCE(brand, '') || ' ' || COALESCE(origin_country, '') || ' ' || COALESCE(format, '') || ' ' ||
+	COALESCE(wrapper, '') || ' ' || COALESCE(binder, '') || ' ' || COALESCE(notes, '')
+FROM tobaccos;
+
+INSERT INTO search_index (rowid, kind, ref_id, wine_id, name, details)
+SELECT id * 4 + 3, 'consumption', id, wine_id, '', comment
+FROM consumption_history
+WHERE COALESCE(comment, '') <> '';
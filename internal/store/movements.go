@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// movementColumns lit un déplacement avec les noms des caves et emplacements (alias m)
+const movementColumns = `m.id, m.wine_id, m.target_wine_id, m.unit_id, m.quantity,
+	m.from_cave_id, m.from_cell_id, m.to_cave_id, m.to_cell_id,
+	COALESCE(fc.name, ''), COALESCE(fl.location, ''), COALESCE(tc.name, ''), COALESCE(tl.location, ''),
+	m.note, m.moved_at
+	FROM bottle_movements m
+	LEFT JOIN caves fc ON fc.id = m.from_cave_id
+	LEFT JOIN cells fl ON fl.id = m.from_cell_id
+	LEFT JOIN caves tc ON tc.id = m.to_cave_id
+	LEFT JOIN cells tl ON tl.id = m.to_cell_id`
+
+// scanMovement lit une ligne sélectionnée avec movementColumns
+func scanMovement(row rowScanner) (*domain.BottleMovement, error) {
+	m := &domain.BottleMovement{}
+	err := row.Scan(&m.ID, &m.WineID, &m.TargetWineID, &m.UnitID, &m.Quantity,
+		&m.FromCaveID, &m.FromCellID, &m.ToCaveID, &m.ToCellID,
+		&m.FromCave, &m.FromCell, &m.ToCave, &m.ToCell,
+		&m.Note, &m.MovedAt)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// nullableID convertit un identifiant nullable SQL en pointeur
+func nullableID(id sql.NullInt64) *int64 {
+	if !id.Valid {
+		return nil
+	}
+	return &id.Int64
+}
+
+// recordMovement enregistre un déplacement dans la transaction (unitID : bouteille déplacée seule)
+func recordMovement(ctx context.Context, tx *sql.Tx, wineID, targetWineID int64, unitID *int64, quantity int, from, to stock, note string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `
+	INSERT INTO bottle_movements (wine_id, target_wine_id, unit_id, quantity, from_cave_id, from_cell_id, to_cave_id, to_cell_id, note, moved_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		wineID, targetWineID, unitID, quantity, from.caveID, from.cellID, to.caveID, to.cellID, note, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to record bottle movement: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// moveDestination résout la destination d'un déplacement : la cave est déduite de
+// l'emplacement ; une cave seule (ex. garde extérieure) laisse l'emplacement vide
+func moveDestination(ctx context.Context, tx *sql.Tx, caveID, cellID *int64) (stock, error) {
+	var to stock
+	switch {
+	case cellID != nil:
+		var cellCave sql.NullInt64
+		err := tx.QueryRowContext(ctx,
+			`SELECT cave_id FROM cells WHERE id = ? AND deleted_at IS NULL`, *cellID).Scan(&cellCave)
+		if err == sql.ErrNoRows {
+			return stock{}, NewValidationError("cell_id", fmt.Sprintf("cell %d does not exist", *cellID))
+		}
+		if err != nil {
+			return stock{}, fmt.Errorf("failed to check cell: %w", err)
+		}
+		if caveID != nil && (!cellCave.Valid || cellCave.Int64 != *caveID) {
+			return stock{}, NewValidationError("cell_id", fmt.Sprintf("cell %d does not belong to cave %d", *cellID, *caveID))
+		}
+		to.cellID = sql.NullInt64{Int64: *cellID, Valid: true}
+		to.caveID = cellCave
+	case caveID != nil:
+		var found int
+		err := tx.QueryRowContext(ctx,
+			`SELECT 1 FROM caves WHERE id = ? AND deleted_at IS NULL`, *caveID).Scan(&found)
+		if err == sql.ErrNoRows {
+			return stock{}, NewValidationError("cave_id", fmt.Sprintf("cave %d does not exist", *caveID))
+		}
+		if err != nil {
+			return stock{}, fmt.Errorf("failed to check cave: %w", err)
+		}
+		to.caveID = sql.NullInt64{Int64: *caveID, Valid: true}
+	default:
+		return stock{}, NewValidationError("cell_id", "cave_id or cell_id is required")
+	}
+	return to, nil
+}
+
+// checkPlacement vérifie qu'un emplacement ou une cave désignés existent et ne sont pas
+// dans la corbeille (ErrValidation). Aucun des deux : l'élément n'est pas rangé.
+func checkPlacement(ctx context.Context, tx *sql.Tx, caveID, cellID *int64) error {
+	if caveID == nil && cellID == nil {
+		return nil
+	}
+	_, err := moveDestination(ctx, tx, caveID, cellID)
+	return err
+}
+
+// MoveWine déplace quantity bouteilles d'un vin vers une cave et/ou un emplacement.
+// quantity nulle déplace toute l'entrée ; un déplacement partiel scinde l'entrée :
+// les bouteilles déplacées forment une nouvelle entrée du même vin.
+// version, si non nulle, est la version attendue de l'entrée d'origine (If-Match).
+func (s *Store) MoveWine(ctx context.Context, id int64, quantity int, caveID, cellID *int64, note string, version int64, overflow bool) (*domain.MoveResult, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wine, err := scanWine(tx.QueryRowContext(ctx,
+		`SELECT `+wineColumns+` FROM wines WHERE id = ? AND deleted_at IS NULL`, id))
+	if err == sql.ErrNoRows {
+		return nil, notFound("wine", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wine: %w", err)
+	}
+	if version != 0 && wine.Version != version {
+		return nil, StaleVersion("wine", id, wine.Version)
+	}
+	if wine.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: wine %d has no bottle in stock", ErrConflict, id)
+	}
+	if quantity == 0 {
+		quantity = wine.Quantity
+	}
+	if quantity < 0 || quantity > wine.Quantity {
+		return nil, NewValidationError("quantity", fmt.Sprintf("quantity must be between 1 and %d", wine.Quantity))
+	}
+
+	from, err := readStock(ctx, tx, "wines", id)
+	if err != nil {
+		return nil, err
+	}
+	to, err := moveDestination(ctx, tx, caveID, cellID)
+	if err != nil {
+		return nil, err
+	}
+	if from.caveID == to.caveID && from.cellID == to.cellID {
+		return nil, NewValidationError("cell_id", "wine is already stored there")
+	}
+
+	targetID := id
+	if quantity == wine.Quantity {
+		// Déplacement complet : l'entrée change de place
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE wines SET cave_id = ?, cell_id = ?, version = version + 1 WHERE id = ?`,
+			to.caveID, to.cellID, id); err != nil {
+			return nil, fmt.Errorf("failed to move wine: %w", err)
+		}
+		if !overflow {
+			after, err := readStock(ctx, tx, "wines", id)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkCapacity(ctx, tx, from, after); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// Déplacement partiel : scission de l'entrée, la nouvelle repart sans consommation
+		if targetID, err = splitWine(ctx, tx, wine, quantity, to); err != nil {
+			return nil, err
+		}
+		if !overflow {
+			placed, err := readStock(ctx, tx, "wines", targetID)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkCapacity(ctx, tx, stock{}, placed); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	movementID, err := recordMovement(ctx, tx, id, targetID, nil, quantity, from, to, note)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result := &domain.MoveResult{}
+	if result.Source, err = s.GetWineByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if result.Target, err = s.GetWineByID(ctx, targetID); err != nil {
+		return nil, err
+	}
+	if result.Movement, err = s.getMovement(ctx, movementID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getMovement relit un déplacement enregistré
+func (s *Store) getMovement(ctx context.Context, id int64) (*domain.BottleMovement, error) {
+	movement, err := scanMovement(s.Db.QueryRowContext(ctx, `SELECT `+movementColumns+` WHERE m.id = ?`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bottle movement: %w", err)
+	}
+	return movement, nil
+}
+
+// GetWineMovements retourne les déplacements d'une entrée, qu'elle en soit l'origine
+// ou la destination (scission comprise), du plus récent au plus ancien
+func (s *Store) GetWineMovements(ctx context.Context, wineID int64) ([]*domain.BottleMovement, error) {
+	if _, err := s.GetWineByID(ctx, wineID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.QueryContext(ctx, `SELECT `+movementColumns+`
+	WHERE m.wine_id = ? OR m.target_wine_id = ?
+	ORDER BY m.moved_at DESC, m.id DESC`, wineID, wineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bottle movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := make([]*domain.BottleMovement, 0)
+	for rows.Next() {
+		m, err := scanMovement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bottle movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
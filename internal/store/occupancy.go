@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Les colonnes current des caves et emplacements sont tenues à jour par des triggers
+// (migrations 0008 et 0012) ; ce fichier vérifie les capacités lors des rangements.
+
+// stock est la part d'un vin ou d'un tabac dans ses contenants
+type stock struct {
+	cellID   sql.NullInt64
+	caveID   sql.NullInt64
+	quantity int
+}
+
+// readStock lit l'emplacement et la quantité d'un élément ; un élément absent ou
+// dans la corbeille n'occupe aucune place
+func readStock(ctx context.Context, db queryRower, table string, id int64) (stock, error) {
+	var st stock
+	err := db.QueryRowContext(ctx, fmt.Sprintf(
+		`SELECT cell_id, cave_id, CASE WHEN deleted_at IS NULL THEN quantity ELSE 0 END FROM %s WHERE id = ?`, table),
+		id).Scan(&st.cellID, &st.caveID, &st.quantity)
+	if err == sql.ErrNoRows {
+		return stock{}, nil
+	}
+	if err != nil {
+		return stock{}, fmt.Errorf("failed to read stock: %w", err)
+	}
+	return st, nil
+}
+
+// samePlacement indique si un identifiant de contenant est inchangé
+func samePlacement(current sql.NullInt64, id *int64) bool {
+	if id == nil {
+		return !current.Valid
+	}
+	return current.Valid && current.Int64 == *id
+}
+
+// checkCapacity refuse (ErrConflict) une écriture qui ajoute des unités à un emplacement
+// ou une cave désormais au-delà de sa capacité. À appeler dans la transaction, après l'écriture.
+// Retirer des unités d'un contenant déjà plein reste possible ; une capacité nulle n'est pas limitée.
+func checkCapacity(ctx context.Context, db queryRower, before, after stock) error {
+	containers := []struct {
+		kind, table   string
+		before, after sql.NullInt64
+	}{
+		{"cell", "cells", before.cellID, after.cellID},
+		{"cave", "caves", before.caveID, after.caveID},
+	}
+
+	for _, c := range containers {
+		if !c.after.Valid {
+			continue
+		}
+		added := after.quantity
+		if c.before == c.after {
+			added -= before.quantity
+		}
+		if added <= 0 {
+			continue
+		}
+
+		var capacity, current int
+		err := db.QueryRowContext(ctx,
+			fmt.Sprintf(`SELECT capacity, current FROM %s WHERE id = ?`, c.table), c.after.Int64).Scan(&capacity, &current)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check %s capacity: %w", c.kind, err)
+		}
+		if capacity > 0 && current > capacity {
+			return fmt.Errorf("%w: %s %d is full (%d/%d after adding %d); use overflow=true to exceed its capacity",
+				ErrConflict, c.kind, c.after.Int64, current, capacity, added)
+		}
+	}
+	return nil
+}
+
+// RecomputeOccupancy recalcule les compteurs des vins depuis leurs bouteilles, puis toutes
+// les colonnes current depuis le stock réel.
+// Retourne le nombre de caves et d'emplacements corrigés.
+func (s *Store) RecomputeOccupancy(ctx context.Context) (int64, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	steps := []string{
+		// Le compteur d'une entrée de vin est le nombre de ses bouteilles en stock
+		`UPDATE wines SET quantity = units, version = version + 1 FROM (
+			SELECT wines.id AS wine, (SELECT COUNT(*) FROM bottle_units WHERE wine_id = wines.id AND state = 'in_stock') AS units FROM wines
+		) WHERE wines.id = wine AND quantity <> units`,
+		`UPDATE tobaccos SET cave_id = (SELECT cave_id FROM cells WHERE cells.id = tobaccos.cell_id)
+		WHERE cave_id IS NULL AND cell_id IS NOT NULL`,
+		`UPDATE cells SET current = occupancy FROM (
+			SELECT cells.id AS cell, (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cell_id = cells.id) AS occupancy FROM cells
+		) WHERE cells.id = cell AND current <> occupancy`,
+		`UPDATE caves SET current = occupancy FROM (
+			SELECT caves.id AS cave, (SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE cave_id = caves.id) AS occupancy FROM caves
+		) WHERE caves.id = cave AND current <> occupancy`,
+	}
+
+	var fixed int64
+	for i, step := range steps {
+		result, err := tx.ExecContext(ctx, step)
+		if err != nil {
+			return 0, fmt.Errorf("failed to recompute occupancy: %w", err)
+		}
+		if i > 1 {
+			n, _ := result.RowsAffected()
+			fixed += n
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return fixed, nil
+}
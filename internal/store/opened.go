@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// OpenUnit ouvre une bouteille en stock (elle sort du stock, pleine) avec sa méthode de
+// conservation. Sur une bouteille déjà ouverte, seules la conservation et la date changent.
+// Sans openedAt, la bouteille est ouverte maintenant.
+func (s *Store) OpenUnit(ctx context.Context, id int64, preservation string, openedAt *time.Time) (*domain.BottleUnit, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	unit, err := getUnit(ctx, tx, `u.id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if unit.State != domain.UnitStateInStock && unit.State != domain.UnitStateOpened {
+		return nil, fmt.Errorf("%w: bottle unit %d is already %s", ErrConflict, unit.ID, unit.State)
+	}
+
+	now := time.Now()
+	if openedAt == nil && unit.OpenedAt == nil {
+		openedAt = &now
+	}
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE bottle_units SET state = 'opened', preservation = ?, opened_at = COALESCE(?, opened_at),
+		state_changed_at = CASE WHEN state <> 'opened' THEN ? ELSE state_changed_at END
+	WHERE id = ?`, preservation, openedAt, now, unit.ID); err != nil {
+		return nil, fmt.Errorf("failed to open bottle unit: %w", err)
+	}
+	if unit.State == domain.UnitStateInStock {
+		if err := refreshWineQuantity(ctx, tx, unit.WineID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetUnit(ctx, id)
+}
+
+// GetOpenedBottles liste les bouteilles ouvertes avec leur reste et la date limite
+// de dégustation, les plus urgentes d'abord
+func (s *Store) GetOpenedBottles(ctx context.Context) ([]*domain.OpenedBottle, error) {
+	rows, err := s.Db.QueryContext(ctx, `SELECT `+unitColumns+`
+	JOIN wines w ON w.id = u.wine_id
+	WHERE u.state = 'opened' AND w.deleted_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query opened bottles: %w", err)
+	}
+	defer rows.Close()
+
+	units := make([]*domain.BottleUnit, 0)
+	for rows.Next() {
+		u, err := scanUnit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bottle unit: %w", err)
+		}
+		units = append(units, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bottle units: %w", err)
+	}
+	rows.Close()
+
+	// Le type, le degré et le volume du vin donnent la durée de conservation et le reste
+	wines := make(map[int64]*domain.Wine)
+	now := time.Now()
+	opened := make([]*domain.OpenedBottle, 0, len(units))
+	for _, u := range units {
+		wine, ok := wines[u.WineID]
+		if !ok {
+			if wine, err = s.GetWineByID(ctx, u.WineID); err != nil {
+				return nil, err
+			}
+			wines[u.WineID] = wine
+		}
+
+		b := &domain.OpenedBottle{
+			BottleUnit:       *u,
+			WineName:         wine.Name,
+			BottleType:       wine.BottleType,
+			VolumeML:         wine.VolumeML,
+			RemainingPercent: 100,
+			ShelfLifeDays:    domain.ShelfLifeDays(wine.BottleType, wine.AlcoholLevel, u.Preservation),
+		}
+		if u.RemainingML != nil && wine.VolumeML > 0 {
+			b.RemainingPercent = roundTo(float64(*u.RemainingML)*100/float64(wine.VolumeML), 1)
+		}
+		if b.ShelfLifeDays > 0 && u.OpenedAt != nil {
+			drinkBy := u.OpenedAt.AddDate(0, 0, b.ShelfLifeDays)
+			b.DrinkBy = &drinkBy
+			b.Overdue = drinkBy.Before(now)
+		}
+		opened = append(opened, b)
+	}
+
+	sortOpened(opened)
+	return opened, nil
+}
+
+// sortOpened trie par date limite croissante, les bouteilles sans date limite en dernier
+func sortOpened(opened []*domain.OpenedBottle) {
+	slices.SortStableFunc(opened, func(a, b *domain.OpenedBottle) int {
+		switch {
+		case a.DrinkBy == nil && b.DrinkBy == nil:
+			return 0
+		case a.DrinkBy == nil:
+			return 1
+		case b.DrinkBy == nil:
+			return -1
+		}
+		return a.DrinkBy.Compare(*b.DrinkBy)
+	})
+}
+
+// generateOpenedAlerts crée une alerte opened_too_long par vin dont une bouteille ouverte
+// a dépassé sa durée de conservation. Les vins terminés sont concernés : leur dernière
+// bouteille peut être ouverte.
+func (s *Store) generateOpenedAlerts(ctx context.Context) error {
+	opened, err := s.GetOpenedBottles(ctx)
+	if err != nil {
+		return err
+	}
+
+	alerted := make(map[int64]bool)
+	for _, b := range opened {
+		if !b.Overdue || alerted[b.WineID] {
+			continue
+		}
+		alerted[b.WineID] = true
+
+		existing, err := s.GetAlertsByWineID(ctx, b.WineID)
+		if err != nil {
+			return fmt.Errorf("failed to get alerts for wine %d: %w", b.WineID, err)
+		}
+		if slices.ContainsFunc(existing, func(a *domain.Alert) bool {
+			return a.Status == "active" && a.AlertType == "opened_too_long"
+		}) {
+			continue
+		}
+
+		alert := &domain.Alert{
+			WineID:    b.WineID,
+			AlertType: "opened_too_long",
+			Status:    "active",
+			CreatedAt: time.Now(),
+		}
+		if _, err := s.CreateAlert(ctx, alert); err != nil {
+			return fmt.Errorf("failed to create opened_too_long alert for wine %d: %w", b.WineID, err)
+		}
+	}
+	return nil
+}
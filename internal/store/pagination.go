@@ -0,0 +1,248 @@
+package store
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ListOptions décrit une requête de liste : pagination par curseur, tri et filtres
+type ListOptions struct {
+	Limit   int    // 0 = pas de limite
+	Cursor  string // curseur opaque retourné par la page précédente
+	Sort    string // colonne de tri, préfixée par "-" pour un tri décroissant
+	Filters ListFilters
+}
+
+// ListFilters regroupe les filtres par plage communs aux listes
+type ListFilters struct {
+	VintageMin *int
+	VintageMax *int
+	PriceMin   *float64
+	PriceMax   *float64
+	RatingMin  *float64
+	CaveID     *int64
+	CellID     *int64
+
+	// IncludeFinished inclut les vins terminés (masqués par défaut)
+	IncludeFinished bool
+}
+
+// PageInfo décrit la position dans une liste paginée
+type PageInfo struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// cursor est le contenu (encodé en base64) d'un curseur de pagination :
+// la valeur de tri et l'ID du dernier élément de la page.
+// Value garde le type SQLite de la valeur (nombre JSON pour INTEGER et REAL, chaîne sinon) :
+// un REAL relu sous forme de texte ne serait plus égal à la valeur stockée.
+type cursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    int64       `json:"id"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, NewValidationError("cursor", "invalid cursor")
+	}
+	var c cursor
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil {
+		return nil, NewValidationError("cursor", "invalid cursor")
+	}
+	switch v := c.Value.(type) {
+	case nil, string:
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			c.Value = i
+		} else if f, err := v.Float64(); err == nil {
+			c.Value = f
+		} else {
+			return nil, NewValidationError("cursor", "invalid cursor")
+		}
+	default:
+		return nil, NewValidationError("cursor", "invalid cursor")
+	}
+	return &c, nil
+}
+
+// listSpec décrit, pour une table, les colonnes triables et filtrables
+type listSpec struct {
+	idColumn    string            // colonne d'identifiant (clé de départage)
+	sortable    map[string]string // nom public → colonne SQL
+	defaultSort string            // tri par défaut, ex: "-created_at"
+	filters     map[string]string // "vintage", "price", "rating", "cave_id", "cell_id" → colonne SQL
+}
+
+// listQuery est une requête de liste construite à partir de ListOptions
+type listQuery struct {
+	where    []string
+	args     []interface{}
+	sortKey  string // tri public retenu, ex: "-vintage"
+	sortCol  string
+	idColumn string
+	desc     bool
+	limit    int
+}
+
+// sortNames retourne la liste triée des colonnes triables (pour les messages d'erreur)
+func (spec listSpec) sortNames() []string {
+	names := make([]string, 0, len(spec.sortable))
+	for name := range spec.sortable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// build valide les options et prépare filtres, tri et condition de curseur
+func (spec listSpec) build(opts ListOptions) (*listQuery, error) {
+	q := &listQuery{limit: opts.Limit, idColumn: spec.idColumn}
+	verr := &ValidationError{}
+
+	// Tri
+	sortKey := opts.Sort
+	if sortKey == "" {
+		sortKey = spec.defaultSort
+	}
+	name := strings.TrimPrefix(sortKey, "-")
+	col, ok := spec.sortable[name]
+	if !ok {
+		verr.Add("sort", fmt.Sprintf("cannot sort by %q: must be one of %s", name, strings.Join(spec.sortNames(), ", ")))
+	}
+	q.sortKey, q.sortCol, q.desc = sortKey, col, strings.HasPrefix(sortKey, "-")
+
+	// Filtres par plage
+	f := opts.Filters
+	addFilter := func(field, key, op string, value interface{}) {
+		column, ok := spec.filters[key]
+		if !ok {
+			verr.Add(field, fmt.Sprintf("filter %s is not supported here", field))
+			return
+		}
+		q.where = append(q.where, fmt.Sprintf("%s %s ?", column, op))
+		q.args = append(q.args, value)
+	}
+	if f.VintageMin != nil {
+		addFilter("vintage_min", "vintage", ">=", *f.VintageMin)
+	}
+	if f.VintageMax != nil {
+		addFilter("vintage_max", "vintage", "<=", *f.VintageMax)
+	}
+	if f.VintageMin != nil && f.VintageMax != nil && *f.VintageMin > *f.VintageMax {
+		verr.Add("vintage_min", "vintage_min must be lower than vintage_max")
+	}
+	if f.PriceMin != nil {
+		addFilter("price_min", "price", ">=", *f.PriceMin)
+	}
+	if f.PriceMax != nil {
+		addFilter("price_max", "price", "<=", *f.PriceMax)
+	}
+	if f.PriceMin != nil && f.PriceMax != nil && *f.PriceMin > *f.PriceMax {
+		verr.Add("price_min", "price_min must be lower than price_max")
+	}
+	if f.RatingMin != nil {
+		addFilter("rating_min", "rating", ">=", *f.RatingMin)
+	}
+	if f.CaveID != nil {
+		addFilter("cave_id", "cave_id", "=", *f.CaveID)
+	}
+	if f.CellID != nil {
+		addFilter("cell_id", "cell_id", "=", *f.CellID)
+	}
+
+	// Curseur : reprendre après le dernier élément de la page précédente.
+	// Les valeurs NULL sont toujours placées en fin de liste.
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != sortKey {
+			verr.Add("cursor", "cursor does not match the requested sort")
+		}
+		cmp := ">"
+		if q.desc {
+			cmp = "<"
+		}
+		if c.Value == nil {
+			q.where = append(q.where, fmt.Sprintf("(%s IS NULL AND %s %s ?)", col, spec.idColumn, cmp))
+			q.args = append(q.args, c.ID)
+		} else {
+			q.where = append(q.where, fmt.Sprintf(
+				"((%[1]s IS NOT NULL AND (%[1]s %[2]s ? OR (%[1]s = ? AND %[3]s %[2]s ?))) OR %[1]s IS NULL)",
+				col, cmp, spec.idColumn,
+			))
+			q.args = append(q.args, c.Value, c.Value, c.ID)
+		}
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// sortSelect retourne l'expression à ajouter au SELECT pour relire la valeur de tri :
+// les nombres gardent leur type, le reste (dates comprises) est relu en texte
+func (q *listQuery) sortSelect() string {
+	return fmt.Sprintf("CASE WHEN typeof(%[1]s) IN ('integer', 'real') THEN %[1]s ELSE CAST(%[1]s AS TEXT) END", q.sortCol)
+}
+
+// whereClause retourne les conditions combinées (préfixées par AND)
+func (q *listQuery) whereClause() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(q.where, " AND ")
+}
+
+// orderLimit retourne les clauses ORDER BY et LIMIT (une ligne de plus pour détecter la page suivante)
+func (q *listQuery) orderLimit() string {
+	dir := "ASC"
+	if q.desc {
+		dir = "DESC"
+	}
+	clause := fmt.Sprintf(" ORDER BY (%[1]s IS NULL), %[1]s %[2]s, %[3]s %[2]s", q.sortCol, dir, q.idColumn)
+	if q.limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", q.limit+1)
+	}
+	return clause
+}
+
+// pageInfo tronque la page à la limite et calcule le curseur suivant.
+// keys et ids contiennent la valeur de tri et l'ID de chaque ligne lue.
+func (q *listQuery) pageInfo(count int, keys []interface{}, ids []int64) (int, *PageInfo) {
+	info := &PageInfo{}
+	if q.limit <= 0 || count <= q.limit {
+		return count, info
+	}
+	last := q.limit - 1
+	info.HasMore = true
+	info.NextCursor = cursor{Sort: q.sortKey, Value: keys[last], ID: ids[last]}.encode()
+	return q.limit, info
+}
+
+// sortKeyScanner lit, en plus des colonnes de l'entité, la valeur de tri
+// ajoutée en dernière colonne par sortSelect
+type sortKeyScanner struct {
+	row rowScanner
+	key *interface{}
+}
+
+func (s sortKeyScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.key)...)
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := cursor{Sort: "-name", Value: "Château Margaux", ID: 7}
+	decoded, err := decodeCursor(c.encode())
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if *decoded != c {
+		t.Errorf("expected %+v, got %+v", c, *decoded)
+	}
+
+	// Un REAL issu d'un float32 doit revenir à l'identique, un entier rester entier
+	price := float64(float32(12.3))
+	for _, value := range []interface{}{price, int64(2015), nil} {
+		c := cursor{Sort: "price", Value: value, ID: 3}
+		decoded, err := decodeCursor(c.encode())
+		if err != nil {
+			t.Fatalf("decodeCursor: %v", err)
+		}
+		if decoded.Value != value {
+			t.Errorf("expected %#v, got %#v", value, decoded.Value)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	if _, err := decodeCursor("%%%"); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a validation error for invalid base64, got %v", err)
+	}
+	if _, err := decodeCursor("bm90IGpzb24"); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a validation error for invalid JSON, got %v", err)
+	}
+}
+
+func TestListWinesPagesThroughTiedRealValues(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	price := float32(12.3)
+	for i := 0; i < 4; i++ {
+		wine := &domain.Wine{Name: "Margaux", BottleType: "red_wine", Region: "Bordeaux", Vintage: 2015, Quantity: 1, Price: &price}
+		if _, err := s.CreateWine(ctx, wine, false); err != nil {
+			t.Fatalf("CreateWine: %v", err)
+		}
+	}
+
+	for _, sortKey := range []string{"price", "-price"} {
+		seen := make(map[int64]bool)
+		opts := ListOptions{Limit: 1, Sort: sortKey}
+		for page := 0; page < 10; page++ {
+			wines, info, err := s.ListWines(ctx, opts)
+			if err != nil {
+				t.Fatalf("%s: ListWines: %v", sortKey, err)
+			}
+			for _, w := range wines {
+				if seen[w.ID] {
+					t.Fatalf("%s: wine %d returned twice", sortKey, w.ID)
+				}
+				seen[w.ID] = true
+			}
+			if !info.HasMore {
+				break
+			}
+			opts.Cursor = info.NextCursor
+		}
+		if len(seen) != 4 {
+			t.Errorf("%s: expected 4 wines across pages, got %d", sortKey, len(seen))
+		}
+	}
+}
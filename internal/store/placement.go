@@ -0,0 +1,548 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// Températures de garde acceptables par type (°C) : une zone dont la plage ne
+// recoupe pas celle du type n'est pas proposée
+var storageTemperatures = map[string][2]float64{
+	domain.BottleTypeRedWine:       {12, 18},
+	domain.BottleTypeWhiteWine:     {8, 14},
+	domain.BottleTypeRoseWine:      {8, 14},
+	domain.BottleTypeSparklingWine: {6, 12},
+	domain.BottleTypeBeer:          {4, 12},
+	domain.BottleTypeSpirit:        {10, 20},
+}
+
+// Horizons du profil de consommation
+const (
+	drinkSoonHorizon = 2 * 365 * 24 * time.Hour // Fin d'apogée proche
+	keeperHorizon    = 3 * 365 * 24 * time.Hour // Apogée lointaine
+)
+
+// placementProfile classe un vin selon sa fenêtre de consommation
+func placementProfile(wine *domain.Wine, now time.Time) string {
+	if bottleType(wine) == domain.BottleTypeBeer {
+		return domain.PlacementDrinkSoon
+	}
+	if wine.MinApogeeDate != nil && wine.MinApogeeDate.After(now.Add(keeperHorizon)) {
+		return domain.PlacementKeeper
+	}
+	if wine.MaxApogeeDate != nil && wine.MaxApogeeDate.Before(now.Add(drinkSoonHorizon)) {
+		return domain.PlacementDrinkSoon
+	}
+	if wine.MinApogeeDate != nil && !wine.MinApogeeDate.After(now) {
+		return domain.PlacementDrinkSoon
+	}
+	return domain.PlacementStandard
+}
+
+// storedWine est un vin rangé dans un emplacement de la cave
+type storedWine struct {
+	id              int64
+	name, producer  string
+	vintage         int
+	bottleType      string
+	cellID          int64
+	sameAsCandidate bool
+}
+
+// sameWine indique si deux entrées désignent le même vin (même nom, producteur et millésime)
+func sameWine(a *domain.Wine, name, producer string, vintage int) bool {
+	return strings.EqualFold(strings.TrimSpace(a.Name), strings.TrimSpace(name)) &&
+		strings.EqualFold(strings.TrimSpace(a.Producer), strings.TrimSpace(producer)) &&
+		a.Vintage == vintage
+}
+
+// placementCandidate est un emplacement évalué par l'optimiseur
+type placementCandidate struct {
+	cell          *domain.Cell
+	zone          *domain.CaveZone
+	free          int // -1 : capacité non limitée
+	accessibility float64
+	sameWine      bool // L'emplacement contient déjà ce vin
+	otherWines    bool // L'emplacement contient d'autres vins
+	taken         bool
+}
+
+// rackKey identifie une clayette (les emplacements libres n'en ont pas)
+func rackKey(cell *domain.Cell) string {
+	if cell.Zone == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%d", cell.Zone, cell.Rack)
+}
+
+// PlanPlacement propose des emplacements de la cave pour quantity bouteilles du vin.
+// Le vin peut ne pas encore exister (ID nul) : seuls son type, sa fenêtre de consommation
+// et son identité (nom, producteur, millésime) sont utilisés.
+// Les emplacements sont classés du meilleur au moins bon ; le plan se remplit dans cet ordre.
+func (s *Store) PlanPlacement(ctx context.Context, caveID int64, wine *domain.Wine, quantity int) (*domain.PlacementPlan, error) {
+	if quantity <= 0 {
+		return nil, NewValidationError("quantity", "quantity must be greater than 0")
+	}
+
+	cave, err := s.GetCaveByID(ctx, caveID)
+	if err != nil {
+		return nil, err
+	}
+	cells, err := s.GetCellsByCave(ctx, caveID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.storedWines(ctx, caveID, wine)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &domain.PlacementPlan{
+		CaveID:     caveID,
+		BottleType: bottleType(wine),
+		Profile:    placementProfile(wine, time.Now()),
+		Quantity:   quantity,
+		Placements: make([]*domain.PlacementSuggestion, 0),
+	}
+	if wine.ID != 0 {
+		plan.WineID = &wine.ID
+	}
+
+	zones := make(map[string]*domain.CaveZone)
+	if cave.Layout != nil {
+		for i := range cave.Layout.Zones {
+			zones[cave.Layout.Zones[i].Name] = &cave.Layout.Zones[i]
+		}
+	}
+
+	// Contenu des emplacements et des clayettes
+	sameWineRacks := make(map[string]bool)
+	typesByRack := make(map[string]map[string]bool)
+	cellWines := make(map[int64][]storedWine)
+	for _, sw := range stored {
+		cellWines[sw.cellID] = append(cellWines[sw.cellID], sw)
+	}
+
+	var candidates []*placementCandidate
+	for _, cell := range cells {
+		c := &placementCandidate{cell: cell, zone: zones[cell.Zone], accessibility: 0.5}
+		for _, sw := range cellWines[cell.ID] {
+			if sw.sameAsCandidate {
+				c.sameWine = true
+			} else {
+				c.otherWines = true
+			}
+		}
+		if key := rackKey(cell); key != "" {
+			if c.sameWine {
+				sameWineRacks[key] = true
+			}
+			for _, sw := range cellWines[cell.ID] {
+				if typesByRack[key] == nil {
+					typesByRack[key] = make(map[string]bool)
+				}
+				typesByRack[key][sw.bottleType] = true
+			}
+		}
+
+		if !temperatureFits(c.zone, plan.BottleType) {
+			continue
+		}
+
+		if cell.Capacity <= 0 {
+			c.free = -1
+		} else {
+			c.free = cell.Capacity - cell.Current
+			// Les bouteilles du vin qui quittent leur emplacement y libèrent de la place
+			if wine.ID != 0 && wine.CellID != nil && *wine.CellID == cell.ID {
+				c.free += min(quantity, wine.Quantity)
+			}
+			if c.free <= 0 {
+				continue
+			}
+		}
+
+		// Accessibilité : la première clayette (haut) est la plus accessible, la dernière la plus profonde
+		if c.zone != nil && cell.Rack > 0 {
+			levels := c.zone.Racks * c.zone.Rows
+			if levels > 1 {
+				level := (cell.Rack-1)*c.zone.Rows + (cell.Row - 1)
+				c.accessibility = 1 - float64(level)/float64(levels-1)
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	// Remplissage glouton : à chaque étape, le meilleur emplacement restant,
+	// en favorisant les voisins de ceux déjà retenus pour garder le vin groupé
+	chosenRacks := make(map[string]bool)
+	remaining := quantity
+	for remaining > 0 {
+		var best *placementCandidate
+		var bestScore float64
+		var bestReasons []string
+		for _, c := range candidates {
+			if c.taken {
+				continue
+			}
+			score, reasons := scorePlacement(c, plan, remaining, sameWineRacks, chosenRacks, typesByRack)
+			if best == nil || score > bestScore {
+				best, bestScore, bestReasons = c, score, reasons
+			}
+		}
+		if best == nil {
+			break
+		}
+
+		best.taken = true
+		n := remaining
+		if best.free >= 0 && best.free < n {
+			n = best.free
+		}
+		remaining -= n
+		if key := rackKey(best.cell); key != "" {
+			chosenRacks[key] = true
+		}
+		plan.Placements = append(plan.Placements, &domain.PlacementSuggestion{
+			Rank:     len(plan.Placements) + 1,
+			CellID:   best.cell.ID,
+			Location: best.cell.Location,
+			Zone:     best.cell.Zone,
+			Quantity: n,
+			Free:     best.free,
+			Score:    math.Round(bestScore*10) / 10,
+			Reasons:  bestReasons,
+		})
+	}
+
+	plan.Placed = quantity - remaining
+	plan.Unplaced = remaining
+	return plan, nil
+}
+
+// temperatureFits indique si la plage de température de la zone convient au type.
+// Une zone sans température déclarée, ou un type sans préférence, convient toujours.
+func temperatureFits(zone *domain.CaveZone, bottleType string) bool {
+	pref, ok := storageTemperatures[bottleType]
+	if !ok || zone == nil {
+		return true
+	}
+	if zone.MinTemperature != nil && *zone.MinTemperature > pref[1] {
+		return false
+	}
+	if zone.MaxTemperature != nil && *zone.MaxTemperature < pref[0] {
+		return false
+	}
+	return true
+}
+
+// scorePlacement note un emplacement pour le vin à ranger et explique la note
+func scorePlacement(c *placementCandidate, plan *domain.PlacementPlan, remaining int,
+	sameWineRacks, chosenRacks map[string]bool, typesByRack map[string]map[string]bool) (float64, []string) {
+	score := 0.0
+	reasons := make([]string, 0)
+	key := rackKey(c.cell)
+
+	// Garder le même vin groupé
+	switch {
+	case c.sameWine:
+		score += 40
+		reasons = append(reasons, "already holds this wine")
+	case key != "" && chosenRacks[key]:
+		score += 25
+		reasons = append(reasons, "same rack as the rest of this plan")
+	case key != "" && sameWineRacks[key]:
+		score += 20
+		reasons = append(reasons, "next to bottles of the same wine")
+	}
+
+	// Fenêtre de consommation : à portée de main ou au fond
+	switch plan.Profile {
+	case domain.PlacementDrinkSoon:
+		score += 30 * c.accessibility
+		if c.accessibility >= 0.5 {
+			reasons = append(reasons, "accessible slot for a wine to drink soon")
+		}
+	case domain.PlacementKeeper:
+		score += 30 * (1 - c.accessibility)
+		if c.accessibility <= 0.5 {
+			reasons = append(reasons, "deep slot for a long keeper")
+		}
+	default:
+		score += 10 * (1 - math.Abs(c.accessibility-0.5)*2)
+	}
+
+	// Zone de température adaptée
+	if c.zone != nil && (c.zone.MinTemperature != nil || c.zone.MaxTemperature != nil) {
+		if _, ok := storageTemperatures[plan.BottleType]; ok {
+			score += 10
+			reasons = append(reasons, fmt.Sprintf("zone %s suits %s", c.zone.Name, plan.BottleType))
+		}
+	}
+
+	// Rangement avec des bouteilles du même type
+	if key != "" && typesByRack[key][plan.BottleType] {
+		score += 5
+		reasons = append(reasons, "rack already holds "+plan.BottleType)
+	}
+
+	// Préférer peu d'emplacements : tout ranger au même endroit si possible
+	if c.free < 0 || c.free >= remaining {
+		score += 10
+		reasons = append(reasons, "room for all remaining bottles")
+	} else {
+		score += 10 * float64(c.free) / float64(remaining)
+	}
+
+	if c.otherWines && !c.sameWine {
+		score -= 5
+		reasons = append(reasons, "shared with other wines")
+	}
+	return score, reasons
+}
+
+// storedWines liste les vins rangés dans un emplacement de la cave, en signalant ceux
+// identiques au vin à placer
+func (s *Store) storedWines(ctx context.Context, caveID int64, wine *domain.Wine) ([]storedWine, error) {
+	rows, err := s.Db.QueryContext(ctx, `
+	SELECT DISTINCT w.id, w.name, w.producer, w.vintage, w.type, u.cell_id FROM bottle_units u
+	JOIN wines w ON w.id = u.wine_id
+	WHERE u.cave_id = ? AND u.cell_id IS NOT NULL AND u.state = 'in_stock' AND w.deleted_at IS NULL`, caveID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stored wines: %w", err)
+	}
+	defer rows.Close()
+
+	var stored []storedWine
+	for rows.Next() {
+		var sw storedWine
+		var producer, bottleType sql.NullString
+		if err := rows.Scan(&sw.id, &sw.name, &producer, &sw.vintage, &bottleType, &sw.cellID); err != nil {
+			return nil, fmt.Errorf("failed to scan stored wine: %w", err)
+		}
+		sw.producer = producer.String
+		sw.bottleType = bottleType.String
+		sw.sameAsCandidate = (wine.ID != 0 && sw.id == wine.ID) || sameWine(wine, sw.name, sw.producer, sw.vintage)
+		stored = append(stored, sw)
+	}
+	return stored, rows.Err()
+}
+
+// checkPlacements valide les emplacements d'un plan : quantités positives, emplacements
+// distincts et appartenant à la cave. Retourne le nombre total de bouteilles.
+func checkPlacements(ctx context.Context, tx *sql.Tx, caveID int64, placements []domain.Placement) (int, error) {
+	verr := &ValidationError{}
+	if len(placements) == 0 {
+		verr.Add("placements", "at least one placement is required")
+		return 0, verr.Err()
+	}
+
+	total := 0
+	seen := make(map[int64]bool)
+	for i, p := range placements {
+		field := fmt.Sprintf("placements[%d]", i)
+		if p.Quantity <= 0 {
+			verr.Add(field+".quantity", "quantity must be greater than 0")
+		}
+		total += p.Quantity
+		if seen[p.CellID] {
+			verr.Add(field+".cell_id", fmt.Sprintf("cell %d appears more than once", p.CellID))
+			continue
+		}
+		seen[p.CellID] = true
+
+		var found int
+		err := tx.QueryRowContext(ctx,
+			`SELECT 1 FROM cells WHERE id = ? AND cave_id = ? AND deleted_at IS NULL`, p.CellID, caveID).Scan(&found)
+		if err == sql.ErrNoRows {
+			verr.Add(field+".cell_id", fmt.Sprintf("cell %d does not belong to cave %d", p.CellID, caveID))
+		} else if err != nil {
+			return 0, fmt.Errorf("failed to check cell: %w", err)
+		}
+	}
+	if total > domain.MaxBottleQuantity {
+		verr.Add("placements", fmt.Sprintf("plan places %d bottles, more than %d", total, domain.MaxBottleQuantity))
+	}
+	return total, verr.Err()
+}
+
+// insertPlacedWine crée une entrée du vin dans un emplacement et vérifie sa capacité
+func insertPlacedWine(ctx context.Context, tx *sql.Tx, wine domain.Wine, caveID int64, p domain.Placement, overflow bool) (int64, error) {
+	wine.ID = 0
+	wine.Quantity = p.Quantity
+	wine.CellID = &p.CellID
+	wine.CaveID = &caveID
+	id, err := insertWine(ctx, tx, &wine)
+	if err != nil {
+		return 0, err
+	}
+	if !overflow {
+		placed, err := readStock(ctx, tx, "wines", id)
+		if err != nil {
+			return 0, err
+		}
+		if err := checkCapacity(ctx, tx, stock{}, placed); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// placementNote annote les déplacements faits par un plan de rangement
+const placementNote = "placement plan"
+
+// PlaceWine applique un plan de rangement à un vin existant en une seule transaction.
+// Les bouteilles non mentionnées restent à leur emplacement ; celles réparties dans
+// d'autres emplacements deviennent des entrées distinctes du même vin ; chaque
+// déplacement est tracé dans bottle_movements.
+// Retourne les entrées du vin concernées, l'originale en premier.
+func (s *Store) PlaceWine(ctx context.Context, caveID, wineID int64, placements []domain.Placement, overflow bool) ([]*domain.Wine, error) {
+	if _, err := s.GetCaveByID(ctx, caveID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wine, err := scanWine(tx.QueryRowContext(ctx,
+		`SELECT `+wineColumns+` FROM wines WHERE id = ? AND deleted_at IS NULL`, wineID))
+	if err == sql.ErrNoRows {
+		return nil, notFound("wine", wineID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wine: %w", err)
+	}
+
+	total, err := checkPlacements(ctx, tx, caveID, placements)
+	if err != nil {
+		return nil, err
+	}
+	if total > wine.Quantity {
+		return nil, NewValidationError("placements",
+			fmt.Sprintf("plan places %d bottles but wine %d only has %d", total, wineID, wine.Quantity))
+	}
+
+	// Les bouteilles déjà à leur place restent sur l'entrée d'origine
+	stay := wine.Quantity - total
+	moves := make([]domain.Placement, 0, len(placements))
+	for _, p := range placements {
+		if wine.CellID != nil && *wine.CellID == p.CellID {
+			stay += p.Quantity
+			continue
+		}
+		moves = append(moves, p)
+	}
+
+	before, err := readStock(ctx, tx, "wines", wineID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Si toutes les bouteilles bougent, l'entrée d'origine suit le premier emplacement ;
+	// les autres emplacements reçoivent des bouteilles détachées en nouvelles entrées
+	var first *domain.Placement
+	if stay == 0 {
+		first = &moves[0]
+		moves = moves[1:]
+	}
+	ids := []int64{wineID}
+	for _, p := range moves {
+		to := stock{
+			cellID:   sql.NullInt64{Int64: p.CellID, Valid: true},
+			caveID:   sql.NullInt64{Int64: caveID, Valid: true},
+			quantity: p.Quantity,
+		}
+		id, err := splitWine(ctx, tx, wine, p.Quantity, to)
+		if err != nil {
+			return nil, err
+		}
+		if !overflow {
+			if err := checkCapacity(ctx, tx, stock{}, to); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := recordMovement(ctx, tx, wineID, id, nil, p.Quantity, before, to, placementNote); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if first != nil {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE wines SET cell_id = ?, cave_id = ?, version = version + 1 WHERE id = ?`,
+			first.CellID, caveID, wineID); err != nil {
+			return nil, fmt.Errorf("failed to update wine: %w", err)
+		}
+		after, err := readStock(ctx, tx, "wines", wineID)
+		if err != nil {
+			return nil, err
+		}
+		if !overflow {
+			if err := checkCapacity(ctx, tx, stock{cellID: before.cellID, caveID: before.caveID, quantity: after.quantity}, after); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := recordMovement(ctx, tx, wineID, wineID, nil, after.quantity, before, after, placementNote); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.winesByID(ctx, ids)
+}
+
+// CreatePlacedWine crée un vin réparti selon un plan de rangement : une entrée par emplacement.
+// La quantité du vin est ignorée au profit de celles du plan.
+func (s *Store) CreatePlacedWine(ctx context.Context, caveID int64, wine *domain.Wine, placements []domain.Placement, overflow bool) ([]*domain.Wine, error) {
+	if _, err := s.GetCaveByID(ctx, caveID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := checkPlacements(ctx, tx, caveID, placements); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(placements))
+	for _, p := range placements {
+		id, err := insertPlacedWine(ctx, tx, *wine, caveID, p, overflow)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.winesByID(ctx, ids)
+}
+
+// winesByID relit des vins dans l'ordre donné
+func (s *Store) winesByID(ctx context.Context, ids []int64) ([]*domain.Wine, error) {
+	wines := make([]*domain.Wine, 0, len(ids))
+	for _, id := range ids {
+		wine, err := s.GetWineByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		wines = append(wines, wine)
+	}
+	return wines, nil
+}
@@ -0,0 +1,600 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// Les producteurs sont rapprochés par leur nom normalisé (domain.NormalizeProducerName) :
+// un vin saisi avec un nom ou un alias connu est rattaché au producteur existant,
+// sinon un producteur est créé. Les doublons restants se fusionnent (MergeProducers).
+
+// minProducerScore est la similarité minimale d'une suggestion de producteur
+const minProducerScore = 0.75
+
+// producerColumns lit un producteur avec son nombre de vins (alias p)
+const producerColumns = `p.id, p.name, p.aliases, p.country, p.region, p.website, p.notes,
+	(SELECT COUNT(*) FROM wines WHERE producer_id = p.id AND deleted_at IS NULL), p.created_at
+	FROM producers p`
+
+// scanProducer lit une ligne sélectionnée avec producerColumns
+func scanProducer(row rowScanner) (*domain.Producer, error) {
+	p := &domain.Producer{}
+	var aliases string
+	if err := row.Scan(&p.ID, &p.Name, &aliases, &p.Country, &p.Region, &p.Website, &p.Notes,
+		&p.WineCount, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(aliases), &p.Aliases); err != nil || p.Aliases == nil {
+		p.Aliases = []string{}
+	}
+	return p, nil
+}
+
+// encodeAliases sérialise les alias en retirant les doublons et ceux égaux au nom.
+// Seule la casse est ignorée : "Ch. Margaux" reste un alias de "Château Margaux",
+// chaque graphie rencontrée restant ainsi tracée.
+func encodeAliases(name string, aliases []string) string {
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(name)): true}
+	kept := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		key := strings.ToLower(alias)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, alias)
+	}
+	data, _ := json.Marshal(kept)
+	return string(data)
+}
+
+// queryProducers exécute une requête de producteurs (colonnes producerColumns)
+func queryProducers(ctx context.Context, db queryer, query string, args ...interface{}) ([]*domain.Producer, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query producers: %w", err)
+	}
+	defer rows.Close()
+
+	producers := make([]*domain.Producer, 0)
+	for rows.Next() {
+		p, err := scanProducer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan producer: %w", err)
+		}
+		producers = append(producers, p)
+	}
+	return producers, rows.Err()
+}
+
+// queryer est implémenté par *sql.DB et *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// GetProducers retourne les producteurs par ordre alphabétique
+func (s *Store) GetProducers(ctx context.Context) ([]*domain.Producer, error) {
+	return queryProducers(ctx, s.Db, `SELECT `+producerColumns+` ORDER BY p.normalized_name, p.id`)
+}
+
+// GetProducer retourne un producteur par son ID
+func (s *Store) GetProducer(ctx context.Context, id int64) (*domain.Producer, error) {
+	return getProducer(ctx, s.Db, id)
+}
+
+// getProducer lit un producteur dans la base ou la transaction
+func getProducer(ctx context.Context, db queryRower, id int64) (*domain.Producer, error) {
+	p, err := scanProducer(db.QueryRowContext(ctx, `SELECT `+producerColumns+` WHERE p.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, notFound("producer", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query producer: %w", err)
+	}
+	return p, nil
+}
+
+// CreateProducer crée un producteur ; un nom déjà connu (une fois normalisé) est refusé (ErrConflict)
+func (s *Store) CreateProducer(ctx context.Context, p *domain.Producer) (int64, error) {
+	result, err := s.Db.ExecContext(ctx, `
+	INSERT INTO producers (name, normalized_name, aliases, country, region, website, notes, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.Name, domain.NormalizeProducerName(p.Name), encodeAliases(p.Name, p.Aliases),
+		p.Country, p.Region, p.Website, p.Notes, time.Now())
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%w: producer %q already exists", ErrConflict, p.Name)
+		}
+		return 0, fmt.Errorf("failed to create producer: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateProducer met à jour un producteur ; un nouveau nom est reporté sur ses vins
+func (s *Store) UpdateProducer(ctx context.Context, p *domain.Producer) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE producers SET name = ?, normalized_name = ?, aliases = ?, country = ?, region = ?, website = ?, notes = ?
+	WHERE id = ?`,
+		p.Name, domain.NormalizeProducerName(p.Name), encodeAliases(p.Name, p.Aliases),
+		p.Country, p.Region, p.Website, p.Notes, p.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: producer %q already exists", ErrConflict, p.Name)
+		}
+		return fmt.Errorf("failed to update producer: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return notFound("producer", p.ID)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE wines SET producer = ?, version = version + 1 WHERE producer_id = ? AND producer <> ?`,
+		p.Name, p.ID, p.Name); err != nil {
+		return fmt.Errorf("failed to rename producer wines: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteProducer supprime un producteur sans vin rattaché (ErrConflict sinon, corbeille comprise)
+func (s *Store) DeleteProducer(ctx context.Context, id int64) error {
+	var wines int
+	if err := s.Db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM wines WHERE producer_id = ?`, id).Scan(&wines); err != nil {
+		return fmt.Errorf("failed to count producer wines: %w", err)
+	}
+	if wines > 0 {
+		return fmt.Errorf("%w: producer %d has %d wine(s); merge it into another producer instead", ErrConflict, id, wines)
+	}
+
+	result, err := s.Db.ExecContext(ctx, `DELETE FROM producers WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete producer: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return notFound("producer", id)
+	}
+	return nil
+}
+
+// MatchProducers retourne les producteurs dont le nom ou un alias ressemble à name,
+// du plus proche au moins proche (au plus limit)
+func (s *Store) MatchProducers(ctx context.Context, name string, limit int) ([]*domain.ProducerMatch, error) {
+	producers, err := s.GetProducers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matchProducers(producers, name, limit), nil
+}
+
+// matchProducers classe les producteurs par similarité avec name
+func matchProducers(producers []*domain.Producer, name string, limit int) []*domain.ProducerMatch {
+	key := domain.NormalizeProducerName(name)
+	matches := make([]*domain.ProducerMatch, 0)
+	if key == "" {
+		return matches
+	}
+	for _, p := range producers {
+		best := &domain.ProducerMatch{Producer: p}
+		for _, candidate := range append([]string{p.Name}, p.Aliases...) {
+			if score := producerSimilarity(key, domain.NormalizeProducerName(candidate)); score > best.Score {
+				best.Score = score
+				best.MatchedOn = candidate
+			}
+		}
+		if best.Score >= minProducerScore {
+			matches = append(matches, best)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// genericProducerWords ne suffisent pas à identifier un producteur
+var genericProducerWords = map[string]bool{
+	"chateau": true, "domaine": true, "clos": true, "maison": true, "cave": true, "caves": true,
+	"de": true, "du": true, "des": true, "la": true, "le": true, "les": true, "et": true,
+	"saint": true, "sainte": true, "vignobles": true, "famille": true, "mas": true, "weingut": true,
+}
+
+// producerSimilarity compare deux noms normalisés (0 à 1). La distance d'édition est
+// calculée sur les noms et sur leurs mots triés ; un nom dont les mots distinctifs
+// sont tous contenus dans l'autre ("Margaux" / "Chateau Margaux") est proche.
+func producerSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	score := editSimilarity(a, b)
+
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	sortedA, sortedB := append([]string(nil), wordsA...), append([]string(nil), wordsB...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	if s := editSimilarity(strings.Join(sortedA, " "), strings.Join(sortedB, " ")); s > score {
+		score = s
+	}
+
+	if containsDistinctiveWords(wordsA, wordsB) || containsDistinctiveWords(wordsB, wordsA) {
+		score = max(score, 0.85)
+	}
+	return min(score, 0.99)
+}
+
+// containsDistinctiveWords indique si les mots distinctifs de short figurent tous dans long
+func containsDistinctiveWords(short, long []string) bool {
+	present := make(map[string]bool, len(long))
+	for _, w := range long {
+		present[w] = true
+	}
+	distinctive := 0
+	for _, w := range short {
+		if genericProducerWords[w] {
+			continue
+		}
+		if !present[w] {
+			return false
+		}
+		distinctive++
+	}
+	return distinctive > 0
+}
+
+// editSimilarity vaut 1 - distance de Levenshtein / longueur du plus long nom
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// findProducer retourne l'ID du producteur dont le nom ou un alias a la clé normalisée key
+func findProducer(ctx context.Context, tx *sql.Tx, key string) (int64, string, bool, error) {
+	producers, err := queryProducers(ctx, tx, `SELECT `+producerColumns)
+	if err != nil {
+		return 0, "", false, err
+	}
+	for _, p := range producers {
+		if domain.NormalizeProducerName(p.Name) == key {
+			return p.ID, p.Name, true, nil
+		}
+	}
+	for _, p := range producers {
+		for _, alias := range p.Aliases {
+			if domain.NormalizeProducerName(alias) == key {
+				return p.ID, p.Name, true, nil
+			}
+		}
+	}
+	return 0, "", false, nil
+}
+
+// resolveProducer rattache un vin à son producteur : producer_id fourni, ou nom (ou alias)
+// reconnu, ou producteur créé pour un nom inconnu. Le nom du vin devient celui du producteur.
+func resolveProducer(ctx context.Context, tx *sql.Tx, wine *domain.Wine) error {
+	if wine.ProducerID != nil {
+		var name string
+		err := tx.QueryRowContext(ctx, `SELECT name FROM producers WHERE id = ?`, *wine.ProducerID).Scan(&name)
+		if err == sql.ErrNoRows {
+			return NewValidationError("producer_id", fmt.Sprintf("producer %d does not exist", *wine.ProducerID))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query producer: %w", err)
+		}
+		wine.Producer = name
+		return nil
+	}
+
+	wine.Producer = strings.TrimSpace(wine.Producer)
+	key := domain.NormalizeProducerName(wine.Producer)
+	if key == "" {
+		return nil
+	}
+	id, name, found, err := findProducer(ctx, tx, key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO producers (name, normalized_name, created_at) VALUES (?, ?, ?)`, wine.Producer, key, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to create producer: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		name = wine.Producer
+	}
+	wine.ProducerID = &id
+	wine.Producer = name
+	return nil
+}
+
+// linkProducers rattache les vins sans producteur à un producteur, créé au besoin.
+// Les graphies d'un même producteur sont regroupées : la plus fréquente devient
+// le nom, toutes les autres sont enregistrées comme alias. Les vins gardent le texte
+// saisi, ce qui permet de revenir en arrière.
+func linkProducers(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+	SELECT TRIM(producer), COUNT(*) FROM wines
+	WHERE producer_id IS NULL AND TRIM(COALESCE(producer, '')) <> ''
+	GROUP BY TRIM(producer) ORDER BY COUNT(*) DESC, TRIM(producer)`)
+	if err != nil {
+		return fmt.Errorf("failed to query wine producers: %w", err)
+	}
+	type spelling struct {
+		name  string
+		count int
+	}
+	groups := make(map[string][]spelling)
+	var keys []string
+	for rows.Next() {
+		var sp spelling
+		if err := rows.Scan(&sp.name, &sp.count); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan wine producer: %w", err)
+		}
+		key := domain.NormalizeProducerName(sp.name)
+		if key == "" {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], sp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		spellings := groups[key]
+		id, _, found, err := findProducer(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		if found {
+			existing, err := getProducer(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			aliases := existing.Aliases
+			for _, sp := range spellings {
+				aliases = append(aliases, sp.name)
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE producers SET aliases = ? WHERE id = ?`,
+				encodeAliases(existing.Name, aliases), id); err != nil {
+				return fmt.Errorf("failed to update producer aliases: %w", err)
+			}
+		} else {
+			// Les graphies sont triées par fréquence : la première devient le nom
+			name := spellings[0].name
+			aliases := make([]string, 0, len(spellings)-1)
+			for _, sp := range spellings[1:] {
+				aliases = append(aliases, sp.name)
+			}
+			result, err := tx.ExecContext(ctx,
+				`INSERT INTO producers (name, normalized_name, aliases, created_at) VALUES (?, ?, ?, ?)`,
+				name, key, encodeAliases(name, aliases), time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to create producer: %w", err)
+			}
+			if id, err = result.LastInsertId(); err != nil {
+				return fmt.Errorf("failed to get last insert id: %w", err)
+			}
+		}
+		for _, sp := range spellings {
+			if _, err := tx.ExecContext(ctx, `
+			UPDATE wines SET producer_id = ?, version = version + 1
+			WHERE producer_id IS NULL AND TRIM(producer) = ?`, id, sp.name); err != nil {
+				return fmt.Errorf("failed to link wine producer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// MergeProducers fusionne les producteurs sourceIDs dans targetID : leurs vins sont
+// rattachés à la cible, leurs noms et alias deviennent des alias de la cible, et les
+// champs vides de la cible sont complétés. Les sources sont supprimées.
+func (s *Store) MergeProducers(ctx context.Context, targetID int64, sourceIDs []int64) (*domain.Producer, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	target, err := getProducer(ctx, tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	verr := &ValidationError{}
+	sources := make([]*domain.Producer, 0, len(sourceIDs))
+	seen := map[int64]bool{}
+	for i, id := range sourceIDs {
+		field := fmt.Sprintf("source_ids[%d]", i)
+		switch {
+		case id == targetID:
+			verr.Add(field, "a producer cannot be merged into itself")
+		case seen[id]:
+			verr.Add(field, fmt.Sprintf("producer %d appears more than once", id))
+		default:
+			source, err := getProducer(ctx, tx, id)
+			if errors.Is(err, ErrNotFound) {
+				verr.Add(field, fmt.Sprintf("producer %d does not exist", id))
+			} else if err != nil {
+				return nil, err
+			} else {
+				sources = append(sources, source)
+			}
+		}
+		seen[id] = true
+	}
+	if len(sourceIDs) == 0 {
+		verr.Add("source_ids", "at least one producer to merge is required")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	aliases := target.Aliases
+	for _, source := range sources {
+		if _, err := tx.ExecContext(ctx, `
+		UPDATE wines SET producer_id = ?, producer = ?, version = version + 1 WHERE producer_id = ?`,
+			targetID, target.Name, source.ID); err != nil {
+			return nil, fmt.Errorf("failed to move producer wines: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM producers WHERE id = ?`, source.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete merged producer: %w", err)
+		}
+
+		aliases = append(aliases, source.Name)
+		aliases = append(aliases, source.Aliases...)
+		for _, f := range []struct {
+			dst *string
+			src string
+		}{
+			{&target.Country, source.Country}, {&target.Region, source.Region},
+			{&target.Website, source.Website}, {&target.Notes, source.Notes},
+		} {
+			if *f.dst == "" {
+				*f.dst = f.src
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE producers SET aliases = ?, country = ?, region = ?, website = ?, notes = ? WHERE id = ?`,
+		encodeAliases(target.Name, aliases), target.Country, target.Region, target.Website, target.Notes, targetID); err != nil {
+		return nil, fmt.Errorf("failed to update merged producer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetProducer(ctx, targetID)
+}
+
+// GetProducerWines retourne les vins d'un producteur (terminés compris)
+func (s *Store) GetProducerWines(ctx context.Context, id int64) ([]*domain.Wine, error) {
+	if _, err := s.GetProducer(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.queryWines(ctx, `SELECT `+wineColumns+` FROM wines
+	WHERE producer_id = ? AND deleted_at IS NULL ORDER BY name, vintage, id`, id)
+}
+
+// GetProducerConsumption retourne les dégustations des vins d'un producteur, de la plus récente à la plus ancienne
+func (s *Store) GetProducerConsumption(ctx context.Context, id int64) ([]*domain.ConsumptionHistory, error) {
+	if _, err := s.GetProducer(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.QueryContext(ctx, `
+	SELECT h.id, h.wine_id, h.unit_id, h.quantity, h.rating, h.comment, h.reason, h.date, h.created_at
+	FROM consumption_history h JOIN wines w ON w.id = h.wine_id
+	WHERE w.producer_id = ? AND w.deleted_at IS NULL
+	ORDER BY h.date DESC, h.id DESC`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query consumption history: %w", err)
+	}
+	defer rows.Close()
+
+	history := make([]*domain.ConsumptionHistory, 0)
+	for rows.Next() {
+		h := &domain.ConsumptionHistory{}
+		if err := rows.Scan(&h.ID, &h.WineID, &h.UnitID, &h.Quantity, &h.Rating, &h.Comment, &h.Reason, &h.Date, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan history: %w", err)
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+// migrateProducers crée la table des producteurs, relie les vins et regroupe les graphies existantes
+func migrateProducers(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS producers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		normalized_name TEXT NOT NULL UNIQUE,
+		aliases TEXT NOT NULL DEFAULT '[]',
+		country TEXT NOT NULL DEFAULT '',
+		region TEXT NOT NULL DEFAULT '',
+		website TEXT NOT NULL DEFAULT '',
+		notes TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create producers table: %w", err)
+	}
+
+	exists, err := columnExists(ctx, tx, "wines", "producer_id")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE wines ADD COLUMN producer_id INTEGER`); err != nil {
+			return fmt.Errorf("failed to add wines.producer_id: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_wines_producer ON wines(producer_id)`); err != nil {
+		return fmt.Errorf("failed to create producer index: %w", err)
+	}
+
+	return linkProducers(ctx, tx)
+}
+
+// migrateProducersDown retire les producteurs ; les vins gardent leur nom de producteur
+func migrateProducersDown(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	DROP INDEX IF EXISTS idx_wines_producer;
+	ALTER TABLE wines DROP COLUMN producer_id;
+	DROP TABLE IF EXISTS producers;
+	`)
+	return err
+}
@@ -0,0 +1,410 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// merchantColumns liste les colonnes lues par scanMerchant
+const merchantColumns = `id, name, website, email, phone, address, notes, created_at`
+
+// scanMerchant lit une ligne sélectionnée avec merchantColumns
+func scanMerchant(row rowScanner) (*domain.Merchant, error) {
+	m := &domain.Merchant{}
+	if err := row.Scan(&m.ID, &m.Name, &m.Website, &m.Email, &m.Phone, &m.Address, &m.Notes, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GetMerchants retourne les marchands par ordre alphabétique
+func (s *Store) GetMerchants(ctx context.Context) ([]*domain.Merchant, error) {
+	rows, err := s.Db.QueryContext(ctx, `SELECT `+merchantColumns+` FROM merchants ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchants: %w", err)
+	}
+	defer rows.Close()
+
+	merchants := make([]*domain.Merchant, 0)
+	for rows.Next() {
+		m, err := scanMerchant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan merchant: %w", err)
+		}
+		merchants = append(merchants, m)
+	}
+	return merchants, rows.Err()
+}
+
+// GetMerchant retourne un marchand par son ID
+func (s *Store) GetMerchant(ctx context.Context, id int64) (*domain.Merchant, error) {
+	m, err := scanMerchant(s.Db.QueryRowContext(ctx, `SELECT `+merchantColumns+` FROM merchants WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, notFound("merchant", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchant: %w", err)
+	}
+	return m, nil
+}
+
+// CreateMerchant crée un marchand (nom unique, sans tenir compte de la casse)
+func (s *Store) CreateMerchant(ctx context.Context, m *domain.Merchant) (int64, error) {
+	result, err := s.Db.ExecContext(ctx, `
+	INSERT INTO merchants (name, website, email, phone, address, notes) VALUES (?, ?, ?, ?, ?, ?)`,
+		m.Name, m.Website, m.Email, m.Phone, m.Address, m.Notes)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%w: merchant %q already exists", ErrConflict, m.Name)
+		}
+		return 0, fmt.Errorf("failed to create merchant: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateMerchant met à jour un marchand
+func (s *Store) UpdateMerchant(ctx context.Context, m *domain.Merchant) error {
+	result, err := s.Db.ExecContext(ctx, `
+	UPDATE merchants SET name = ?, website = ?, email = ?, phone = ?, address = ?, notes = ? WHERE id = ?`,
+		m.Name, m.Website, m.Email, m.Phone, m.Address, m.Notes, m.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: merchant %q already exists", ErrConflict, m.Name)
+		}
+		return fmt.Errorf("failed to update merchant: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return notFound("merchant", m.ID)
+	}
+	return nil
+}
+
+// DeleteMerchant supprime un marchand sans achat enregistré (ErrConflict sinon)
+func (s *Store) DeleteMerchant(ctx context.Context, id int64) error {
+	var lots int
+	if err := s.Db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM purchase_lots WHERE merchant_id = ?`, id).Scan(&lots); err != nil {
+		return fmt.Errorf("failed to count merchant purchases: %w", err)
+	}
+	if lots > 0 {
+		return fmt.Errorf("%w: merchant %d has %d purchase lot(s)", ErrConflict, id, lots)
+	}
+
+	result, err := s.Db.ExecContext(ctx, `DELETE FROM merchants WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete merchant: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return notFound("merchant", id)
+	}
+	return nil
+}
+
+// lotColumns lit un lot avec le nom de l'article et du marchand (alias l)
+const lotColumns = `l.id, l.item_type, l.item_id,
+	COALESCE(CASE l.item_type
+		WHEN 'wine' THEN (SELECT name FROM wines WHERE id = l.item_id)
+		WHEN 'tobacco' THEN (SELECT name FROM tobaccos WHERE id = l.item_id)
+	END, ''),
+	l.merchant_id, COALESCE(m.name, ''), l.purchase_date, l.quantity, l.unit_price, l.currency,
+	l.invoice_ref, l.notes, l.created_at
+	FROM purchase_lots l
+	LEFT JOIN merchants m ON m.id = l.merchant_id`
+
+// scanLot lit une ligne sélectionnée avec lotColumns
+func scanLot(row rowScanner) (*domain.PurchaseLot, error) {
+	l := &domain.PurchaseLot{}
+	if err := row.Scan(&l.ID, &l.ItemType, &l.ItemID, &l.ItemName, &l.MerchantID, &l.Merchant,
+		&l.PurchaseDate, &l.Quantity, &l.UnitPrice, &l.Currency, &l.InvoiceRef, &l.Notes, &l.CreatedAt); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// queryLots exécute une requête de lots (colonnes lotColumns)
+func (s *Store) queryLots(ctx context.Context, query string, args ...interface{}) ([]*domain.PurchaseLot, error) {
+	rows, err := s.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query purchase lots: %w", err)
+	}
+	defer rows.Close()
+
+	lots := make([]*domain.PurchaseLot, 0)
+	for rows.Next() {
+		l, err := scanLot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan purchase lot: %w", err)
+		}
+		lots = append(lots, l)
+	}
+	return lots, rows.Err()
+}
+
+// itemInStock retourne la quantité en stock d'un vin ou d'un tabac (non supprimé)
+func itemInStock(ctx context.Context, db queryRower, itemType string, id int64) (int, error) {
+	var table string
+	switch itemType {
+	case domain.PurchaseItemWine:
+		table = "wines"
+	case domain.PurchaseItemTobacco:
+		table = "tobaccos"
+	default:
+		return 0, NewValidationError("item_type", "item_type must be wine or tobacco")
+	}
+
+	var quantity int
+	err := db.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT quantity FROM %s WHERE id = ? AND deleted_at IS NULL`, table), id).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0, notFound(itemType, id)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", itemType, err)
+	}
+	return quantity, nil
+}
+
+// checkMerchant vérifie que le marchand référencé par un lot existe
+func (s *Store) checkMerchant(ctx context.Context, merchantID *int64) error {
+	if merchantID == nil {
+		return nil
+	}
+	if _, err := s.GetMerchant(ctx, *merchantID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return NewValidationError("merchant_id", fmt.Sprintf("merchant %d does not exist", *merchantID))
+		}
+		return err
+	}
+	return nil
+}
+
+// GetPurchaseHistory retourne les lots d'un article, du plus récent au plus ancien,
+// avec le coût moyen et le coût du stock restant par devise
+func (s *Store) GetPurchaseHistory(ctx context.Context, itemType string, itemID int64) (*domain.PurchaseHistory, error) {
+	inStock, err := itemInStock(ctx, s.Db, itemType, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	lots, err := s.queryLots(ctx, `SELECT `+lotColumns+`
+	WHERE l.item_type = ? AND l.item_id = ?
+	ORDER BY l.purchase_date DESC, l.id DESC`, itemType, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.PurchaseHistory{
+		ItemType: itemType,
+		ItemID:   itemID,
+		InStock:  inStock,
+		Lots:     lots,
+		Costs:    summarizeLots(lots, inStock),
+	}, nil
+}
+
+// summarizeLots calcule les coûts par devise. lots est trié du plus récent au plus
+// ancien : les unités en stock sont attribuées aux lots les plus récents (premier
+// entré, premier sorti) ; au-delà du total des lots, leur coût est inconnu.
+func summarizeLots(lots []*domain.PurchaseLot, inStock int) []*domain.PurchaseCost {
+	byCurrency := make(map[string]*domain.PurchaseCost)
+	remaining := inStock
+	for _, lot := range lots {
+		cost := byCurrency[lot.Currency]
+		if cost == nil {
+			cost = &domain.PurchaseCost{Currency: lot.Currency}
+			byCurrency[lot.Currency] = cost
+		}
+		cost.Lots++
+		cost.Quantity += lot.Quantity
+		cost.TotalCost += float64(lot.Quantity) * lot.UnitPrice
+
+		kept := min(remaining, lot.Quantity)
+		remaining -= kept
+		cost.RemainingQuantity += kept
+		cost.RemainingCost += float64(kept) * lot.UnitPrice
+	}
+
+	costs := make([]*domain.PurchaseCost, 0, len(byCurrency))
+	for _, cost := range byCurrency {
+		if cost.Quantity > 0 {
+			cost.AverageCost = cost.TotalCost / float64(cost.Quantity)
+		}
+		costs = append(costs, cost)
+	}
+	sort.Slice(costs, func(i, j int) bool { return costs[i].Currency < costs[j].Currency })
+	return costs
+}
+
+// movedLotShares répartit les unités en stock entre les lots comme summarizeLots (lots du
+// plus récent au plus ancien) et retourne, par lot, la part des `moved` unités qui quittent
+// l'entrée. Les plus anciennes partent : l'entrée garde les lots les plus récents.
+func movedLotShares(lots []*domain.PurchaseLot, inStock, moved int) []int {
+	shares := make([]int, len(lots))
+	remaining := inStock
+	stay := inStock - moved
+	for i, lot := range lots {
+		kept := min(remaining, lot.Quantity)
+		remaining -= kept
+		staying := min(stay, kept)
+		stay -= staying
+		shares[i] = kept - staying
+	}
+	return shares
+}
+
+// moveLots transfère à l'entrée toID la part des lots de fromID correspondant aux `moved`
+// bouteilles détachées lors d'une scission (inStock : bouteilles en stock avant la scission).
+// Un lot entièrement transféré change d'entrée, un lot partagé est scindé ; le prix d'achat
+// des bouteilles déplacées devient celui de leur lot.
+func moveLots(ctx context.Context, tx *sql.Tx, fromID, toID int64, inStock, moved int) error {
+	rows, err := tx.QueryContext(ctx, `
+	SELECT id, quantity, unit_price FROM purchase_lots
+	WHERE item_type = 'wine' AND item_id = ?
+	ORDER BY purchase_date DESC, id DESC`, fromID)
+	if err != nil {
+		return fmt.Errorf("failed to query purchase lots: %w", err)
+	}
+	lots := make([]*domain.PurchaseLot, 0)
+	for rows.Next() {
+		l := &domain.PurchaseLot{}
+		if err := rows.Scan(&l.ID, &l.Quantity, &l.UnitPrice); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan purchase lot: %w", err)
+		}
+		lots = append(lots, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating purchase lots: %w", err)
+	}
+
+	prices := make([]float64, 0, moved)
+	for i, share := range movedLotShares(lots, inStock, moved) {
+		if share == 0 {
+			continue
+		}
+		lot := lots[i]
+		if share == lot.Quantity {
+			_, err = tx.ExecContext(ctx, `UPDATE purchase_lots SET item_id = ? WHERE id = ?`, toID, lot.ID)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+			INSERT INTO purchase_lots (item_type, item_id, merchant_id, purchase_date, quantity, unit_price, currency, invoice_ref, notes, created_at)
+			SELECT item_type, ?, merchant_id, purchase_date, ?, unit_price, currency, invoice_ref, notes, created_at
+			FROM purchase_lots WHERE id = ?`, toID, share, lot.ID)
+			if err == nil {
+				_, err = tx.ExecContext(ctx, `UPDATE purchase_lots SET quantity = quantity - ? WHERE id = ?`, share, lot.ID)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to move purchase lot %d: %w", lot.ID, err)
+		}
+		for range share {
+			prices = append(prices, lot.UnitPrice)
+		}
+	}
+
+	// Les bouteilles sans lot (stock au-delà des lots) gardent leur prix
+	rows, err = tx.QueryContext(ctx,
+		`SELECT id FROM bottle_units WHERE wine_id = ? AND state = 'in_stock' ORDER BY id LIMIT ?`, toID, len(prices))
+	if err != nil {
+		return fmt.Errorf("failed to query bottle units: %w", err)
+	}
+	unitIDs := make([]int64, 0, len(prices))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan bottle unit: %w", err)
+		}
+		unitIDs = append(unitIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating bottle units: %w", err)
+	}
+	for i, id := range unitIDs {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE bottle_units SET purchase_price = ? WHERE id = ?`, prices[i], id); err != nil {
+			return fmt.Errorf("failed to update bottle purchase price: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetPurchaseLot retourne un lot par son ID
+func (s *Store) GetPurchaseLot(ctx context.Context, id int64) (*domain.PurchaseLot, error) {
+	lot, err := scanLot(s.Db.QueryRowContext(ctx, `SELECT `+lotColumns+` WHERE l.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, notFound("purchase lot", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query purchase lot: %w", err)
+	}
+	return lot, nil
+}
+
+// CreatePurchaseLot enregistre un lot d'achat pour un vin ou un tabac existant.
+// Le stock de l'article n'est pas modifié : le lot documente l'acquisition.
+func (s *Store) CreatePurchaseLot(ctx context.Context, lot *domain.PurchaseLot) (int64, error) {
+	if _, err := itemInStock(ctx, s.Db, lot.ItemType, lot.ItemID); err != nil {
+		return 0, err
+	}
+	if err := s.checkMerchant(ctx, lot.MerchantID); err != nil {
+		return 0, err
+	}
+
+	result, err := s.Db.ExecContext(ctx, `
+	INSERT INTO purchase_lots (item_type, item_id, merchant_id, purchase_date, quantity, unit_price, currency, invoice_ref, notes)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		lot.ItemType, lot.ItemID, lot.MerchantID, lot.PurchaseDate, lot.Quantity, lot.UnitPrice, lot.Currency, lot.InvoiceRef, lot.Notes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create purchase lot: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdatePurchaseLot met à jour un lot (l'article rattaché ne change pas)
+func (s *Store) UpdatePurchaseLot(ctx context.Context, lot *domain.PurchaseLot) error {
+	if err := s.checkMerchant(ctx, lot.MerchantID); err != nil {
+		return err
+	}
+
+	result, err := s.Db.ExecContext(ctx, `
+	UPDATE purchase_lots SET merchant_id = ?, purchase_date = ?, quantity = ?, unit_price = ?, currency = ?, invoice_ref = ?, notes = ?
+	WHERE id = ?`,
+		lot.MerchantID, lot.PurchaseDate, lot.Quantity, lot.UnitPrice, lot.Currency, lot.InvoiceRef, lot.Notes, lot.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update purchase lot: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return notFound("purchase lot", lot.ID)
+	}
+	return nil
+}
+
+// DeletePurchaseLot supprime un lot
+func (s *Store) DeletePurchaseLot(ctx context.Context, id int64) error {
+	result, err := s.Db.ExecContext(ctx, `DELETE FROM purchase_lots WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete purchase lot: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return notFound("purchase lot", id)
+	}
+	return nil
+}
+
+// GetMerchantPurchases retourne les achats faits chez un marchand, du plus récent au plus ancien
+func (s *Store) GetMerchantPurchases(ctx context.Context, merchantID int64) ([]*domain.PurchaseLot, error) {
+	if _, err := s.GetMerchant(ctx, merchantID); err != nil {
+		return nil, err
+	}
+	return s.queryLots(ctx, `SELECT `+lotColumns+`
+	WHERE l.merchant_id = ?
+	ORDER BY l.purchase_date DESC, l.id DESC`, merchantID)
+}
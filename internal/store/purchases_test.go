@@ -0,0 +1,44 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+func TestSummarizeLots(t *testing.T) {
+	// Du plus récent au plus ancien : le lot à 10 € a été consommé en premier
+	lots := []*domain.PurchaseLot{
+		{Quantity: 6, UnitPrice: 20, Currency: "EUR"},
+		{Quantity: 6, UnitPrice: 10, Currency: "EUR"},
+		{Quantity: 2, UnitPrice: 30, Currency: "USD"},
+	}
+
+	costs := summarizeLots(lots, 8)
+	if len(costs) != 2 || costs[0].Currency != "EUR" || costs[1].Currency != "USD" {
+		t.Fatalf("expected one EUR and one USD summary, got %+v", costs)
+	}
+	eur := costs[0]
+	if eur.Lots != 2 || eur.Quantity != 12 || eur.TotalCost != 180 || eur.AverageCost != 15 {
+		t.Errorf("unexpected EUR totals: %+v", eur)
+	}
+	if eur.RemainingQuantity != 8 || eur.RemainingCost != 140 {
+		t.Errorf("expected 8 bottles left worth 140, got %d worth %v", eur.RemainingQuantity, eur.RemainingCost)
+	}
+	if usd := costs[1]; usd.RemainingQuantity != 0 || usd.RemainingCost != 0 {
+		t.Errorf("expected no USD bottle left, got %+v", usd)
+	}
+}
+
+func TestMovedLotShares(t *testing.T) {
+	lots := []*domain.PurchaseLot{
+		{Quantity: 6, UnitPrice: 20},
+		{Quantity: 6, UnitPrice: 10},
+	}
+
+	// 10 bouteilles en stock (6 à 20, 4 à 10) : les 5 plus anciennes partent
+	shares := movedLotShares(lots, 10, 5)
+	if len(shares) != 2 || shares[0] != 1 || shares[1] != 4 {
+		t.Errorf("expected shares [1 4], got %v", shares)
+	}
+}
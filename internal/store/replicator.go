@@ -0,0 +1,552 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Réplication continue (à la Litestream) : la base tourne en mode WAL et le Replicator
+// recopie les transactions validées du fichier -wal vers un répertoire de réplique.
+//
+// Chaque génération commence par un instantané de la base ; les segments de WAL expédiés
+// ensuite se rejouent dans l'ordre par-dessus. Une nouvelle génération démarre quand SQLite
+// recycle le WAL (checkpoint complet) et à chaque intervalle d'instantané :
+//
+//	<dir>/generations/<début UTC>/snapshot.db
+//	<dir>/generations/<début UTC>/wal/<séquence>-<unix ms>.wal
+
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+
+	// generationFormat nomme les générations ; l'ordre lexical est l'ordre chronologique
+	generationFormat = "20060102T150405.000000Z"
+)
+
+// ReplicaPolicy décrit la fréquence des instantanés et la durée de conservation de la réplique
+type ReplicaPolicy struct {
+	SnapshotInterval time.Duration // démarre une nouvelle génération après cette durée
+	Retention        time.Duration // une génération remplacée depuis plus longtemps est supprimée
+}
+
+// ReplicaStatus est l'état de la réplication exposé par /health
+type ReplicaStatus struct {
+	Enabled    bool       `json:"enabled"`
+	Healthy    bool       `json:"healthy"`
+	Generation string     `json:"generation,omitempty"`
+	LastSyncAt *time.Time `json:"last_sync_at,omitempty"`
+	LagSeconds float64    `json:"lag_seconds"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Replicator expédie en continu le WAL de la base vers un répertoire de réplique
+type Replicator struct {
+	store  *Store
+	dbPath string
+	dir    string
+	policy ReplicaPolicy
+
+	// Génération courante (utilisée uniquement par la boucle de réplication)
+	generationStart time.Time
+	offset          int64  // octets du -wal déjà expédiés
+	salt            []byte // sels de l'en-tête WAL expédié (nil tant qu'aucun en-tête)
+	pageSize        int64
+	seq             int
+
+	state   sync.Mutex // protège les champs lus par Status
+	status  ReplicaStatus
+	started time.Time
+	maxLag  time.Duration
+
+	ticker   *time.Ticker
+	stopChan chan struct{}
+	stopOnce sync.Once
+	done     chan struct{} // nil tant que Start n'a pas été appelé
+	mu       sync.Mutex
+}
+
+// NewReplicator crée un Replicator pour la base dbPath (déjà ouverte par store) vers dir
+func NewReplicator(store *Store, dbPath, dir string, policy ReplicaPolicy) *Replicator {
+	return &Replicator{
+		store:    store,
+		dbPath:   dbPath,
+		dir:      dir,
+		policy:   policy,
+		status:   ReplicaStatus{Enabled: true},
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start réplique immédiatement puis à chaque intervalle.
+// Un Replicator ne démarre qu'une fois : les appels suivants sont sans effet.
+func (r *Replicator) Start(interval time.Duration) {
+	r.mu.Lock()
+	if r.done != nil {
+		r.mu.Unlock()
+		return
+	}
+	ticker := time.NewTicker(interval)
+	r.ticker = ticker
+	r.done = make(chan struct{})
+	r.mu.Unlock()
+
+	r.state.Lock()
+	r.started = time.Now()
+	// Au-delà de ce retard, la réplique est signalée comme dégradée
+	r.maxLag = max(10*interval, time.Minute)
+	r.state.Unlock()
+
+	go func() {
+		defer close(r.done)
+
+		r.sync()
+		for {
+			select {
+			case <-ticker.C:
+				r.sync()
+			case <-r.stopChan:
+				// Expédier les dernières transactions avant l'arrêt
+				r.sync()
+				return
+			}
+		}
+	}()
+}
+
+// Stop arrête la réplication après une dernière synchronisation.
+// Sans effet si la réplication n'a pas démarré ; plusieurs appels sont possibles.
+func (r *Replicator) Stop() {
+	r.mu.Lock()
+	if r.ticker != nil {
+		r.ticker.Stop()
+		r.ticker = nil
+	}
+	done := r.done
+	r.mu.Unlock()
+
+	if done == nil {
+		return
+	}
+	r.stopOnce.Do(func() { close(r.stopChan) })
+	<-done
+}
+
+// Status retourne l'état courant de la réplication ; le retard est le temps écoulé
+// depuis la dernière synchronisation réussie.
+func (r *Replicator) Status() ReplicaStatus {
+	r.state.Lock()
+	defer r.state.Unlock()
+
+	status := r.status
+	since := r.started
+	if status.LastSyncAt != nil {
+		since = *status.LastSyncAt
+	}
+	if !since.IsZero() {
+		status.LagSeconds = time.Since(since).Seconds()
+	}
+	status.Healthy = status.Error == "" && status.LastSyncAt != nil && time.Since(since) <= r.maxLag
+	return status
+}
+
+// sync réplique et met à jour l'état exposé
+func (r *Replicator) sync() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	err := r.replicate(ctx)
+
+	r.state.Lock()
+	defer r.state.Unlock()
+	if err != nil {
+		if r.status.Error == "" {
+			log.Printf("Error replicating database: %v", err)
+		}
+		r.status.Error = err.Error()
+		return
+	}
+	now := time.Now()
+	r.status.Error = ""
+	r.status.LastSyncAt = &now
+	r.status.Generation = r.generationName()
+}
+
+// generationName retourne le nom de la génération courante
+func (r *Replicator) generationName() string {
+	if r.generationStart.IsZero() {
+		return ""
+	}
+	return r.generationStart.Format(generationFormat)
+}
+
+// replicate expédie les trames validées depuis la dernière synchronisation
+func (r *Replicator) replicate(ctx context.Context) error {
+	snapshotDue := r.generationStart.IsZero() || time.Since(r.generationStart) >= r.policy.SnapshotInterval
+	if snapshotDue {
+		// Vider le WAL dans la base : la nouvelle génération part d'un instantané compact
+		if _, err := r.store.Db.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+			return fmt.Errorf("failed to checkpoint WAL: %w", err)
+		}
+	}
+
+	conn, err := r.store.Db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Le verrou d'écriture fige le WAL : aucune trame ne peut être ajoutée ni recyclée pendant la copie
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return fmt.Errorf("failed to lock database: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `ROLLBACK`)
+
+	wal, err := os.ReadFile(r.dbPath + "-wal")
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read WAL: %w", err)
+	}
+
+	// Un WAL plus court que ce qui a été expédié, ou dont les sels ont changé, a été recyclé
+	restarted := int64(len(wal)) < r.offset
+	if r.salt != nil && (len(wal) < walHeaderSize || !bytes.Equal(wal[16:24], r.salt)) {
+		restarted = true
+	}
+	if snapshotDue || restarted {
+		if err := r.newGeneration(); err != nil {
+			return err
+		}
+	}
+
+	if r.salt == nil {
+		if len(wal) < walHeaderSize {
+			return nil
+		}
+		if magic := binary.BigEndian.Uint32(wal[0:4]); magic != 0x377f0682 && magic != 0x377f0683 {
+			return fmt.Errorf("invalid WAL header (magic %#x)", magic)
+		}
+		r.salt = bytes.Clone(wal[16:24])
+		r.pageSize = int64(binary.BigEndian.Uint32(wal[8:12]))
+	}
+
+	end := lastCommitEnd(wal, max(r.offset, walHeaderSize), r.pageSize, r.salt)
+	if end <= r.offset || end <= walHeaderSize {
+		return nil
+	}
+	if err := r.writeSegment(wal[r.offset:end]); err != nil {
+		return err
+	}
+	r.offset = end
+	return nil
+}
+
+// lastCommitEnd retourne la fin de la dernière trame de validation (commit) à partir de start.
+// Les trames d'une transaction non validée ou d'un WAL précédent (autres sels) sont ignorées.
+func lastCommitEnd(wal []byte, start, pageSize int64, salt []byte) int64 {
+	end := start
+	frameSize := walFrameHeaderSize + pageSize
+	for pos := start; pos+frameSize <= int64(len(wal)); pos += frameSize {
+		header := wal[pos : pos+walFrameHeaderSize]
+		if !bytes.Equal(header[8:16], salt) {
+			break
+		}
+		if binary.BigEndian.Uint32(header[4:8]) != 0 {
+			end = pos + frameSize
+		}
+	}
+	return end
+}
+
+// newGeneration copie la base dans une nouvelle génération.
+// Appelée verrou d'écriture tenu : la copie, complétée par le WAL entier, est cohérente
+// même si un checkpoint est en cours (ses pages sont réécrites au rejeu).
+func (r *Replicator) newGeneration() error {
+	start := time.Now().UTC()
+	dir := filepath.Join(r.dir, "generations", start.Format(generationFormat))
+	if err := os.MkdirAll(filepath.Join(dir, "wal"), 0o750); err != nil {
+		return fmt.Errorf("failed to create replica generation: %w", err)
+	}
+
+	snapshot := filepath.Join(dir, "snapshot.db")
+	if err := copyFile(r.dbPath, snapshot+".tmp"); err != nil {
+		os.Remove(snapshot + ".tmp")
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	if err := os.Rename(snapshot+".tmp", snapshot); err != nil {
+		return fmt.Errorf("failed to finalize snapshot: %w", err)
+	}
+
+	r.generationStart = start
+	r.offset = 0
+	r.salt = nil
+	r.pageSize = 0
+	r.seq = 0
+
+	if err := r.prune(); err != nil {
+		log.Printf("Error pruning replica generations: %v", err)
+	}
+	return nil
+}
+
+// writeSegment écrit un segment de WAL dans la génération courante
+func (r *Replicator) writeSegment(data []byte) error {
+	name := fmt.Sprintf("%08d-%d.wal", r.seq, time.Now().UnixMilli())
+	path := filepath.Join(r.dir, "generations", r.generationName(), "wal", name)
+
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL segment: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write WAL segment: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync WAL segment: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write WAL segment: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to finalize WAL segment: %w", err)
+	}
+
+	r.seq++
+	return nil
+}
+
+// prune supprime les générations remplacées depuis plus longtemps que la rétention.
+// La génération courante est toujours conservée.
+func (r *Replicator) prune() error {
+	generations, err := listGenerations(r.dir)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-r.policy.Retention)
+	for i := 0; i < len(generations)-1; i++ {
+		// Une génération couvre la période jusqu'au début de la suivante
+		if generations[i+1].start.After(cutoff) {
+			break
+		}
+		if err := os.RemoveAll(generations[i].path); err != nil {
+			return fmt.Errorf("failed to remove generation %s: %w", filepath.Base(generations[i].path), err)
+		}
+	}
+	return nil
+}
+
+// replicaGeneration est une génération présente dans le répertoire de réplique
+type replicaGeneration struct {
+	path  string
+	start time.Time
+}
+
+// listGenerations liste les générations complètes (avec instantané), de la plus ancienne à la plus récente
+func listGenerations(dir string) ([]replicaGeneration, error) {
+	root := filepath.Join(dir, "generations")
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replica directory: %w", err)
+	}
+
+	generations := make([]replicaGeneration, 0, len(entries))
+	for _, entry := range entries {
+		start, err := time.Parse(generationFormat, entry.Name())
+		if !entry.IsDir() || err != nil {
+			continue
+		}
+		path := filepath.Join(root, entry.Name())
+		if _, err := os.Stat(filepath.Join(path, "snapshot.db")); err != nil {
+			continue
+		}
+		generations = append(generations, replicaGeneration{path: path, start: start})
+	}
+	sort.Slice(generations, func(i, j int) bool {
+		return generations[i].start.Before(generations[j].start)
+	})
+	return generations, nil
+}
+
+// walSegment est un segment de WAL expédié
+type walSegment struct {
+	path     string
+	seq      int
+	syncedAt time.Time
+}
+
+// listSegments liste les segments d'une génération dans l'ordre de rejeu
+func listSegments(generationPath string) ([]walSegment, error) {
+	entries, err := os.ReadDir(filepath.Join(generationPath, "wal"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WAL segments: %w", err)
+	}
+
+	segments := make([]walSegment, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".wal")
+		if !ok {
+			continue
+		}
+		seqStr, msStr, ok := strings.Cut(name, "-")
+		seq, err1 := strconv.Atoi(seqStr)
+		ms, err2 := strconv.ParseInt(msStr, 10, 64)
+		if !ok || err1 != nil || err2 != nil {
+			continue
+		}
+		segments = append(segments, walSegment{
+			path:     filepath.Join(generationPath, "wal", entry.Name()),
+			seq:      seq,
+			syncedAt: time.UnixMilli(ms),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].seq < segments[j].seq })
+
+	// Un trou dans la séquence rendrait le WAL illisible au-delà
+	for i, segment := range segments {
+		if segment.seq != i {
+			return segments[:i], nil
+		}
+	}
+	return segments, nil
+}
+
+// ReplicaRestore décrit le résultat d'une restauration depuis la réplique
+type ReplicaRestore struct {
+	Generation    string
+	RestoredTo    time.Time // date du dernier segment rejoué (ou début de la génération)
+	Segments      int
+	SchemaVersion int
+	SafetyCopy    string
+}
+
+// RestoreReplica reconstruit la base dbPath depuis la réplique telle qu'elle était à la date at
+// (zéro = état le plus récent) : instantané de la génération couvrant at, puis rejeu des segments
+// synchronisés au plus tard à cette date. Le serveur doit être arrêté ; la base courante est
+// conservée comme pour RestoreBackup.
+func RestoreReplica(ctx context.Context, replicaDir, dbPath string, at time.Time) (*ReplicaRestore, error) {
+	generations, err := listGenerations(replicaDir)
+	if err != nil {
+		return nil, err
+	}
+	var generation *replicaGeneration
+	for i := range generations {
+		if at.IsZero() || !generations[i].start.After(at) {
+			generation = &generations[i]
+		}
+	}
+	if generation == nil {
+		if at.IsZero() {
+			return nil, fmt.Errorf("%w: no replica generation in %s", ErrNotFound, replicaDir)
+		}
+		return nil, fmt.Errorf("%w: no replica generation before %s", ErrNotFound, at.Format(time.RFC3339))
+	}
+
+	segments, err := listSegments(generation.path)
+	if err != nil {
+		return nil, err
+	}
+	result := &ReplicaRestore{Generation: filepath.Base(generation.path), RestoredTo: generation.start}
+	for _, segment := range segments {
+		if !at.IsZero() && segment.syncedAt.After(at) {
+			break
+		}
+		result.Segments++
+		result.RestoredTo = segment.syncedAt
+	}
+
+	tmpPath := dbPath + ".restore.tmp"
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(tmpPath + suffix)
+	}
+	cleanup := func() {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(tmpPath + suffix)
+		}
+	}
+
+	if err := copyFile(filepath.Join(generation.path, "snapshot.db"), tmpPath); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	if err := writeReplayWAL(tmpPath+"-wal", segments[:result.Segments]); err != nil {
+		cleanup()
+		return nil, err
+	}
+	if err := checkpointReplay(ctx, tmpPath); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	result.SchemaVersion, err = checkRestorable(ctx, tmpPath)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	result.SafetyCopy, err = replaceDatabase(tmpPath, dbPath)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// writeReplayWAL concatène les segments en un fichier -wal que SQLite rejouera à l'ouverture
+func writeReplayWAL(path string, segments []walSegment) error {
+	if len(segments) == 0 {
+		return nil
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL: %w", err)
+	}
+	for _, segment := range segments {
+		in, err := os.Open(segment.path)
+		if err != nil {
+			out.Close()
+			return fmt.Errorf("failed to open WAL segment: %w", err)
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			out.Close()
+			return fmt.Errorf("failed to copy WAL segment: %w", err)
+		}
+	}
+	return out.Close()
+}
+
+// checkpointReplay ouvre la base reconstruite, intègre le WAL rejoué dans le fichier
+// principal et repasse en journal classique pour obtenir un fichier autonome
+func checkpointReplay(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to open restored database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("failed to replay WAL: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, `PRAGMA journal_mode=DELETE`); err != nil {
+		return fmt.Errorf("failed to checkpoint restored database: %w", err)
+	}
+	return nil
+}
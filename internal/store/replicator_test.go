@@ -0,0 +1,42 @@
+package store
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// walFrames construit un WAL (en-tête vide) dont chaque trame porte le sel donné ;
+// un commit non nul marque la dernière trame d'une transaction
+func walFrames(pageSize int, salt []byte, commits ...uint32) []byte {
+	wal := make([]byte, walHeaderSize)
+	for i, commit := range commits {
+		header := make([]byte, walFrameHeaderSize)
+		binary.BigEndian.PutUint32(header[0:4], uint32(i+1))
+		binary.BigEndian.PutUint32(header[4:8], commit)
+		copy(header[8:16], salt)
+		wal = append(wal, header...)
+		wal = append(wal, make([]byte, pageSize)...)
+	}
+	return wal
+}
+
+func TestLastCommitEnd(t *testing.T) {
+	const pageSize = 16
+	salt := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	frameSize := int64(walFrameHeaderSize + pageSize)
+
+	// Deux transactions validées puis une trame non validée
+	wal := walFrames(pageSize, salt, 0, 2, 3, 0)
+	if end := lastCommitEnd(wal, walHeaderSize, pageSize, salt); end != walHeaderSize+3*frameSize {
+		t.Errorf("expected the end of the third frame, got %d", end)
+	}
+	if end := lastCommitEnd(wal, walHeaderSize+3*frameSize, pageSize, salt); end != walHeaderSize+3*frameSize {
+		t.Errorf("expected no progress past the uncommitted frame, got %d", end)
+	}
+
+	// Les trames d'un WAL précédent (autre sel) arrêtent la lecture
+	stale := append(walFrames(pageSize, salt, 1), walFrames(pageSize, []byte{9, 9, 9, 9, 9, 9, 9, 9}, 2)[walHeaderSize:]...)
+	if end := lastCommitEnd(stale, walHeaderSize, pageSize, salt); end != walHeaderSize+frameSize {
+		t.Errorf("expected the scan to stop at the stale frame, got %d", end)
+	}
+}
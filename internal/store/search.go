@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// ftsQuery convertit une saisie libre en requête FTS5 : chaque mot devient un
+// préfixe entre guillemets ("chat"* trouve "Château") et les mots sont combinés en ET.
+// La ponctuation est ignorée, ce qui neutralise la syntaxe FTS5 (guillemets, NEAR, *...).
+func ftsQuery(input string) string {
+	words := strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// Search effectue une recherche plein texte sur les vins, les tabacs et les
+// commentaires de dégustation. Les résultats sont triés par pertinence (bm25),
+// un même vin n'apparaît qu'une fois.
+func (s *Store) Search(ctx context.Context, input string, limit int) ([]*domain.SearchResult, error) {
+	match := ftsQuery(input)
+	if match == "" {
+		return []*domain.SearchResult{}, nil
+	}
+
+	// Le nom pèse plus lourd que les détails ; les colonnes UNINDEXED ont un poids nul
+	query := `
+	SELECT kind, ref_id, wine_id,
+		snippet(search_index, -1, '<mark>', '</mark>', '…', 12),
+		bm25(search_index, 0, 0, 0, 10.0, 1.0) AS rank
+	FROM search_index
+	WHERE search_index MATCH ?
+	ORDER BY rank
+	LIMIT ?
+	`
+	// Marge pour les doublons (un vin trouvé aussi via ses commentaires)
+	rows, err := s.Db.QueryContext(ctx, query, match, limit*3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	type hit struct {
+		kind    string
+		refID   int64
+		wineID  *int64
+		snippet string
+		rank    float64
+	}
+	var hits []hit
+	for rows.Next() {
+		var h hit
+		if err := rows.Scan(&h.kind, &h.refID, &h.wineID, &h.snippet, &h.rank); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		hits = append(hits, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	results := make([]*domain.SearchResult, 0, limit)
+	seen := make(map[string]bool)
+	for _, h := range hits {
+		if len(results) >= limit {
+			break
+		}
+
+		result := &domain.SearchResult{MatchedIn: h.kind, Snippet: h.snippet, Rank: h.rank}
+		switch h.kind {
+		case "wine", "consumption":
+			if h.wineID == nil {
+				continue
+			}
+			result.Type, result.ID = "wine", *h.wineID
+		case "tobacco":
+			result.Type, result.ID = "tobacco", h.refID
+		default:
+			continue
+		}
+
+		key := fmt.Sprintf("%s:%d", result.Type, result.ID)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if result.Type == "wine" {
+			wine, err := s.GetWineByID(ctx, result.ID)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			result.Name, result.Wine = wine.Name, wine
+		} else {
+			tobacco, err := s.GetTobaccoByID(ctx, result.ID)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			result.Name, result.Tobacco = tobacco.Name, tobacco
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
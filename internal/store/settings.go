@@ -17,7 +17,7 @@ func (s *Store) GetSettings(ctx context.Context) (*domain.Settings, error) {
 		   public_domain, public_protocol, proxy_mode, proxy_headers,
 		   allow_registration, require_approval, enable_notifications, maintenance_mode,
 		   rows_per_page, date_format, language, max_request_body_size, session_timeout,
-		   smtp_configured, created_at, updated_at
+		   smtp_configured, trash_retention_days, created_at, updated_at
 	FROM settings
 	LIMIT 1
 	`
@@ -33,7 +33,7 @@ func (s *Store) GetSettings(ctx context.Context) (*domain.Settings, error) {
 		&settings.ProxyHeaders, &settings.AllowRegistration, &settings.RequireApproval,
 		&settings.EnableNotifications, &settings.MaintenanceMode, &settings.RowsPerPage,
 		&settings.DateFormat, &settings.Language, &settings.MaxRequestBodySize, &settings.SessionTimeout,
-		&smtpConfigured, &settings.CreatedAt, &settings.UpdatedAt,
+		&smtpConfigured, &settings.TrashRetentionDays, &settings.CreatedAt, &settings.UpdatedAt,
 	)
 
 	settings.SMTPConfigured = smtpConfigured == 1
@@ -67,6 +67,7 @@ func (s *Store) createDefaultSettings(ctx context.Context) (*domain.Settings, er
 		Language:            "en",
 		MaxRequestBodySize:  1048576,
 		SessionTimeout:      1440,
+		TrashRetentionDays:  DefaultTrashRetentionDays,
 		CreatedAt:           time.Now(),
 		UpdatedAt:           time.Now(),
 	}
@@ -75,8 +76,8 @@ func (s *Store) createDefaultSettings(ctx context.Context) (*domain.Settings, er
 	INSERT INTO settings (app_title, app_slogan, theme_color, secondary_color, accent_color, dark_mode_default,
 		public_protocol, proxy_mode, proxy_headers, allow_registration, require_approval,
 		enable_notifications, maintenance_mode, rows_per_page, date_format, language,
-		max_request_body_size, session_timeout, created_at, updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		max_request_body_size, session_timeout, trash_retention_days, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := s.Db.ExecContext(ctx, query,
@@ -85,7 +86,7 @@ func (s *Store) createDefaultSettings(ctx context.Context) (*domain.Settings, er
 		settings.ProxyHeaders, settings.AllowRegistration, settings.RequireApproval,
 		settings.EnableNotifications, settings.MaintenanceMode, settings.RowsPerPage,
 		settings.DateFormat, settings.Language, settings.MaxRequestBodySize, settings.SessionTimeout,
-		settings.CreatedAt, settings.UpdatedAt,
+		settings.TrashRetentionDays, settings.CreatedAt, settings.UpdatedAt,
 	)
 
 	if err != nil {
@@ -107,7 +108,7 @@ func (s *Store) UpdateSettings(ctx context.Context, settings *domain.Settings) e
 		public_domain = ?, public_protocol = ?, proxy_mode = ?, proxy_headers = ?,
 		allow_registration = ?, require_approval = ?, enable_notifications = ?, maintenance_mode = ?,
 		rows_per_page = ?, date_format = ?, language = ?, max_request_body_size = ?, session_timeout = ?,
-		smtp_configured = ?, updated_at = ?
+		smtp_configured = ?, trash_retention_days = ?, updated_at = ?
 	WHERE id = ?
 	`
 
@@ -135,6 +136,7 @@ func (s *Store) UpdateSettings(ctx context.Context, settings *domain.Settings) e
 		settings.MaxRequestBodySize,
 		settings.SessionTimeout,
 		boolToInt(settings.SMTPConfigured),
+		settings.TrashRetentionDays,
 		time.Now(),
 		settings.ID,
 	)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/romain/glou-server/internal/crypto"
@@ -17,27 +19,50 @@ type Store struct {
 	EncryptionService *crypto.EncryptionService // Service de chiffrement ANSSI
 }
 
-// New initialise et retourne un Store
+// New initialise et retourne un Store dont le schéma est à jour
 func New(dbPath string) (*Store, error) {
+	store, err := Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Appliquer les migrations en attente
+	applied, err := store.Migrate(context.Background())
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	}
+
+	return store, nil
+}
+
+// connectionPragmas sont appliqués à chaque connexion du pool.
+// Le mode WAL laisse les lectures concurrentes des écritures et permet la réplication continue.
+const connectionPragmas = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+// Open ouvre la base sans appliquer de migration (utilisé par la CLI)
+func Open(dbPath string) (*Store, error) {
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+
 	// Ouvrir la connexion SQLite
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := sql.Open("sqlite", dbPath+separator+connectionPragmas)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Vérifier la connexion
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	store := &Store{Db: db}
-
-	// Initialiser le schéma
-	if err := store.initSchema(); err != nil {
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
-	return store, nil
+	return &Store{Db: db}, nil
 }
 
 // SetEncryptionService configure le service de chiffrement
@@ -53,212 +78,194 @@ func (s *Store) Close() error {
 	return s.Db.Close()
 }
 
-// initSchema crée les tables si elles n'existent pas
-func (s *Store) initSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS caves (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		model TEXT,
-		location TEXT NOT NULL DEFAULT 'Principale',
-		capacity INTEGER NOT NULL DEFAULT 100,
-		current INTEGER NOT NULL DEFAULT 0,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS cells (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		cave_id INTEGER NOT NULL,
-		location TEXT NOT NULL,
-		capacity INTEGER NOT NULL DEFAULT 1,
-		current INTEGER NOT NULL DEFAULT 0,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (cave_id) REFERENCES caves(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS wines (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		region TEXT NOT NULL,
-		vintage INTEGER NOT NULL,
-		type TEXT NOT NULL,
-		quantity INTEGER NOT NULL DEFAULT 1,
-		cell_id INTEGER,
-		producer TEXT,
-		alcohol_level REAL,
-		price REAL,
-		current_value REAL,
-		rating REAL,
-		comments TEXT,
-		consumed INTEGER NOT NULL DEFAULT 0,
-		min_apogee_date DATE,
-		max_apogee_date DATE,
-		consumption_date DATE,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (cell_id) REFERENCES cells(id) ON DELETE SET NULL
-	);
-
-	CREATE TABLE IF NOT EXISTS alerts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		wine_id INTEGER NOT NULL,
-		alert_type TEXT NOT NULL,
-		status TEXT NOT NULL DEFAULT 'active',
-		dismissed_at DATETIME,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (wine_id) REFERENCES wines(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS tobacco_alerts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		tobacco_id INTEGER NOT NULL,
-		alert_type TEXT NOT NULL,
-		status TEXT NOT NULL DEFAULT 'active',
-		dismissed_at DATETIME,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (tobacco_id) REFERENCES tobaccos(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS consumption_history (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		wine_id INTEGER NOT NULL,
-		quantity INTEGER NOT NULL,
-		rating REAL,
-		comment TEXT,
-		reason TEXT,
-		date DATE NOT NULL,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (wine_id) REFERENCES wines(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS settings (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		app_title TEXT DEFAULT 'Glou',
-		app_slogan TEXT DEFAULT 'Your personal cellar',
-		logo_url TEXT,
-		favicon_url TEXT,
-		support_email TEXT,
-		theme_color TEXT DEFAULT '#007bff',
-		secondary_color TEXT DEFAULT '#6c757d',
-		accent_color TEXT DEFAULT '#28a745',
-		dark_mode_default INTEGER DEFAULT 0,
-		public_domain TEXT,
-		public_protocol TEXT DEFAULT 'http',
-		proxy_mode INTEGER DEFAULT 0,
-		proxy_headers INTEGER DEFAULT 0,
-		allow_registration INTEGER DEFAULT 0,
-		require_approval INTEGER DEFAULT 0,
-		enable_notifications INTEGER DEFAULT 1,
-		maintenance_mode INTEGER DEFAULT 0,
-		rows_per_page INTEGER DEFAULT 10,
-		date_format TEXT DEFAULT 'YYYY-MM-DD',
-		language TEXT DEFAULT 'en',
-		max_request_body_size INTEGER DEFAULT 1048576,
-		session_timeout INTEGER DEFAULT 1440,
-		smtp_configured INTEGER DEFAULT 0,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS activity_log (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		entity_type TEXT NOT NULL,
-		entity_id INTEGER NOT NULL,
-		action TEXT NOT NULL,
-		details TEXT,
-		ip_address TEXT,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT NOT NULL UNIQUE,
-		email TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		role TEXT NOT NULL DEFAULT 'user',
-		is_active INTEGER NOT NULL DEFAULT 1,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS setup_wizard (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		completed INTEGER NOT NULL DEFAULT 0,
-		completed_at DATETIME,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS encrypted_credentials (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		service_name TEXT NOT NULL UNIQUE,
-		credential_type TEXT NOT NULL,
-		encrypted_value TEXT NOT NULL,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS password_reset_tokens (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		token TEXT NOT NULL UNIQUE,
-		expires_at DATETIME NOT NULL,
-		used INTEGER NOT NULL DEFAULT 0,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS tobaccos (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		brand TEXT,
-		purchase_date DATE,
-		quantity INTEGER NOT NULL DEFAULT 1,
-		purchase_price REAL,
-		current_value REAL,
-		cave_id INTEGER,
-		cell_id INTEGER,
-		notes TEXT,
-		origin_country TEXT,
-		format TEXT,
-		wrapper TEXT,
-		binder TEXT,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (cave_id) REFERENCES caves(id) ON DELETE SET NULL,
-		FOREIGN KEY (cell_id) REFERENCES cells(id) ON DELETE SET NULL
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_activity_log_entity ON activity_log(entity_type, entity_id);
-	CREATE INDEX IF NOT EXISTS idx_activity_log_created ON activity_log(created_at DESC);
-	CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
-	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
-	CREATE INDEX IF NOT EXISTS idx_encrypted_credentials_service ON encrypted_credentials(service_name);
-	CREATE INDEX IF NOT EXISTS idx_password_reset_token ON password_reset_tokens(token);
-	CREATE INDEX IF NOT EXISTS idx_password_reset_expires ON password_reset_tokens(expires_at);
-	`
+// wineColumns liste les colonnes lues par scanWine, dans l'ordre
+const wineColumns = `id, name, region, appellation_id, vintage, type, quantity, format, volume_ml, cell_id, cave_id, user_id, producer, producer_id,
+	alcohol_level, price, current_value, rating, comments, consumed, min_apogee_date,
+	max_apogee_date, consumption_date, bar_code, image, external_id, status, finished_at,
+	style, ibu, ebc, packaged_at, best_before, age_statement, distillery, cask_type, cask_number,
+	distilled_year, bottled_year, cask_strength, independent_bottler, created_at, version`
+
+// rowScanner est implémenté par *sql.Row et *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWine lit une ligne sélectionnée avec wineColumns
+func scanWine(row rowScanner) (*domain.Wine, error) {
+	wine := &domain.Wine{}
+	err := row.Scan(
+		&wine.ID,
+		&wine.Name,
+		&wine.Region,
+		&wine.AppellationID,
+		&wine.Vintage,
+		&wine.BottleType,
+		&wine.Quantity,
+		&wine.Format,
+		&wine.VolumeML,
+		&wine.CellID,
+		&wine.CaveID,
+		&wine.UserID,
+		&wine.Producer,
+		&wine.ProducerID,
+		&wine.AlcoholLevel,
+		&wine.Price,
+		&wine.CurrentValue,
+		&wine.Rating,
+		&wine.Comments,
+		&wine.Consumed,
+		&wine.MinApogeeDate,
+		&wine.MaxApogeeDate,
+		&wine.ConsumptionDate,
+		&wine.BarCode,
+		&wine.Image,
+		&wine.ExternalID,
+		&wine.Status,
+		&wine.FinishedAt,
+		&wine.Style,
+		&wine.IBU,
+		&wine.EBC,
+		&wine.PackagedAt,
+		&wine.BestBefore,
+		&wine.AgeStatement,
+		&wine.Distillery,
+		&wine.CaskType,
+		&wine.CaskNumber,
+		&wine.DistilledYear,
+		&wine.BottledYear,
+		&wine.CaskStrength,
+		&wine.IndependentBottler,
+		&wine.CreatedAt,
+		&wine.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// WineType reste l'alias historique de BottleType
+	wine.WineType = wine.BottleType
+	return wine, nil
+}
 
-	if _, err := s.Db.Exec(schema); err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
+// queryWines exécute une requête sélectionnant wineColumns et retourne les vins
+func (s *Store) queryWines(ctx context.Context, query string, args ...interface{}) ([]*domain.Wine, error) {
+	rows, err := s.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wines: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
+	wines := make([]*domain.Wine, 0)
+	for rows.Next() {
+		wine, err := scanWine(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wine row: %w", err)
+		}
+		wines = append(wines, wine)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wine rows: %w", err)
+	}
+	rows.Close()
+
+	if err := loadBlends(ctx, s.Db, wines); err != nil {
+		return nil, err
+	}
+	return wines, nil
+}
+
+// bottleType retourne le type stocké en base (BottleType, ou WineType pour les anciens clients)
+func bottleType(wine *domain.Wine) string {
+	if wine.BottleType != "" {
+		return wine.BottleType
+	}
+	return wine.WineType
+}
+
+// wineStatus déduit le statut de la quantité : un vin sans bouteille est terminé
+func wineStatus(quantity int) string {
+	if quantity <= 0 {
+		return domain.BottleStatusFinished
+	}
+	return domain.BottleStatusInStock
+}
+
+// CreateWine insère un nouveau vin et retourne son ID.
+// Sans overflow, un emplacement ou une cave pleine est refusé (ErrConflict).
+func (s *Store) CreateWine(ctx context.Context, wine *domain.Wine, overflow bool) (int64, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, err := insertWine(ctx, tx, wine)
+	if err != nil {
+		return 0, err
+	}
+
+	if !overflow {
+		placed, err := readStock(ctx, tx, "wines", id)
+		if err != nil {
+			return 0, err
+		}
+		if err := checkCapacity(ctx, tx, stock{}, placed); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return id, nil
 }
 
-// CreateWine insère un nouveau vin et retourne son ID
-func (s *Store) CreateWine(ctx context.Context, wine *domain.Wine) (int64, error) {
+// insertWine insère un vin dans la transaction, sans contrôle de capacité
+func insertWine(ctx context.Context, tx *sql.Tx, wine *domain.Wine) (int64, error) {
+	if err := checkPlacement(ctx, tx, wine.CaveID, wine.CellID); err != nil {
+		return 0, err
+	}
+	if err := resolveProducer(ctx, tx, wine); err != nil {
+		return 0, err
+	}
+	if err := resolveAppellation(wine); err != nil {
+		return 0, err
+	}
+	if err := resolveFormat(wine); err != nil {
+		return 0, err
+	}
+	if wine.Format == "" {
+		f := domain.DefaultBottleFormat(bottleType(wine))
+		wine.Format, wine.VolumeML = f.ID, f.VolumeML
+	}
+
+	// La cave est déduite de l'emplacement si elle n'est pas fournie
 	query := `
-	INSERT INTO wines (name, region, vintage, type, quantity, cell_id, producer, 
+	INSERT INTO wines (name, region, appellation_id, vintage, type, quantity, format, volume_ml, cell_id, cave_id, user_id, producer, producer_id,
 		alcohol_level, price, current_value, rating, comments, consumed, min_apogee_date, 
-		max_apogee_date, consumption_date, created_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		max_apogee_date, consumption_date, bar_code, image, external_id, status,
+		style, ibu, ebc, packaged_at, best_before, age_statement, distillery, cask_type, cask_number,
+		distilled_year, bottled_year, cask_strength, independent_bottler, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, (SELECT cave_id FROM cells WHERE id = ?)), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+		?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
+	wine.Status = wineStatus(wine.Quantity)
 
-	result, err := s.Db.ExecContext(ctx, query,
+	result, err := tx.ExecContext(ctx, query,
 		wine.Name,
 		wine.Region,
+		wine.AppellationID,
 		wine.Vintage,
-		wine.WineType,
+		bottleType(wine),
 		wine.Quantity,
+		wine.Format,
+		wine.VolumeML,
 		wine.CellID,
+		wine.CaveID,
+		wine.CellID,
+		wine.UserID,
 		wine.Producer,
+		wine.ProducerID,
 		wine.AlcoholLevel,
 		wine.Price,
 		wine.CurrentValue,
@@ -268,6 +275,23 @@ func (s *Store) CreateWine(ctx context.Context, wine *domain.Wine) (int64, error
 		wine.MinApogeeDate,
 		wine.MaxApogeeDate,
 		wine.ConsumptionDate,
+		wine.BarCode,
+		wine.Image,
+		wine.ExternalID,
+		wine.Status,
+		wine.Style,
+		wine.IBU,
+		wine.EBC,
+		wine.PackagedAt,
+		wine.BestBefore,
+		wine.AgeStatement,
+		wine.Distillery,
+		wine.CaskType,
+		wine.CaskNumber,
+		wine.DistilledYear,
+		wine.BottledYear,
+		wine.CaskStrength,
+		wine.IndependentBottler,
 		time.Now(),
 	)
 	if err != nil {
@@ -278,154 +302,86 @@ func (s *Store) CreateWine(ctx context.Context, wine *domain.Wine) (int64, error
 	if err != nil {
 		return 0, fmt.Errorf("failed to get last insert id: %w", err)
 	}
-
+	if wine.Blend == nil {
+		wine.Blend = []*domain.BlendComponent{}
+	}
+	if err := saveBlend(ctx, tx, id, wine.Blend); err != nil {
+		return 0, err
+	}
 	return id, nil
 }
 
 // GetWines retourne la liste de tous les vins
 func (s *Store) GetWines(ctx context.Context) ([]*domain.Wine, error) {
-	query := `
-	SELECT id, name, region, vintage, type, quantity, cell_id, producer, 
-	       alcohol_level, price, current_value, rating, comments, consumed, min_apogee_date, 
-	       max_apogee_date, consumption_date, created_at
-	FROM wines
-	ORDER BY created_at DESC
-	`
-
-	rows, err := s.Db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query wines: %w", err)
-	}
-	defer rows.Close()
-
-	wines := make([]*domain.Wine, 0)
-	for rows.Next() {
-		wine := &domain.Wine{}
-		err := rows.Scan(
-			&wine.ID,
-			&wine.Name,
-			&wine.Region,
-			&wine.Vintage,
-			&wine.WineType,
-			&wine.Quantity,
-			&wine.CellID,
-			&wine.Producer,
-			&wine.AlcoholLevel,
-			&wine.Price,
-			&wine.CurrentValue,
-			&wine.Rating,
-			&wine.Comments,
-			&wine.Consumed,
-			&wine.MinApogeeDate,
-			&wine.MaxApogeeDate,
-			&wine.ConsumptionDate,
-			&wine.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan wine row: %w", err)
-		}
-		wines = append(wines, wine)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating wine rows: %w", err)
-	}
-
-	return wines, nil
+	query := `SELECT ` + wineColumns + ` FROM wines WHERE deleted_at IS NULL ORDER BY created_at DESC`
+	return s.queryWines(ctx, query)
 }
 
 // GetWineByID retourne un vin par son ID
 func (s *Store) GetWineByID(ctx context.Context, id int64) (*domain.Wine, error) {
-	query := `
-	SELECT id, name, region, vintage, type, quantity, cell_id, producer, 
-	       alcohol_level, price, current_value, rating, comments, consumed, min_apogee_date, 
-	       max_apogee_date, consumption_date, created_at
-	FROM wines
-	WHERE id = ?
-	`
-
-	wine := &domain.Wine{}
-	err := s.Db.QueryRowContext(ctx, query, id).Scan(
-		&wine.ID,
-		&wine.Name,
-		&wine.Region,
-		&wine.Vintage,
-		&wine.WineType,
-		&wine.Quantity,
-		&wine.CellID,
-		&wine.Producer,
-		&wine.AlcoholLevel,
-		&wine.Price,
-		&wine.CurrentValue,
-		&wine.Rating,
-		&wine.Comments,
-		&wine.Consumed,
-		&wine.MinApogeeDate,
-		&wine.MaxApogeeDate,
-		&wine.ConsumptionDate,
-		&wine.CreatedAt,
-	)
+	query := `SELECT ` + wineColumns + ` FROM wines WHERE id = ? AND deleted_at IS NULL`
 
+	wine, err := scanWine(s.Db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("wine not found with id %d", id)
+		return nil, notFound("wine", id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query wine by id: %w", err)
 	}
+	if err := loadBlends(ctx, s.Db, []*domain.Wine{wine}); err != nil {
+		return nil, err
+	}
 
 	return wine, nil
 }
 
-// DeleteWine supprime un vin par son ID (consommation)
-func (s *Store) DeleteWine(ctx context.Context, id int64) error {
-	// Vérifier que le vin existe
-	wine, err := s.GetWineByID(ctx, id)
-	if err != nil {
-		return err
+// RebuyWine crée une nouvelle entrée en stock à partir d'un vin existant (typiquement terminé).
+// Les informations descriptives sont reprises ; la consommation et les notes repartent de zéro.
+func (s *Store) RebuyWine(ctx context.Context, id int64, quantity int, price *float32, cellID *int64, overflow bool) (*domain.Wine, error) {
+	if quantity <= 0 || quantity > domain.MaxBottleQuantity {
+		return nil, NewValidationError("quantity", fmt.Sprintf("quantity must be between 1 and %d", domain.MaxBottleQuantity))
 	}
 
-	if wine.Quantity <= 1 {
-		// Supprimer complètement si c'était la dernière bouteille
-		query := "DELETE FROM wines WHERE id = ?"
-		result, err := s.Db.ExecContext(ctx, query, id)
-		if err != nil {
-			return fmt.Errorf("failed to delete wine: %w", err)
-		}
-
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("failed to get rows affected: %w", err)
-		}
-
-		if rowsAffected == 0 {
-			return fmt.Errorf("wine not found with id %d", id)
-		}
-	} else {
-		// Décrémenter la quantité
-		query := "UPDATE wines SET quantity = quantity - 1 WHERE id = ?"
-		result, err := s.Db.ExecContext(ctx, query, id)
-		if err != nil {
-			return fmt.Errorf("failed to update wine quantity: %w", err)
-		}
-
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("failed to get rows affected: %w", err)
-		}
+	source, err := s.GetWineByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-		if rowsAffected == 0 {
-			return fmt.Errorf("wine not found with id %d", id)
-		}
+	wine := *source
+	wine.ID = 0
+	wine.Quantity = quantity
+	wine.Consumed = 0
+	wine.ConsumptionDate = nil
+	wine.Comments = ""
+	wine.FinishedAt = nil
+	if price != nil {
+		wine.Price = price
+	}
+	if cellID != nil {
+		// La cave sera déduite du nouvel emplacement
+		wine.CellID = cellID
+		wine.CaveID = nil
 	}
 
-	return nil
+	newID, err := s.CreateWine(ctx, &wine, overflow)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetWineByID(ctx, newID)
 }
 
 // SearchWines recherche les vins avec filtres
 func (s *Store) SearchWines(ctx context.Context, filters map[string]interface{}) ([]*domain.Wine, error) {
-	query := `SELECT id, name, region, vintage, type, quantity, cell_id, producer, alcohol_level, price, current_value, rating, comments, consumed, min_apogee_date, max_apogee_date, consumption_date, created_at FROM wines WHERE 1=1`
+	query := `SELECT ` + wineColumns + ` FROM wines WHERE deleted_at IS NULL`
 	var args []interface{}
 
+	if q, ok := filters["q"].(string); ok && ftsQuery(q) != "" {
+		query += ` AND id IN (SELECT wine_id FROM search_index WHERE search_index MATCH ?)`
+		args = append(args, ftsQuery(q))
+	}
+	if includeFinished, _ := filters["include_finished"].(bool); !includeFinished {
+		query += ` AND status <> 'finished'`
+	}
 	if name, ok := filters["name"].(string); ok && name != "" {
 		query += ` AND name LIKE ?`
 		args = append(args, "%"+name+"%")
@@ -435,6 +391,9 @@ func (s *Store) SearchWines(ctx context.Context, filters map[string]interface{})
 		args = append(args, "%"+region+"%")
 	}
 	if wineType, ok := filters["type"].(string); ok && wineType != "" {
+		if bt, ok := domain.NormalizeBottleType(wineType); ok {
+			wineType = bt
+		}
 		query += ` AND type = ?`
 		args = append(args, wineType)
 	}
@@ -442,30 +401,90 @@ func (s *Store) SearchWines(ctx context.Context, filters map[string]interface{})
 		query += ` AND vintage = ?`
 		args = append(args, vintage)
 	}
+	// Cépage désigné par son nom ou un synonyme (Shiraz trouve la Syrah)
+	if grape, ok := filters["grape"].(string); ok && strings.TrimSpace(grape) != "" {
+		g, err := findGrape(ctx, s.Db, grape)
+		if err != nil {
+			return nil, err
+		}
+		if g == nil {
+			return []*domain.Wine{}, nil
+		}
+		query += ` AND id IN (SELECT wine_id FROM wine_grapes WHERE grape_id = ?)`
+		args = append(args, g.ID)
+	}
 
 	query += ` ORDER BY created_at DESC`
 
-	rows, err := s.Db.QueryContext(ctx, query, args...)
+	wines, err := s.queryWines(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search wines: %w", err)
 	}
+	return wines, nil
+}
+
+// wineListSpec décrit les tris et filtres acceptés par ListWines
+var wineListSpec = listSpec{
+	idColumn: "id",
+	sortable: map[string]string{
+		"vintage":         "vintage",
+		"rating":          "rating",
+		"price":           "price",
+		"max_apogee_date": "max_apogee_date",
+		"name":            "name COLLATE NOCASE",
+		"created_at":      "created_at",
+	},
+	defaultSort: "-created_at",
+	filters: map[string]string{
+		"vintage": "vintage",
+		"price":   "price",
+		"rating":  "rating",
+		"cave_id": "cave_id",
+		"cell_id": "cell_id",
+	},
+}
+
+// ListWines retourne une page de vins filtrée et triée (pagination par curseur)
+func (s *Store) ListWines(ctx context.Context, opts ListOptions) ([]*domain.Wine, *PageInfo, error) {
+	q, err := wineListSpec.build(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `SELECT ` + wineColumns + `, ` + q.sortSelect() + ` FROM wines WHERE deleted_at IS NULL`
+	if !opts.Filters.IncludeFinished {
+		query += ` AND status <> 'finished'`
+	}
+	query += q.whereClause() + q.orderLimit()
+	rows, err := s.Db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list wines: %w", err)
+	}
 	defer rows.Close()
 
 	wines := make([]*domain.Wine, 0)
+	var keys []interface{}
+	var ids []int64
 	for rows.Next() {
-		wine := &domain.Wine{}
-		err := rows.Scan(
-			&wine.ID, &wine.Name, &wine.Region, &wine.Vintage, &wine.WineType, &wine.Quantity, &wine.CellID,
-			&wine.Producer, &wine.AlcoholLevel, &wine.Price, &wine.Rating, &wine.Comments, &wine.Consumed,
-			&wine.MinApogeeDate, &wine.MaxApogeeDate, &wine.ConsumptionDate, &wine.CreatedAt,
-		)
+		var key interface{}
+		wine, err := scanWine(sortKeyScanner{row: rows, key: &key})
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan wine: %w", err)
+			return nil, nil, fmt.Errorf("failed to scan wine row: %w", err)
 		}
 		wines = append(wines, wine)
+		keys = append(keys, key)
+		ids = append(ids, wine.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating wine rows: %w", err)
+	}
+	rows.Close()
+	if err := loadBlends(ctx, s.Db, wines); err != nil {
+		return nil, nil, err
 	}
 
-	return wines, rows.Err()
+	n, info := q.pageInfo(len(wines), keys, ids)
+	return wines[:n], info, nil
 }
 
 // AddAlert crée une alerte vin
@@ -500,6 +519,68 @@ func (s *Store) GetAlerts(ctx context.Context) ([]*domain.Alert, error) {
 	return alerts, rows.Err()
 }
 
+// alertListSpec décrit les tris et filtres acceptés par ListAlerts (via le vin concerné)
+var alertListSpec = listSpec{
+	idColumn: "a.id",
+	sortable: map[string]string{
+		"created_at":      "a.created_at",
+		"name":            "w.name COLLATE NOCASE",
+		"vintage":         "w.vintage",
+		"rating":          "w.rating",
+		"price":           "w.price",
+		"max_apogee_date": "w.max_apogee_date",
+	},
+	defaultSort: "-created_at",
+	filters: map[string]string{
+		"vintage": "w.vintage",
+		"price":   "w.price",
+		"rating":  "w.rating",
+		"cave_id": "w.cave_id",
+		"cell_id": "w.cell_id",
+	},
+}
+
+// ListAlerts retourne une page d'alertes actives filtrée et triée (pagination par curseur)
+func (s *Store) ListAlerts(ctx context.Context, opts ListOptions) ([]*domain.Alert, *PageInfo, error) {
+	q, err := alertListSpec.build(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `SELECT a.id, a.wine_id, a.alert_type, a.status, a.created_at, ` + q.sortSelect() + `
+	FROM alerts a LEFT JOIN wines w ON w.id = a.wine_id
+	WHERE a.status = 'active' AND w.deleted_at IS NULL`
+	if !opts.Filters.IncludeFinished {
+		query += ` AND (w.status IS NULL OR w.status <> 'finished')`
+	}
+	query += q.whereClause() + q.orderLimit()
+	rows, err := s.Db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := make([]*domain.Alert, 0)
+	var keys []interface{}
+	var ids []int64
+	for rows.Next() {
+		alert := &domain.Alert{}
+		var key interface{}
+		if err := rows.Scan(&alert.ID, &alert.WineID, &alert.AlertType, &alert.Status, &alert.CreatedAt, &key); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+		keys = append(keys, key)
+		ids = append(ids, alert.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating alert rows: %w", err)
+	}
+
+	n, info := q.pageInfo(len(alerts), keys, ids)
+	return alerts[:n], info, nil
+}
+
 // DismissAlert marque une alerte comme dismissée
 func (s *Store) DismissAlert(ctx context.Context, alertID int64) error {
 	query := `UPDATE alerts SET status = 'dismissed', dismissed_at = ? WHERE id = ?`
@@ -507,8 +588,26 @@ func (s *Store) DismissAlert(ctx context.Context, alertID int64) error {
 	return err
 }
 
-// RecordConsumption enregistre une dégustation avec transaction
+// RecordConsumption enregistre une dégustation avec transaction.
+// Avec UnitID, cette bouteille est consommée ; sinon les bouteilles les plus récentes de l'entrée.
+// Avec VolumeML ou Glasses, le vin est servi depuis une bouteille ouverte (recordPour).
 func (s *Store) RecordConsumption(ctx context.Context, consumption *domain.ConsumptionHistory) (int64, error) {
+	if consumption.VolumeML != nil || consumption.Glasses != nil {
+		return s.recordPour(ctx, consumption)
+	}
+	if consumption.UnitID != nil {
+		// Une bouteille précise : la quantité est d'une bouteille
+		if consumption.Quantity == 0 {
+			consumption.Quantity = 1
+		}
+		if consumption.Quantity != 1 {
+			return 0, NewValidationError("quantity", "quantity must be 1 when a unit_id is given")
+		}
+	}
+	if consumption.Quantity <= 0 {
+		return 0, NewValidationError("quantity", "quantity must be greater than 0")
+	}
+
 	// Begin transaction
 	tx, err := s.Db.BeginTx(ctx, nil)
 	if err != nil {
@@ -516,27 +615,97 @@ func (s *Store) RecordConsumption(ctx context.Context, consumption *domain.Consu
 	}
 	defer tx.Rollback()
 
-	// Insert consumption record
+	// Bouteilles retirées du stock (une bouteille déjà ouverte n'y est plus comptée)
+	fromStock := consumption.Quantity
+	var volume sql.NullInt64
+	if consumption.UnitID != nil {
+		unit, err := getUnit(ctx, tx, `u.id = ?`, *consumption.UnitID)
+		if err != nil {
+			return 0, err
+		}
+		if consumption.WineID == 0 {
+			consumption.WineID = unit.WineID
+		}
+		if unit.WineID != consumption.WineID {
+			return 0, NewValidationError("unit_id", fmt.Sprintf("bottle unit %d does not belong to wine %d", unit.ID, consumption.WineID))
+		}
+		switch unit.State {
+		case domain.UnitStateInStock:
+		case domain.UnitStateOpened:
+			fromStock = 0
+			if unit.RemainingML != nil {
+				volume = sql.NullInt64{Int64: int64(*unit.RemainingML), Valid: true}
+			}
+		default:
+			return 0, fmt.Errorf("%w: bottle unit %d is already %s", ErrConflict, unit.ID, unit.State)
+		}
+		// Marquée avant la mise à jour du compteur : le trigger n'a plus rien à consommer
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE bottle_units SET state = 'consumed', state_changed_at = ? WHERE id = ?`,
+			time.Now(), unit.ID); err != nil {
+			return 0, fmt.Errorf("failed to consume bottle unit: %w", err)
+		}
+	}
+
+	// Le stock doit couvrir les bouteilles retirées ; la bouteille ouverte d'un vin terminé
+	// peut encore être finie
+	var stock int
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT quantity, status FROM wines WHERE id = ? AND deleted_at IS NULL`,
+		consumption.WineID).Scan(&stock, &status)
+	if err == sql.ErrNoRows {
+		return 0, notFound("wine", consumption.WineID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query wine stock: %w", err)
+	}
+	if fromStock > 0 {
+		if status == domain.BottleStatusFinished || stock == 0 {
+			return 0, fmt.Errorf("%w: wine %d is finished", ErrConflict, consumption.WineID)
+		}
+		if fromStock > stock {
+			return 0, NewValidationError("quantity", fmt.Sprintf("only %d bottle(s) left in stock", stock))
+		}
+	}
+
+	// Volume bu : les bouteilles entières, ou le reste de la bouteille ouverte
 	query := `
-	INSERT INTO consumption_history (wine_id, quantity, rating, comment, reason, date)
-	VALUES (?, ?, ?, ?, ?, ?)
+	INSERT INTO consumption_history (wine_id, unit_id, quantity, volume_ml, rating, comment, reason, date)
+	VALUES (?, ?, ?, COALESCE(?, ? * (SELECT volume_ml FROM wines WHERE id = ?)), ?, ?, ?, ?)
+	RETURNING id, volume_ml
 	`
-	result, err := tx.ExecContext(ctx, query, consumption.WineID, consumption.Quantity, consumption.Rating, consumption.Comment, consumption.Reason, consumption.Date)
+	var consumptionID int64
+	var volumeML sql.NullInt64
+	err = tx.QueryRowContext(ctx, query, consumption.WineID, consumption.UnitID, consumption.Quantity,
+		volume, consumption.Quantity, consumption.WineID,
+		consumption.Rating, consumption.Comment, consumption.Reason, consumption.Date).Scan(&consumptionID, &volumeML)
 	if err != nil {
 		return 0, fmt.Errorf("failed to record consumption: %w", err)
 	}
-
-	consumptionID, err := result.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get consumption id: %w", err)
+	if volumeML.Valid {
+		v := int(volumeML.Int64)
+		consumption.VolumeML = &v
 	}
 
 	// Update wine quantity (within same transaction)
-	updateQuery := `UPDATE wines SET quantity = quantity - ?, consumed = consumed + ? WHERE id = ?`
-	_, err = tx.ExecContext(ctx, updateQuery, consumption.Quantity, consumption.Quantity, consumption.WineID)
+	// Le vin passe en "finished" quand la dernière bouteille est bue
+	updateQuery := `
+	UPDATE wines
+	SET quantity = MAX(quantity - ?, 0),
+		consumed = consumed + ?,
+		status = CASE WHEN quantity - ? <= 0 THEN 'finished' ELSE status END,
+		finished_at = CASE WHEN quantity - ? <= 0 THEN COALESCE(finished_at, ?) ELSE finished_at END,
+		version = version + 1
+	WHERE id = ? AND deleted_at IS NULL
+	`
+	q := consumption.Quantity
+	result, err := tx.ExecContext(ctx, updateQuery, fromStock, q, fromStock, fromStock, time.Now(), consumption.WineID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to update wine quantity: %w", err)
 	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return 0, notFound("wine", consumption.WineID)
+	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
@@ -548,7 +717,8 @@ func (s *Store) RecordConsumption(ctx context.Context, consumption *domain.Consu
 
 // GetConsumptionHistory récupère l'historique de dégustation d'un vin
 func (s *Store) GetConsumptionHistory(ctx context.Context, wineID int64) ([]*domain.ConsumptionHistory, error) {
-	query := `SELECT id, wine_id, quantity, rating, comment, reason, date, created_at FROM consumption_history WHERE wine_id = ? ORDER BY date DESC`
+	query := `SELECT id, wine_id, unit_id, quantity, volume_ml, glasses, glass_ml, rating, comment, reason, date, created_at
+	FROM consumption_history WHERE wine_id = ? ORDER BY date DESC`
 	rows, err := s.Db.QueryContext(ctx, query, wineID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query consumption history: %w", err)
@@ -558,7 +728,7 @@ func (s *Store) GetConsumptionHistory(ctx context.Context, wineID int64) ([]*dom
 	history := make([]*domain.ConsumptionHistory, 0)
 	for rows.Next() {
 		h := &domain.ConsumptionHistory{}
-		err := rows.Scan(&h.ID, &h.WineID, &h.Quantity, &h.Rating, &h.Comment, &h.Reason, &h.Date, &h.CreatedAt)
+		err := rows.Scan(&h.ID, &h.WineID, &h.UnitID, &h.Quantity, &h.VolumeML, &h.Glasses, &h.GlassML, &h.Rating, &h.Comment, &h.Reason, &h.Date, &h.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan history: %w", err)
 		}
@@ -568,19 +738,45 @@ func (s *Store) GetConsumptionHistory(ctx context.Context, wineID int64) ([]*dom
 	return history, rows.Err()
 }
 
-// CreateCave crée une nouvelle cave
+// CreateCave crée une nouvelle cave.
+// Si la cave a un agencement, sa capacité en est déduite et ses emplacements sont générés.
 func (s *Store) CreateCave(ctx context.Context, cave *domain.Cave) (int64, error) {
-	query := `INSERT INTO caves (name, model, location, capacity) VALUES (?, ?, ?, ?)`
-	result, err := s.Db.ExecContext(ctx, query, cave.Name, cave.Model, cave.Location, cave.Capacity)
+	layout, err := encodeLayout(cave)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO caves (name, model, location, capacity, layout) VALUES (?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, cave.Name, cave.Model, cave.Location, cave.Capacity, layout)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create cave: %w", err)
 	}
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	if cave.Layout != nil {
+		if err := syncLayoutCells(ctx, tx, id, cave.Layout); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return id, nil
 }
 
 // GetCaves récupère toutes les caves
 func (s *Store) GetCaves(ctx context.Context) ([]*domain.Cave, error) {
-	query := `SELECT id, name, model, location, capacity, current, created_at FROM caves ORDER BY created_at DESC`
+	query := `SELECT ` + caveColumns + ` FROM caves WHERE deleted_at IS NULL ORDER BY created_at DESC`
 	rows, err := s.Db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query caves: %w", err)
@@ -589,8 +785,7 @@ func (s *Store) GetCaves(ctx context.Context) ([]*domain.Cave, error) {
 
 	caves := make([]*domain.Cave, 0)
 	for rows.Next() {
-		cave := &domain.Cave{}
-		err := rows.Scan(&cave.ID, &cave.Name, &cave.Model, &cave.Location, &cave.Capacity, &cave.Current, &cave.CreatedAt)
+		cave, err := scanCave(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan cave: %w", err)
 		}
@@ -600,23 +795,55 @@ func (s *Store) GetCaves(ctx context.Context) ([]*domain.Cave, error) {
 	return caves, rows.Err()
 }
 
-// UpdateCave met à jour une cave existante
+// GetCaveByID récupère une cave par son ID
+func (s *Store) GetCaveByID(ctx context.Context, id int64) (*domain.Cave, error) {
+	query := `SELECT ` + caveColumns + ` FROM caves WHERE id = ? AND deleted_at IS NULL`
+	cave, err := scanCave(s.Db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, notFound("cave", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cave: %w", err)
+	}
+	return cave, nil
+}
+
+// UpdateCave met à jour une cave existante.
+// cave.Version, si non nulle, est la version attendue ; elle reçoit la nouvelle version.
+// Un agencement modifié ajoute ou retire des emplacements ; retirer un emplacement
+// qui contient encore du stock est refusé (ErrConflict).
 func (s *Store) UpdateCave(ctx context.Context, cave *domain.Cave) error {
-	query := `UPDATE caves SET name = ?, model = ?, location = ?, capacity = ? WHERE id = ?`
-	result, err := s.Db.ExecContext(ctx, query, cave.Name, cave.Model, cave.Location, cave.Capacity, cave.ID)
+	layout, err := encodeLayout(cave)
 	if err != nil {
-		return fmt.Errorf("failed to update cave: %w", err)
+		return err
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	query := `UPDATE caves SET name = ?, model = ?, location = ?, capacity = ?, layout = ?, version = version + 1
+	WHERE id = ? AND deleted_at IS NULL` + versionClause + ` RETURNING version`
+	err = tx.QueryRowContext(ctx, query, cave.Name, cave.Model, cave.Location, cave.Capacity, layout,
+		cave.ID, cave.Version, cave.Version).Scan(&cave.Version)
+	if err == sql.ErrNoRows {
+		return staleOrMissing(ctx, tx, "caves", "cave", cave.ID)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to update cave: %w", err)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("cave not found")
+	if cave.Layout != nil {
+		if err := syncLayoutCells(ctx, tx, cave.ID, cave.Layout); err != nil {
+			return err
+		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
@@ -632,7 +859,8 @@ func (s *Store) CreateCell(ctx context.Context, cell *domain.Cell) (int64, error
 
 // GetCellsByCAve récupère les emplacements d'une cave
 func (s *Store) GetCellsByCave(ctx context.Context, caveID int64) ([]*domain.Cell, error) {
-	query := `SELECT id, cave_id, location, capacity, current, created_at FROM cells WHERE cave_id = ? ORDER BY location`
+	query := `SELECT ` + cellColumns + ` FROM cells WHERE cave_id = ? AND deleted_at IS NULL
+	ORDER BY zone IS NOT NULL, zone, rack, row_no, column_no, location`
 	rows, err := s.Db.QueryContext(ctx, query, caveID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query cells: %w", err)
@@ -641,8 +869,7 @@ func (s *Store) GetCellsByCave(ctx context.Context, caveID int64) ([]*domain.Cel
 
 	cells := make([]*domain.Cell, 0)
 	for rows.Next() {
-		cell := &domain.Cell{}
-		err := rows.Scan(&cell.ID, &cell.CaveID, &cell.Location, &cell.Capacity, &cell.Current, &cell.CreatedAt)
+		cell, err := scanCell(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan cell: %w", err)
 		}
@@ -656,66 +883,130 @@ func (s *Store) GetCellsByCave(ctx context.Context, caveID int64) ([]*domain.Cel
 func (s *Store) GetWinesToDrinkNow(ctx context.Context) ([]*domain.Wine, error) {
 	today := time.Now()
 	query := `
-	SELECT id, name, region, vintage, type, quantity, cell_id, producer, alcohol_level, price, current_value, rating, comments, consumed, min_apogee_date, max_apogee_date, consumption_date, created_at
+	SELECT ` + wineColumns + `
 	FROM wines
-	WHERE quantity > 0
+	WHERE quantity > 0 AND status <> 'finished' AND deleted_at IS NULL
 	AND min_apogee_date IS NOT NULL
 	AND min_apogee_date <= ?
 	AND (max_apogee_date IS NULL OR max_apogee_date >= ?)
 	ORDER BY max_apogee_date ASC
 	`
-	rows, err := s.Db.QueryContext(ctx, query, today, today)
+	wines, err := s.queryWines(ctx, query, today, today)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query wines to drink now: %w", err)
 	}
-	defer rows.Close()
-
-	wines := make([]*domain.Wine, 0)
-	for rows.Next() {
-		wine := &domain.Wine{}
-		err := rows.Scan(
-			&wine.ID, &wine.Name, &wine.Region, &wine.Vintage, &wine.WineType, &wine.Quantity, &wine.CellID,
-			&wine.Producer, &wine.AlcoholLevel, &wine.Price, &wine.CurrentValue, &wine.Rating, &wine.Comments, &wine.Consumed,
-			&wine.MinApogeeDate, &wine.MaxApogeeDate, &wine.ConsumptionDate, &wine.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan wine: %w", err)
-		}
-		wines = append(wines, wine)
-	}
-
-	return wines, rows.Err()
+	return wines, nil
 }
 
-// UpdateWine met à jour un vin existant
-func (s *Store) UpdateWine(ctx context.Context, wine *domain.Wine) error {
+// UpdateWine met à jour un vin existant.
+// wine.Version, si non nulle, est la version attendue ; elle reçoit la nouvelle version.
+// Sans overflow, ajouter des bouteilles à un emplacement ou une cave pleine est refusé (ErrConflict).
+func (s *Store) UpdateWine(ctx context.Context, wine *domain.Wine, overflow bool) error {
 	query := `
 	UPDATE wines 
-	SET name=?, region=?, vintage=?, type=?, quantity=?, cell_id=?, 
-		producer=?, alcohol_level=?, price=?, current_value=?, rating=?, comments=?, 
-		consumed=?, min_apogee_date=?, max_apogee_date=?, consumption_date=?
-	WHERE id=?
-	`
+	SET name=?, region=?, appellation_id=?, vintage=?, type=?, quantity=?,
+		format=COALESCE(NULLIF(?, ''), format), volume_ml=COALESCE(NULLIF(?, 0), volume_ml), cell_id=?, 
+		cave_id=COALESCE(?, (SELECT cave_id FROM cells WHERE id = ?)), user_id=?,
+		producer=?, producer_id=?, alcohol_level=?, price=?, current_value=?, rating=?, comments=?, 
+		consumed=?, min_apogee_date=?, max_apogee_date=?, consumption_date=?,
+		bar_code=?, image=?, external_id=?,
+		style=?, ibu=?, ebc=?, packaged_at=?, best_before=?,
+		age_statement=?, distillery=?, cask_type=?, cask_number=?, distilled_year=?, bottled_year=?,
+		cask_strength=?, independent_bottler=?,
+		status=?, finished_at=CASE WHEN ? = 'finished' THEN COALESCE(finished_at, ?) ELSE NULL END,
+		version=version + 1
+	WHERE id=? AND deleted_at IS NULL` + versionClause + ` RETURNING version, format, volume_ml`
+
+	wine.Status = wineStatus(wine.Quantity)
 
-	result, err := s.Db.ExecContext(ctx, query,
-		wine.Name, wine.Region, wine.Vintage, wine.WineType, wine.Quantity, wine.CellID,
-		wine.Producer, wine.AlcoholLevel, wine.Price, wine.CurrentValue, wine.Rating, wine.Comments,
-		wine.Consumed, wine.MinApogeeDate, wine.MaxApogeeDate, wine.ConsumptionDate, wine.ID,
-	)
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
+	before, err := readStock(ctx, tx, "wines", wine.ID)
+	if err != nil {
+		return err
+	}
+	// Seul un nouveau rangement est vérifié : un vin terminé reste modifiable
+	// même si son ancien emplacement est parti à la corbeille
+	if !samePlacement(before.cellID, wine.CellID) || (wine.CaveID != nil && !samePlacement(before.caveID, wine.CaveID)) {
+		if err := checkPlacement(ctx, tx, wine.CaveID, wine.CellID); err != nil {
+			return err
+		}
+	}
+
+	// Un nom de producteur modifié sans changer producer_id désigne un autre producteur
+	var currentProducer, currentFormat string
+	var currentProducerID sql.NullInt64
+	var currentVolume int
+	if err := tx.QueryRowContext(ctx, `SELECT producer, producer_id, format, volume_ml FROM wines WHERE id = ?`, wine.ID).
+		Scan(&currentProducer, &currentProducerID, &currentFormat, &currentVolume); err != nil {
+		return fmt.Errorf("failed to query wine producer: %w", err)
+	}
+	if wine.ProducerID != nil && currentProducerID.Valid && *wine.ProducerID == currentProducerID.Int64 &&
+		strings.TrimSpace(wine.Producer) != currentProducer {
+		wine.ProducerID = nil
+	}
+	if err := resolveProducer(ctx, tx, wine); err != nil {
+		return err
+	}
+	if err := resolveAppellation(wine); err != nil {
+		return err
+	}
+	// Un volume modifié sans changer de format désigne le format correspondant (ou custom).
+	// Sans format ni volume, le format enregistré est conservé.
+	if wine.Format == currentFormat && wine.VolumeML != 0 && wine.VolumeML != currentVolume {
+		wine.Format = ""
+	}
+	if err := resolveFormat(wine); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query,
+		wine.Name, wine.Region, wine.AppellationID, wine.Vintage, bottleType(wine), wine.Quantity,
+		wine.Format, wine.VolumeML, wine.CellID,
+		wine.CaveID, wine.CellID, wine.UserID,
+		wine.Producer, wine.ProducerID, wine.AlcoholLevel, wine.Price, wine.CurrentValue, wine.Rating, wine.Comments,
+		wine.Consumed, wine.MinApogeeDate, wine.MaxApogeeDate, wine.ConsumptionDate,
+		wine.BarCode, wine.Image, wine.ExternalID,
+		wine.Style, wine.IBU, wine.EBC, wine.PackagedAt, wine.BestBefore,
+		wine.AgeStatement, wine.Distillery, wine.CaskType, wine.CaskNumber, wine.DistilledYear, wine.BottledYear,
+		wine.CaskStrength, wine.IndependentBottler,
+		wine.Status, wine.Status, time.Now(), wine.ID, wine.Version, wine.Version,
+	).Scan(&wine.Version, &wine.Format, &wine.VolumeML)
+	if err == sql.ErrNoRows {
+		return staleOrMissing(ctx, tx, "wines", "wine", wine.ID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update wine: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if err := saveBlend(ctx, tx, wine.ID, wine.Blend); err != nil {
+		return err
+	}
+
+	after, err := readStock(ctx, tx, "wines", wine.ID)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return err
+	}
+	if !overflow {
+		if err := checkCapacity(ctx, tx, before, after); err != nil {
+			return err
+		}
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("wine not found with id %d", wine.ID)
+	// Un changement d'emplacement par édition reste tracé dans l'historique des déplacements
+	if moved := min(before.quantity, after.quantity); moved > 0 && (before.cellID != after.cellID || before.caveID != after.caveID) {
+		if _, err := recordMovement(ctx, tx, wine.ID, wine.ID, nil, moved, before, after, "edited"); err != nil {
+			return err
+		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
@@ -742,10 +1033,15 @@ func (s *Store) UpdateAlert(ctx context.Context, alertID int64, status string) e
 	return nil
 }
 
+// bestBeforeWarningDays est le délai avant la date de durabilité minimale à partir duquel on alerte
+const bestBeforeWarningDays = 30
+
 // GenerateAlerts crée automatiquement des alertes basées sur les conditions des vins
 // - Alerte low_stock si quantité < 2 (ou seuil configurable)
 // - Alerte apogee_reached si date d'aujourd'hui >= min_apogee_date
 // - Alerte apogee_ended si date d'aujourd'hui > max_apogee_date
+// - Alerte best_before_approaching si la date de durabilité minimale est à moins de 30 jours
+// - Alerte opened_too_long si une bouteille ouverte dépasse sa durée de conservation
 func (s *Store) GenerateAlerts(ctx context.Context) error {
 	today := time.Now()
 	lowStockThreshold := 2
@@ -757,6 +1053,11 @@ func (s *Store) GenerateAlerts(ctx context.Context) error {
 	}
 
 	for _, wine := range wines {
+		// Les vins terminés sont archivés : pas d'alerte
+		if wine.Status == domain.BottleStatusFinished {
+			continue
+		}
+
 		// Vérifier si une alerte existe déjà pour ce vin
 		existingAlerts, err := s.GetAlertsByWineID(ctx, wine.ID)
 		if err != nil {
@@ -811,9 +1112,23 @@ func (s *Store) GenerateAlerts(ctx context.Context) error {
 				return fmt.Errorf("failed to create apogee_ended alert for wine %d: %w", wine.ID, err)
 			}
 		}
+
+		// Alerte best_before_approaching (bières)
+		if wine.BestBefore != nil && wine.BestBefore.Before(today.AddDate(0, 0, bestBeforeWarningDays)) && !existingAlertTypes["best_before_approaching"] {
+			alert := &domain.Alert{
+				WineID:    wine.ID,
+				AlertType: "best_before_approaching",
+				Status:    "active",
+				CreatedAt: time.Now(),
+			}
+			_, err := s.CreateAlert(ctx, alert)
+			if err != nil {
+				return fmt.Errorf("failed to create best_before_approaching alert for wine %d: %w", wine.ID, err)
+			}
+		}
 	}
 
-	return nil
+	return s.generateOpenedAlerts(ctx)
 }
 
 // GetAlertsByWineID récupère toutes les alertes pour un vin donné
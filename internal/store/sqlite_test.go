@@ -0,0 +1,17 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newTestStore ouvre une base migrée dans un répertoire temporaire
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := New(filepath.Join(t.TempDir(), "glou.db"))
+	if err != nil {
+		t.Fatalf("failed to open test store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
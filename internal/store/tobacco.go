@@ -9,95 +9,193 @@ import (
 	"github.com/romain/glou-server/internal/domain"
 )
 
-// CreateTobacco inserts a new tobacco product
-func (s *Store) CreateTobacco(ctx context.Context, t *domain.Tobacco) (int64, error) {
+// CreateTobacco inserts a new tobacco product.
+// The cave is derived from the cell when not given. Unless overflow is set,
+// placing units into a full cell or cave is rejected (ErrConflict).
+func (s *Store) CreateTobacco(ctx context.Context, t *domain.Tobacco, overflow bool) (int64, error) {
 	query := `
 	INSERT INTO tobaccos (name, brand, purchase_date, quantity, purchase_price, current_value, cave_id, cell_id, notes, origin_country, format, wrapper, binder, created_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	VALUES (?, ?, ?, ?, ?, ?, COALESCE(?, (SELECT cave_id FROM cells WHERE id = ?)), ?, ?, ?, ?, ?, ?, ?)
 	`
-	result, err := s.Db.ExecContext(ctx, query,
-		t.Name, t.Brand, t.PurchaseDate, t.Quantity, t.PurchasePrice, t.CurrentValue, t.CaveID, t.CellID, t.Notes, t.OriginCountry, t.Format, t.Wrapper, t.Binder, time.Now(),
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query,
+		t.Name, t.Brand, t.PurchaseDate, t.Quantity, t.PurchasePrice, t.CurrentValue, t.CaveID, t.CellID, t.CellID, t.Notes, t.OriginCountry, t.Format, t.Wrapper, t.Binder, time.Now(),
 	)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create tobacco: %w", err)
 	}
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	if !overflow {
+		placed, err := readStock(ctx, tx, "tobaccos", id)
+		if err != nil {
+			return 0, err
+		}
+		if err := checkCapacity(ctx, tx, stock{}, placed); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return id, nil
+}
+
+// tobaccoColumns lists the columns read by scanTobacco, in order
+const tobaccoColumns = `id, name, brand, purchase_date, quantity, purchase_price, current_value, cave_id, cell_id, notes, origin_country, format, wrapper, binder, created_at, version`
+
+// scanTobacco reads a row selected with tobaccoColumns
+func scanTobacco(row rowScanner) (*domain.Tobacco, error) {
+	var t domain.Tobacco
+	if err := row.Scan(&t.ID, &t.Name, &t.Brand, &t.PurchaseDate, &t.Quantity, &t.PurchasePrice, &t.CurrentValue, &t.CaveID, &t.CellID, &t.Notes, &t.OriginCountry, &t.Format, &t.Wrapper, &t.Binder, &t.CreatedAt, &t.Version); err != nil {
+		return nil, err
+	}
+	return &t, nil
 }
 
 // GetTobaccos fetches all tobacco products
 func (s *Store) GetTobaccos(ctx context.Context) ([]*domain.Tobacco, error) {
-	query := `
-	SELECT id, name, brand, purchase_date, quantity, purchase_price, current_value, cave_id, cell_id, notes, origin_country, format, wrapper, binder, created_at
-	FROM tobaccos
-	ORDER BY created_at DESC
-	`
-	rows, err := s.Db.QueryContext(ctx, query)
+	items, _, err := s.ListTobaccos(ctx, ListOptions{})
+	return items, err
+}
+
+// tobaccoListSpec describes the sorts and filters accepted by ListTobaccos
+var tobaccoListSpec = listSpec{
+	idColumn: "id",
+	sortable: map[string]string{
+		"name":       "name COLLATE NOCASE",
+		"price":      "purchase_price",
+		"created_at": "created_at",
+	},
+	defaultSort: "-created_at",
+	filters: map[string]string{
+		"price":   "purchase_price",
+		"cave_id": "cave_id",
+		"cell_id": "cell_id",
+	},
+}
+
+// ListTobaccos fetches a filtered, sorted page of tobacco products (cursor pagination)
+func (s *Store) ListTobaccos(ctx context.Context, opts ListOptions) ([]*domain.Tobacco, *PageInfo, error) {
+	q, err := tobaccoListSpec.build(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query tobaccos: %w", err)
+		return nil, nil, err
+	}
+
+	query := `SELECT ` + tobaccoColumns + `, ` + q.sortSelect() + ` FROM tobaccos WHERE deleted_at IS NULL` + q.whereClause() + q.orderLimit()
+	rows, err := s.Db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query tobaccos: %w", err)
 	}
 	defer rows.Close()
 
-	var items []*domain.Tobacco
+	items := make([]*domain.Tobacco, 0)
+	var keys []interface{}
+	var ids []int64
 	for rows.Next() {
-		var t domain.Tobacco
-		if err := rows.Scan(&t.ID, &t.Name, &t.Brand, &t.PurchaseDate, &t.Quantity, &t.PurchasePrice, &t.CurrentValue, &t.CaveID, &t.CellID, &t.Notes, &t.OriginCountry, &t.Format, &t.Wrapper, &t.Binder, &t.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan tobacco: %w", err)
+		var key interface{}
+		t, err := scanTobacco(sortKeyScanner{row: rows, key: &key})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan tobacco: %w", err)
 		}
-		items = append(items, &t)
+		items = append(items, t)
+		keys = append(keys, key)
+		ids = append(ids, t.ID)
 	}
-	return items, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to iterate tobaccos: %w", err)
+	}
+
+	n, info := q.pageInfo(len(items), keys, ids)
+	return items[:n], info, nil
 }
 
 // GetTobaccoByID fetches a single tobacco product
 func (s *Store) GetTobaccoByID(ctx context.Context, id int64) (*domain.Tobacco, error) {
-	query := `
-	SELECT id, name, brand, purchase_date, quantity, purchase_price, current_value, cave_id, cell_id, notes, origin_country, format, wrapper, binder, created_at
-	FROM tobaccos WHERE id = ?
-	`
-	var t domain.Tobacco
-	err := s.Db.QueryRowContext(ctx, query, id).Scan(&t.ID, &t.Name, &t.Brand, &t.PurchaseDate, &t.Quantity, &t.PurchasePrice, &t.CurrentValue, &t.CaveID, &t.CellID, &t.Notes, &t.OriginCountry, &t.Format, &t.Wrapper, &t.Binder, &t.CreatedAt)
+	query := `SELECT ` + tobaccoColumns + ` FROM tobaccos WHERE id = ? AND deleted_at IS NULL`
+	t, err := scanTobacco(s.Db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("tobacco not found with id %d", id)
+		return nil, notFound("tobacco", id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tobacco: %w", err)
 	}
-	return &t, nil
+	return t, nil
 }
 
-// UpdateTobacco updates an existing tobacco product
-func (s *Store) UpdateTobacco(ctx context.Context, t *domain.Tobacco) error {
+// UpdateTobacco updates an existing tobacco product.
+// A non-zero t.Version is the expected version; it receives the new version.
+// Unless overflow is set, adding units to a full cell or cave is rejected (ErrConflict).
+func (s *Store) UpdateTobacco(ctx context.Context, t *domain.Tobacco, overflow bool) error {
 	query := `
-	UPDATE tobaccos SET name=?, brand=?, purchase_date=?, quantity=?, purchase_price=?, current_value=?, cave_id=?, cell_id=?, notes=?, origin_country=?, format=?, wrapper=?, binder=?
-	WHERE id=?
-	`
-	result, err := s.Db.ExecContext(ctx, query, t.Name, t.Brand, t.PurchaseDate, t.Quantity, t.PurchasePrice, t.CurrentValue, t.CaveID, t.CellID, t.Notes, t.OriginCountry, t.Format, t.Wrapper, t.Binder, t.ID)
+	UPDATE tobaccos SET name=?, brand=?, purchase_date=?, quantity=?, purchase_price=?, current_value=?,
+		cave_id=COALESCE(?, (SELECT cave_id FROM cells WHERE id = ?)), cell_id=?, notes=?, origin_country=?, format=?, wrapper=?, binder=?, version=version + 1
+	WHERE id=? AND deleted_at IS NULL` + versionClause + ` RETURNING version`
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := readStock(ctx, tx, "tobaccos", t.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, t.Name, t.Brand, t.PurchaseDate, t.Quantity, t.PurchasePrice, t.CurrentValue, t.CaveID, t.CellID, t.CellID, t.Notes, t.OriginCountry, t.Format, t.Wrapper, t.Binder, t.ID, t.Version, t.Version).Scan(&t.Version)
+	if err == sql.ErrNoRows {
+		return staleOrMissing(ctx, tx, "tobaccos", "tobacco", t.ID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update tobacco: %w", err)
 	}
-	if rows, _ := result.RowsAffected(); rows == 0 {
-		return fmt.Errorf("tobacco not found with id %d", t.ID)
+
+	if !overflow {
+		after, err := readStock(ctx, tx, "tobaccos", t.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkCapacity(ctx, tx, before, after); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return nil
 }
 
-// DeleteTobacco deletes a tobacco product (or decrements quantity)
-func (s *Store) DeleteTobacco(ctx context.Context, id int64) error {
+// DeleteTobacco decrements the quantity of a tobacco product.
+// The last unit moves the product to the trash (see SoftDelete).
+// A non-zero version is the expected version (If-Match).
+func (s *Store) DeleteTobacco(ctx context.Context, id int64, version int64) error {
 	// Fetch current
 	item, err := s.GetTobaccoByID(ctx, id)
 	if err != nil {
 		return err
 	}
 	if item.Quantity <= 1 {
-		_, err := s.Db.ExecContext(ctx, `DELETE FROM tobaccos WHERE id = ?`, id)
-		if err != nil {
-			return fmt.Errorf("failed to delete tobacco: %w", err)
-		}
-		return nil
+		return s.SoftDelete(ctx, TrashTobacco, id, version)
 	}
-	_, err = s.Db.ExecContext(ctx, `UPDATE tobaccos SET quantity = quantity - 1 WHERE id = ?`, id)
+	result, err := s.Db.ExecContext(ctx,
+		`UPDATE tobaccos SET quantity = quantity - 1, version = version + 1 WHERE id = ? AND deleted_at IS NULL`+versionClause,
+		id, version, version)
 	if err != nil {
 		return fmt.Errorf("failed to decrement tobacco: %w", err)
 	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return staleOrMissing(ctx, s.Db, "tobaccos", "tobacco", id)
+	}
 	return nil
 }
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// Types d'éléments gérés par la corbeille
+const (
+	TrashWine    = "wine"
+	TrashTobacco = "tobacco"
+	TrashCave    = "cave"
+	TrashCell    = "cell"
+)
+
+// DefaultTrashRetentionDays est la durée de conservation par défaut de la corbeille
+const DefaultTrashRetentionDays = 30
+
+// trashTables associe chaque type de la corbeille à sa table
+var trashTables = map[string]string{
+	TrashWine:    "wines",
+	TrashTobacco: "tobaccos",
+	TrashCave:    "caves",
+	TrashCell:    "cells",
+}
+
+// versionedTables liste les tables ayant une colonne version (ETag)
+var versionedTables = map[string]bool{
+	"wines":    true,
+	"tobaccos": true,
+	"caves":    true,
+}
+
+// IsTrashType indique si le type est géré par la corbeille
+func IsTrashType(entityType string) bool {
+	_, ok := trashTables[entityType]
+	return ok
+}
+
+// SoftDelete place un élément dans la corbeille (deleted_at).
+// Une cave ou un emplacement contenant encore du stock ne peut pas être supprimé ;
+// les emplacements d'une cave partent à la corbeille avec elle.
+// version, si non nulle, est la version attendue (If-Match) ; les emplacements ne sont pas versionnés.
+func (s *Store) SoftDelete(ctx context.Context, entityType string, id int64, version int64) error {
+	table, ok := trashTables[entityType]
+	if !ok {
+		return NewValidationError("type", fmt.Sprintf("unknown trash type %q", entityType))
+	}
+	if version != 0 && !versionedTables[table] {
+		return NewValidationError("version", fmt.Sprintf("%s is not versioned", entityType))
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Refuser si la cave / l'emplacement contient encore du stock
+	if entityType == TrashCave || entityType == TrashCell {
+		column := "cave_id"
+		if entityType == TrashCell {
+			column = "cell_id"
+		}
+		// stored_items compte chaque bouteille à sa place, y compris celles déplacées une à une
+		var stock int
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(
+			`SELECT COALESCE(SUM(quantity), 0) FROM stored_items WHERE %s = ?`, column), id).Scan(&stock)
+		if err != nil {
+			return fmt.Errorf("failed to check %s contents: %w", entityType, err)
+		}
+		if stock > 0 {
+			return fmt.Errorf("%w: %s %d still holds %d item(s)", ErrConflict, entityType, id, stock)
+		}
+	}
+
+	now := time.Now()
+	query := fmt.Sprintf(`UPDATE %s SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, table)
+	args := []interface{}{now, id}
+	if versionedTables[table] {
+		query = fmt.Sprintf(`UPDATE %s SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL`, table) + versionClause
+		args = append(args, version, version)
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", entityType, err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return staleOrMissing(ctx, tx, table, entityType, id)
+	}
+
+	if entityType == TrashCave {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE cells SET deleted_at = ? WHERE cave_id = ? AND deleted_at IS NULL`, now, id); err != nil {
+			return fmt.Errorf("failed to delete cave cells: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Restore sort un élément de la corbeille.
+// Restaurer une cave restaure aussi les emplacements supprimés avec elle.
+func (s *Store) Restore(ctx context.Context, entityType string, id int64) error {
+	table, ok := trashTables[entityType]
+	if !ok {
+		return NewValidationError("type", fmt.Sprintf("unknown trash type %q", entityType))
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT deleted_at FROM %s WHERE id = ?`, table), id).Scan(&deletedAt)
+	if err == sql.ErrNoRows || (err == nil && !deletedAt.Valid) {
+		return fmt.Errorf("%w: %s %d is not in the trash", ErrNotFound, entityType, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", entityType, err)
+	}
+
+	// Un vin ou un tabac ne peut pas revenir dans un emplacement ou une cave supprimés
+	if entityType == TrashWine || entityType == TrashTobacco {
+		var parentDeleted int
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*) FROM %s t
+		LEFT JOIN cells ce ON ce.id = t.cell_id
+		LEFT JOIN caves c ON c.id = t.cave_id
+		WHERE t.id = ? AND (ce.deleted_at IS NOT NULL OR c.deleted_at IS NOT NULL)
+		`, table), id).Scan(&parentDeleted)
+		if err != nil {
+			return fmt.Errorf("failed to check %s placement: %w", entityType, err)
+		}
+		if parentDeleted > 0 {
+			return fmt.Errorf("%w: restore the cell or cave of %s %d first", ErrConflict, entityType, id)
+		}
+	}
+
+	// Un emplacement ne peut pas revenir dans une cave supprimée
+	if entityType == TrashCell {
+		var caveDeleted int
+		err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM caves c JOIN cells ce ON ce.cave_id = c.id
+		WHERE ce.id = ? AND c.deleted_at IS NOT NULL
+		`, id).Scan(&caveDeleted)
+		if err != nil {
+			return fmt.Errorf("failed to check cell cave: %w", err)
+		}
+		if caveDeleted > 0 {
+			return fmt.Errorf("%w: restore the cave of cell %d first", ErrConflict, id)
+		}
+	}
+
+	if entityType == TrashCave {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE cells SET deleted_at = NULL WHERE cave_id = ? AND deleted_at = (SELECT deleted_at FROM caves WHERE id = ?)`,
+			id, id); err != nil {
+			return fmt.Errorf("failed to restore cave cells: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		fmt.Sprintf(`UPDATE %s SET deleted_at = NULL WHERE id = ?`, table), id); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", entityType, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// trashRetention retourne la durée de conservation configurée
+func (s *Store) trashRetention(ctx context.Context) time.Duration {
+	days := DefaultTrashRetentionDays
+	if settings, err := s.GetSettings(ctx); err == nil && settings.TrashRetentionDays > 0 {
+		days = settings.TrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ListTrash liste les éléments de la corbeille, du plus récent au plus ancien.
+// Les emplacements supprimés avec leur cave n'apparaissent pas séparément.
+func (s *Store) ListTrash(ctx context.Context) ([]*domain.TrashItem, error) {
+	query := `
+	SELECT 'wine', id, name, deleted_at FROM wines WHERE deleted_at IS NOT NULL
+	UNION ALL
+	SELECT 'tobacco', id, name, deleted_at FROM tobaccos WHERE deleted_at IS NOT NULL
+	UNION ALL
+	SELECT 'cave', id, name, deleted_at FROM caves WHERE deleted_at IS NOT NULL
+	UNION ALL
+	SELECT 'cell', ce.id, ce.location, ce.deleted_at FROM cells ce
+	WHERE ce.deleted_at IS NOT NULL
+	AND NOT EXISTS (SELECT 1 FROM caves c WHERE c.id = ce.cave_id AND c.deleted_at = ce.deleted_at)
+	ORDER BY 4 DESC
+	`
+	rows, err := s.Db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trash: %w", err)
+	}
+	defer rows.Close()
+
+	retention := s.trashRetention(ctx)
+	items := make([]*domain.TrashItem, 0)
+	for rows.Next() {
+		item := &domain.TrashItem{}
+		if err := rows.Scan(&item.Type, &item.ID, &item.Name, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan trash item: %w", err)
+		}
+		item.PurgeAt = item.DeletedAt.Add(retention)
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// PurgeTrash supprime définitivement les éléments mis à la corbeille avant `before`,
+// avec leurs données dépendantes (historique, alertes, déplacements, lots d'achat). Retourne le nombre d'éléments purgés.
+func (s *Store) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Les clés étrangères ne sont pas appliquées par SQLite : nettoyage explicite
+	steps := []string{
+		`DELETE FROM consumption_history WHERE wine_id IN (SELECT id FROM wines WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`DELETE FROM alerts WHERE wine_id IN (SELECT id FROM wines WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`DELETE FROM bottle_movements WHERE target_wine_id IN (SELECT id FROM wines WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`DELETE FROM purchase_lots WHERE item_type = 'wine' AND item_id IN (SELECT id FROM wines WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`DELETE FROM wine_grapes WHERE wine_id IN (SELECT id FROM wines WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`DELETE FROM tobacco_alerts WHERE tobacco_id IN (SELECT id FROM tobaccos WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`DELETE FROM purchase_lots WHERE item_type = 'tobacco' AND item_id IN (SELECT id FROM tobaccos WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`UPDATE wines SET cell_id = NULL WHERE cell_id IN (SELECT id FROM cells WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`UPDATE bottle_units SET cell_id = NULL WHERE cell_id IN (SELECT id FROM cells WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`UPDATE tobaccos SET cell_id = NULL WHERE cell_id IN (SELECT id FROM cells WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`UPDATE wines SET cave_id = NULL WHERE cave_id IN (SELECT id FROM caves WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`UPDATE bottle_units SET cave_id = NULL WHERE cave_id IN (SELECT id FROM caves WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+		`UPDATE tobaccos SET cave_id = NULL WHERE cave_id IN (SELECT id FROM caves WHERE deleted_at IS NOT NULL AND deleted_at < ?)`,
+	}
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step, before); err != nil {
+			return 0, fmt.Errorf("failed to purge trash: %w", err)
+		}
+	}
+
+	var purged int64
+	for _, table := range []string{"wines", "tobaccos", "cells", "caves"} {
+		result, err := tx.ExecContext(ctx,
+			fmt.Sprintf(`DELETE FROM %s WHERE deleted_at IS NOT NULL AND deleted_at < ?`, table), before)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+		n, _ := result.RowsAffected()
+		purged += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return purged, nil
+}
+
+// PurgeExpiredTrash purge les éléments dont la durée de rétention est écoulée
+func (s *Store) PurgeExpiredTrash(ctx context.Context) (int64, error) {
+	return s.PurgeTrash(ctx, time.Now().Add(-s.trashRetention(ctx)))
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// TrashPurger purge périodiquement la corbeille selon Settings.TrashRetentionDays
+type TrashPurger struct {
+	store    *Store
+	ticker   *time.Ticker
+	stopChan chan struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+}
+
+// NewTrashPurger crée un TrashPurger
+func NewTrashPurger(store *Store) *TrashPurger {
+	return &TrashPurger{
+		store:    store,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start lance la purge immédiatement puis à chaque intervalle
+func (tp *TrashPurger) Start(interval time.Duration) {
+	tp.mu.Lock()
+	tp.ticker = time.NewTicker(interval)
+	tp.done = make(chan struct{})
+	tp.mu.Unlock()
+
+	go func() {
+		defer close(tp.done)
+
+		tp.purge()
+		for {
+			select {
+			case <-tp.ticker.C:
+				tp.purge()
+			case <-tp.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// purge exécute une passe de purge et la journalise
+func (tp *TrashPurger) purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	purged, err := tp.store.PurgeExpiredTrash(ctx)
+	if err != nil {
+		log.Printf("Error purging trash: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d item(s) from trash", purged)
+		tp.store.LogActivity(ctx, "trash", 0, "trash_purged", map[string]int64{"count": purged}, "")
+	}
+}
+
+// Stop arrête la purge périodique
+func (tp *TrashPurger) Stop() {
+	tp.mu.Lock()
+	if tp.ticker != nil {
+		tp.ticker.Stop()
+		tp.ticker = nil
+	}
+	tp.mu.Unlock()
+
+	close(tp.stopChan)
+
+	if tp.done != nil {
+		<-tp.done
+	}
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+func TestSoftDeleteRefusesContainerHoldingMovedBottles(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	caveA, err := s.CreateCave(ctx, &domain.Cave{Name: "A", Capacity: 10})
+	if err != nil {
+		t.Fatalf("CreateCave: %v", err)
+	}
+	caveB, err := s.CreateCave(ctx, &domain.Cave{Name: "B", Capacity: 10})
+	if err != nil {
+		t.Fatalf("CreateCave: %v", err)
+	}
+	cellB, err := s.CreateCell(ctx, &domain.Cell{CaveID: caveB, Location: "B1", Capacity: 5})
+	if err != nil {
+		t.Fatalf("CreateCell: %v", err)
+	}
+	wineID, err := s.CreateWine(ctx, &domain.Wine{Name: "Margaux", BottleType: "red_wine", Region: "Bordeaux", Vintage: 2015, Quantity: 2, CaveID: &caveA}, false)
+	if err != nil {
+		t.Fatalf("CreateWine: %v", err)
+	}
+
+	// Une seule bouteille part dans cellB : l'entrée de vin reste rattachée à la cave A
+	units, err := s.GetWineUnits(ctx, wineID, domain.UnitStateInStock)
+	if err != nil || len(units) != 2 {
+		t.Fatalf("expected 2 units, got %d (%v)", len(units), err)
+	}
+	if _, err := s.MoveUnit(ctx, wineID, units[0].ID, &caveB, &cellB, "", 0, false); err != nil {
+		t.Fatalf("MoveUnit: %v", err)
+	}
+
+	if err := s.SoftDelete(ctx, TrashCell, cellB, 0); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected a conflict trashing a cell holding a moved bottle, got %v", err)
+	}
+	if err := s.SoftDelete(ctx, TrashCave, caveB, 0); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected a conflict trashing a cave holding a moved bottle, got %v", err)
+	}
+}
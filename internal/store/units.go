@@ -0,0 +1,365 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+// Le suivi à la bouteille complète le compteur wines.quantity (migration 0012) :
+// les écritures au compteur créent ou consomment des bouteilles par triggers, et les
+// opérations à la bouteille ci-dessous recalculent le compteur avec refreshWineQuantity.
+
+// unitColumns lit une bouteille avec l'emplacement où elle est rangée (alias u)
+const unitColumns = `u.id, u.wine_id, u.cell_id, u.cave_id, COALESCE(c.location, ''), u.lot, u.purchase_price,
+	u.fill_level, u.state, u.remaining_ml, u.opened_at, u.preservation, COALESCE(u.barcode, ''),
+	u.state_changed_at, u.created_at
+	FROM bottle_units u
+	LEFT JOIN cells c ON c.id = u.cell_id`
+
+// scanUnit lit une ligne sélectionnée avec unitColumns
+func scanUnit(row rowScanner) (*domain.BottleUnit, error) {
+	u := &domain.BottleUnit{}
+	if err := row.Scan(&u.ID, &u.WineID, &u.CellID, &u.CaveID, &u.Location, &u.Lot, &u.PurchasePrice,
+		&u.FillLevel, &u.State, &u.RemainingML, &u.OpenedAt, &u.Preservation, &u.Barcode, &u.StateChangedAt, &u.CreatedAt); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// unitStock est la place occupée par une bouteille (rien si elle n'est pas en stock)
+func unitStock(u *domain.BottleUnit) stock {
+	st := stock{}
+	if u.CellID != nil {
+		st.cellID = sql.NullInt64{Int64: *u.CellID, Valid: true}
+	}
+	if u.CaveID != nil {
+		st.caveID = sql.NullInt64{Int64: *u.CaveID, Valid: true}
+	}
+	if u.State == domain.UnitStateInStock {
+		st.quantity = 1
+	}
+	return st
+}
+
+// refreshWineQuantity aligne le compteur d'une entrée (et son statut) sur ses bouteilles en stock
+func refreshWineQuantity(ctx context.Context, tx *sql.Tx, wineID int64) error {
+	_, err := tx.ExecContext(ctx, `
+	UPDATE wines SET quantity = units,
+		status = CASE WHEN units > 0 THEN 'in_stock' ELSE 'finished' END,
+		finished_at = CASE WHEN units > 0 THEN NULL ELSE COALESCE(finished_at, ?) END,
+		version = version + 1
+	FROM (SELECT COUNT(*) AS units FROM bottle_units WHERE wine_id = ? AND state = 'in_stock')
+	WHERE id = ? AND quantity <> units`, time.Now(), wineID, wineID)
+	if err != nil {
+		return fmt.Errorf("failed to refresh wine quantity: %w", err)
+	}
+	return nil
+}
+
+// splitWine détache quantity bouteilles en stock d'une entrée vers une nouvelle entrée
+// du même vin rangée en `to` (sans consommation), avec leur part des lots d'achat.
+// Les bouteilles rangées à la position de l'entrée partent en premier. Retourne l'ID
+// de la nouvelle entrée.
+func splitWine(ctx context.Context, tx *sql.Tx, wine *domain.Wine, quantity int, to stock) (int64, error) {
+	split := *wine
+	split.Quantity = 0
+	split.CaveID = nullableID(to.caveID)
+	split.CellID = nullableID(to.cellID)
+	split.Consumed = 0
+	split.ConsumptionDate = nil
+	splitID, err := insertWine(ctx, tx, &split)
+	if err != nil {
+		return 0, err
+	}
+
+	var inStock int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM bottle_units WHERE wine_id = ? AND state = 'in_stock'`, wine.ID).Scan(&inStock); err != nil {
+		return 0, fmt.Errorf("failed to count bottle units: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE bottle_units SET wine_id = ?, cell_id = ?, cave_id = ?
+	WHERE id IN (
+		SELECT id FROM bottle_units WHERE wine_id = ? AND state = 'in_stock'
+		ORDER BY cell_id IS ? DESC, id DESC LIMIT ?
+	)`, splitID, to.cellID, to.caveID, wine.ID, wine.CellID, quantity)
+	if err != nil {
+		return 0, fmt.Errorf("failed to split wine: %w", err)
+	}
+	if n, _ := result.RowsAffected(); int(n) != quantity {
+		return 0, fmt.Errorf("%w: wine %d only has %d bottles in stock", ErrConflict, wine.ID, n)
+	}
+
+	// Les bouteilles détachées emportent leur part des lots d'achat
+	if err := moveLots(ctx, tx, wine.ID, splitID, inStock, quantity); err != nil {
+		return 0, err
+	}
+
+	for _, id := range []int64{wine.ID, splitID} {
+		if err := refreshWineQuantity(ctx, tx, id); err != nil {
+			return 0, err
+		}
+	}
+	return splitID, nil
+}
+
+// GetWineUnits liste les bouteilles d'une entrée, éventuellement filtrées par état
+func (s *Store) GetWineUnits(ctx context.Context, wineID int64, state string) ([]*domain.BottleUnit, error) {
+	if _, err := s.GetWineByID(ctx, wineID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + unitColumns + ` WHERE u.wine_id = ?`
+	args := []interface{}{wineID}
+	if state != "" {
+		query += ` AND u.state = ?`
+		args = append(args, state)
+	}
+	query += ` ORDER BY u.state <> 'in_stock', u.id`
+
+	rows, err := s.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bottle units: %w", err)
+	}
+	defer rows.Close()
+
+	units := make([]*domain.BottleUnit, 0)
+	for rows.Next() {
+		u, err := scanUnit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bottle unit: %w", err)
+		}
+		units = append(units, u)
+	}
+	return units, rows.Err()
+}
+
+// GetUnit retourne une bouteille (d'une entrée qui n'est pas à la corbeille)
+func (s *Store) GetUnit(ctx context.Context, id int64) (*domain.BottleUnit, error) {
+	return getUnit(ctx, s.Db, `u.id = ?`, id)
+}
+
+// GetUnitByBarcode retrouve une bouteille par son code-barres ou QR code
+func (s *Store) GetUnitByBarcode(ctx context.Context, barcode string) (*domain.BottleUnit, error) {
+	return getUnit(ctx, s.Db, `u.barcode = ?`, barcode)
+}
+
+// getUnit lit une bouteille selon une condition sur l'alias u
+func getUnit(ctx context.Context, db queryRower, where string, arg interface{}) (*domain.BottleUnit, error) {
+	u, err := scanUnit(db.QueryRowContext(ctx, `SELECT `+unitColumns+`
+	WHERE `+where+` AND u.wine_id IN (SELECT id FROM wines WHERE deleted_at IS NULL)`, arg))
+	if err == sql.ErrNoRows {
+		if id, ok := arg.(int64); ok {
+			return nil, notFound("bottle unit", id)
+		}
+		return nil, fmt.Errorf("%w: bottle unit %v", ErrNotFound, arg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bottle unit: %w", err)
+	}
+	return u, nil
+}
+
+// AddUnit ajoute une bouteille en stock à une entrée (le compteur augmente d'une unité).
+// Sans position, la bouteille est rangée avec l'entrée.
+func (s *Store) AddUnit(ctx context.Context, wineID int64, unit *domain.BottleUnit, overflow bool) (*domain.BottleUnit, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	line, err := readStock(ctx, tx, "wines", wineID)
+	if err != nil {
+		return nil, err
+	}
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM wines WHERE id = ? AND deleted_at IS NULL`, wineID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, notFound("wine", wineID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wine: %w", err)
+	}
+	var inStock int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM bottle_units WHERE wine_id = ? AND state = 'in_stock'`, wineID).Scan(&inStock); err != nil {
+		return nil, fmt.Errorf("failed to count bottle units: %w", err)
+	}
+	if inStock >= domain.MaxBottleQuantity {
+		return nil, NewValidationError("quantity", fmt.Sprintf("quantity cannot exceed %d", domain.MaxBottleQuantity))
+	}
+
+	to := stock{cellID: line.cellID, caveID: line.caveID, quantity: 1}
+	if unit.CellID != nil || unit.CaveID != nil {
+		if to, err = moveDestination(ctx, tx, unit.CaveID, unit.CellID); err != nil {
+			return nil, err
+		}
+		to.quantity = 1
+	}
+
+	result, err := tx.ExecContext(ctx, `
+	INSERT INTO bottle_units (wine_id, cell_id, cave_id, lot, purchase_price, fill_level, state, barcode, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, 'in_stock', ?, ?)`,
+		wineID, to.cellID, to.caveID, unit.Lot, unit.PurchasePrice, unit.FillLevel, nullIfEmpty(unit.Barcode), time.Now())
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: barcode %s is already used by another bottle", ErrConflict, unit.Barcode)
+		}
+		return nil, fmt.Errorf("failed to create bottle unit: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	if err := refreshWineQuantity(ctx, tx, wineID); err != nil {
+		return nil, err
+	}
+	if !overflow {
+		if err := checkCapacity(ctx, tx, stock{}, to); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetUnit(ctx, id)
+}
+
+// UpdateUnit modifie le lot, le prix, le niveau, le code-barres, l'état et la conservation d'une bouteille.
+// La position se change par un déplacement (MoveUnit). Remettre une bouteille en stock
+// vérifie la capacité de son emplacement, sauf overflow, et la considère pleine et fermée.
+func (s *Store) UpdateUnit(ctx context.Context, unit *domain.BottleUnit, overflow bool) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := getUnit(ctx, tx, `u.id = ?`, unit.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE bottle_units SET lot = ?, purchase_price = ?, fill_level = ?, barcode = ?, state = ?,
+		state_changed_at = CASE WHEN state <> ? THEN ? ELSE state_changed_at END,
+		remaining_ml = CASE WHEN ? = 'in_stock' THEN NULL ELSE remaining_ml END,
+		opened_at = CASE WHEN ? = 'in_stock' THEN NULL WHEN ? = 'opened' THEN COALESCE(opened_at, ?) ELSE opened_at END,
+		preservation = CASE WHEN ? = 'in_stock' THEN '' ELSE ? END
+	WHERE id = ?`,
+		unit.Lot, unit.PurchasePrice, unit.FillLevel, nullIfEmpty(unit.Barcode), unit.State,
+		unit.State, time.Now(), unit.State, unit.State, unit.State, time.Now(),
+		unit.State, unit.Preservation, unit.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: barcode %s is already used by another bottle", ErrConflict, unit.Barcode)
+		}
+		return fmt.Errorf("failed to update bottle unit: %w", err)
+	}
+
+	if current.State != unit.State {
+		if err := refreshWineQuantity(ctx, tx, current.WineID); err != nil {
+			return err
+		}
+		if !overflow {
+			after := unitStock(current)
+			after.quantity = 0
+			if unit.State == domain.UnitStateInStock {
+				after.quantity = 1
+			}
+			if err := checkCapacity(ctx, tx, unitStock(current), after); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// MoveUnit déplace une bouteille en stock de l'entrée wineID vers une cave et/ou un emplacement.
+// La bouteille reste dans son entrée ; le déplacement est tracé avec son ID.
+// version, si non nulle, est la version attendue de l'entrée (If-Match).
+func (s *Store) MoveUnit(ctx context.Context, wineID, unitID int64, caveID, cellID *int64, note string, version int64, overflow bool) (*domain.MoveResult, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if version != 0 {
+		var current int64
+		err := tx.QueryRowContext(ctx, `SELECT version FROM wines WHERE id = ? AND deleted_at IS NULL`, wineID).Scan(&current)
+		if err == sql.ErrNoRows {
+			return nil, notFound("wine", wineID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query wine: %w", err)
+		}
+		if current != version {
+			return nil, StaleVersion("wine", wineID, current)
+		}
+	}
+
+	unit, err := getUnit(ctx, tx, `u.id = ?`, unitID)
+	if err != nil {
+		return nil, err
+	}
+	if unit.WineID != wineID {
+		return nil, NewValidationError("unit_id", fmt.Sprintf("bottle unit %d does not belong to wine %d", unitID, wineID))
+	}
+	if unit.State != domain.UnitStateInStock {
+		return nil, fmt.Errorf("%w: bottle unit %d is %s", ErrConflict, unitID, unit.State)
+	}
+
+	from := unitStock(unit)
+	to, err := moveDestination(ctx, tx, caveID, cellID)
+	if err != nil {
+		return nil, err
+	}
+	to.quantity = 1
+	if from.caveID == to.caveID && from.cellID == to.cellID {
+		return nil, NewValidationError("cell_id", "bottle is already stored there")
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE bottle_units SET cell_id = ?, cave_id = ? WHERE id = ?`, to.cellID, to.caveID, unitID); err != nil {
+		return nil, fmt.Errorf("failed to move bottle unit: %w", err)
+	}
+	if !overflow {
+		if err := checkCapacity(ctx, tx, from, to); err != nil {
+			return nil, err
+		}
+	}
+	movementID, err := recordMovement(ctx, tx, wineID, wineID, &unitID, 1, from, to, note)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result := &domain.MoveResult{}
+	if result.Source, err = s.GetWineByID(ctx, wineID); err != nil {
+		return nil, err
+	}
+	result.Target = result.Source
+	if result.Unit, err = s.GetUnit(ctx, unitID); err != nil {
+		return nil, err
+	}
+	if result.Movement, err = s.getMovement(ctx, movementID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/romain/glou-server/internal/domain"
+)
+
+func TestAddUnitRespectsQuantityCap(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	wineID, err := s.CreateWine(ctx, &domain.Wine{Name: "Margaux", BottleType: "red_wine", Region: "Bordeaux", Vintage: 2015, Quantity: domain.MaxBottleQuantity - 1}, false)
+	if err != nil {
+		t.Fatalf("CreateWine: %v", err)
+	}
+
+	if _, err := s.AddUnit(ctx, wineID, &domain.BottleUnit{}, false); err != nil {
+		t.Fatalf("expected the last bottle under the cap to be added, got %v", err)
+	}
+	if _, err := s.AddUnit(ctx, wineID, &domain.BottleUnit{}, false); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected a validation error past %d bottles, got %v", domain.MaxBottleQuantity, err)
+	}
+
+	wine, err := s.GetWineByID(ctx, wineID)
+	if err != nil {
+		t.Fatalf("GetWineByID: %v", err)
+	}
+	if wine.Quantity != domain.MaxBottleQuantity {
+		t.Errorf("expected %d bottles, got %d", domain.MaxBottleQuantity, wine.Quantity)
+	}
+}
@@ -26,6 +26,9 @@ func (s *Store) CreateUser(ctx context.Context, username, email, password, role
 	now := time.Now()
 	result, err := s.Db.ExecContext(ctx, query, username, email, string(hashedPassword), role, true, now, now)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%w: username or email already exists", ErrConflict)
+		}
 		return 0, fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -341,7 +344,7 @@ func (s *Store) UpdateUser(ctx context.Context, user *domain.User) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return notFound("user", user.ID)
 	}
 
 	user.UpdatedAt = now
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// versionClause restreint une écriture à la version attendue.
+// Elle prend deux fois la version en argument ; 0 désactive la condition.
+const versionClause = ` AND (? = 0 OR version = ?)`
+
+// StaleVersion construit l'erreur retournée quand la version attendue est périmée
+func StaleVersion(entity string, id int64, current int64) error {
+	return fmt.Errorf("%w: %s %d has been modified (current version %d)", ErrPreconditionFailed, entity, id, current)
+}
+
+// queryRower est implémenté par *sql.DB et *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// staleOrMissing explique une écriture conditionnelle sans effet :
+// l'élément a été modifié entre-temps, ou il n'existe pas (ou plus).
+func staleOrMissing(ctx context.Context, db queryRower, table, entity string, id int64) error {
+	var current int64
+	err := db.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT version FROM %s WHERE id = ? AND deleted_at IS NULL`, table), id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return notFound(entity, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s version: %w", entity, err)
+	}
+	return StaleVersion(entity, id, current)
+}
@@ -43,10 +43,12 @@ export const AlertsScreen = () => {
   const getAlertColor = (type) => {
     switch (type) {
       case 'low_stock':
+      case 'best_before_approaching':
         return 'warning';
       case 'apogee_reached':
         return 'success';
       case 'apogee_ended':
+      case 'opened_too_long':
         return 'error';
       default:
         return 'info';
@@ -61,6 +63,10 @@ export const AlertsScreen = () => {
         return 'À boire maintenant';
       case 'apogee_ended':
         return 'Apogée dépassée';
+      case 'opened_too_long':
+        return 'Ouverte depuis trop longtemps';
+      case 'best_before_approaching':
+        return 'DDM proche';
       default:
         return 'Alerte';
     }
@@ -118,7 +118,7 @@ const CaveManagementScreen = () => {
 
     try {
       if (selectedCave) {
-        const updated = await api.updateCave(selectedCave.id, payload);
+        const updated = await api.updateCave(selectedCave.id, payload, { version: selectedCave.version });
         setCaves((prev) => prev.map((c) => (c.id === selectedCave.id ? updated : c)));
       } else {
         const created = await api.createCave(payload);
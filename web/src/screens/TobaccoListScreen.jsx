@@ -46,7 +46,7 @@ export const TobaccoListScreen = () => {
       return;
     }
     try {
-      await api.deleteTobacco(id);
+      await api.deleteTobacco(id, { all: true });
       fetchTobaccos();
     } catch (err) {
       setError(err.message);
@@ -217,9 +217,9 @@ export const WineDetailScreen = ({ wineId, wine: initialWine, onUpdate, onDelete
                       / {wine.consumed || 0} consommées
                     </Typography>
                     <Button size="small" variant="outlined" onClick={async () => {
-                      await api.deleteWine(wine.id);
+                      await api.recordConsumption({ wine_id: wine.id, quantity: 1 });
                       const q = Math.max((wine.quantity || 1) - 1, 0);
-                      const updated = { ...wine, quantity: q };
+                      const updated = { ...wine, quantity: q, consumed: (wine.consumed || 0) + 1 };
                       setWine(updated);
                       if (onUpdate) onUpdate(updated);
                     }}>-</Button>
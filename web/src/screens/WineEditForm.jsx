@@ -43,12 +43,15 @@ export const WineEditForm = ({ wine, onClose, onSave }) => {
     setError(null);
 
     // Validation
-    if (!formData.name || !formData.region || !formData.vintage || !formData.type) {
+    // Une bière n'a ni millésime ni région obligatoires ; un spiritueux sans âge (NAS) n'a pas de millésime
+    const isBeer = formData.type === 'Beer' || formData.bottle_type === 'beer';
+    const isSpirit = formData.type === 'Spirit' || formData.bottle_type === 'spirit';
+    if (!formData.name || (!isBeer && !formData.region) || (!isBeer && !isSpirit && !formData.vintage) || !formData.type) {
       setError('Veuillez remplir tous les champs obligatoires');
       return;
     }
 
-    if (formData.vintage < 1900 || formData.vintage > new Date().getFullYear()) {
+    if ((!(isBeer || isSpirit) || formData.vintage) && (formData.vintage < 1900 || formData.vintage > new Date().getFullYear())) {
       setError('Millésime invalide');
       return;
     }
@@ -63,8 +66,9 @@ export const WineEditForm = ({ wine, onClose, onSave }) => {
       return;
     }
 
-    if (formData.alcohol_level && (formData.alcohol_level < 0 || formData.alcohol_level > 20)) {
-      setError('Le degré alcoolique doit être entre 0 et 20');
+    const maxAlcohol = isSpirit ? 100 : 20;
+    if (formData.alcohol_level && (formData.alcohol_level < 0 || formData.alcohol_level > maxAlcohol)) {
+      setError(`Le degré alcoolique doit être entre 0 et ${maxAlcohol}`);
       return;
     }
 
@@ -14,6 +14,21 @@ function getCookie(name) {
     .find(([key]) => key === name)?.[1];
 }
 
+// Build an If-Match header from a row version (optimistic concurrency)
+function ifMatch(version) {
+  return version ? { 'If-Match': `"${version}"` } : {};
+}
+
+// Query string allowing a placement beyond the cell/cave capacity (409 otherwise)
+function overflowQuery(overflow) {
+  return overflow ? '?overflow=true' : '';
+}
+
+// Headers for an RFC 7396 JSON Merge Patch (null clears a field)
+function mergePatchHeaders(version) {
+  return { 'Content-Type': 'application/merge-patch+json', ...ifMatch(version) };
+}
+
 class ApiClient {
   constructor(baseURL = API_BASE_URL) {
     this.baseURL = baseURL;
@@ -111,6 +126,7 @@ class ApiClient {
     if (filters.producer) params.append('producer', filters.producer);
     if (filters.region) params.append('region', filters.region);
     if (filters.type) params.append('type', filters.type);
+    if (filters.grape) params.append('grape', filters.grape);
     if (filters.min_vintage) params.append('min_vintage', filters.min_vintage);
     if (filters.max_vintage) params.append('max_vintage', filters.max_vintage);
     if (filters.min_price) params.append('min_price', filters.min_price);
@@ -121,21 +137,93 @@ class ApiClient {
   }
 
   /**
-   * Create new wine
+   * Create new wine. A full cell or cave is rejected with a 409 unless { overflow: true }.
    */
-  async createWine(wine) {
-    return this.request('POST', '/wines', wine);
+  async createWine(wine, { overflow = false } = {}) {
+    return this.request('POST', `/wines${overflowQuery(overflow)}`, wine);
   }
 
   /**
-   * Update wine
+   * Update wine. Pass { version } to send If-Match and get a 412 if someone else edited it.
    */
-  async updateWine(id, wine) {
-    return this.request('PUT', `/wines/${id}`, wine);
+  async updateWine(id, wine, { version, overflow = false } = {}) {
+    return this.request('PUT', `/wines/${id}${overflowQuery(overflow)}`, wine, { headers: ifMatch(version) });
   }
 
   /**
-   * Delete wine
+   * Patch wine: only the given fields are changed, null clears a field
+   */
+  async patchWine(id, changes, { version } = {}) {
+    return this.request('PATCH', `/wines/${id}`, changes, { headers: mergePatchHeaders(version) });
+  }
+
+  /**
+   * Move bottles of a wine to another cave and/or cell: { quantity, cave_id, cell_id, note }
+   * (a partial quantity splits the stock line; { unit_id } moves a single bottle instead)
+   */
+  async moveWine(id, move, { version, overflow = false } = {}) {
+    return this.request('POST', `/wines/${id}/move${overflowQuery(overflow)}`, move, { headers: ifMatch(version) });
+  }
+
+  /**
+   * Get wine movement history (most recent first)
+   */
+  async getWineMovements(id) {
+    return this.request('GET', `/wines/${id}/movements`);
+  }
+
+  /**
+   * Get the individual bottles of a wine (optional state: in_stock, opened, consumed, gifted)
+   */
+  async getWineUnits(id, state = '') {
+    const query = state ? `?state=${encodeURIComponent(state)}` : '';
+    return this.request('GET', `/wines/${id}/units${query}`);
+  }
+
+  /**
+   * Add a bottle to a wine: { lot, purchase_price, fill_level, barcode, cave_id, cell_id }
+   */
+  async addWineUnit(id, unit, { overflow = false } = {}) {
+    return this.request('POST', `/wines/${id}/units${overflowQuery(overflow)}`, unit);
+  }
+
+  /**
+   * Get a bottle by ID
+   */
+  async getUnit(id) {
+    return this.request('GET', `/units/${id}`);
+  }
+
+  /**
+   * Find a bottle by its barcode or QR code
+   */
+  async findUnitByBarcode(barcode) {
+    return this.request('GET', `/units?barcode=${encodeURIComponent(barcode)}`);
+  }
+
+  /**
+   * Update a bottle (lot, price, fill level, barcode, state, preservation); consume it with recordConsumption
+   */
+  async updateUnit(id, unit, { overflow = false } = {}) {
+    return this.request('PUT', `/units/${id}${overflowQuery(overflow)}`, unit);
+  }
+
+  /**
+   * Open a bottle ({ preservation, opened_at }, both optional)
+   */
+  async openUnit(id, opening = {}) {
+    return this.request('POST', `/units/${id}/open`, opening);
+  }
+
+  /**
+   * Opened bottles with their remaining fill level and drink-by date, most urgent first
+   */
+  async getOpenedBottles() {
+    return this.request('GET', '/units/opened');
+  }
+
+  /**
+   * Delete wine (moves it to the trash, from where it can be restored)
    */
   async deleteWine(id) {
     return this.request('DELETE', `/wines/${id}`);
@@ -167,8 +255,15 @@ class ApiClient {
   /**
    * Update an existing cave
    */
-  async updateCave(id, cave) {
-    return this.request('PUT', `/caves/${id}`, cave);
+  async updateCave(id, cave, { version } = {}) {
+    return this.request('PUT', `/caves/${id}`, cave, { headers: ifMatch(version) });
+  }
+
+  /**
+   * Patch a cave (JSON Merge Patch)
+   */
+  async patchCave(id, changes, { version } = {}) {
+    return this.request('PATCH', `/caves/${id}`, changes, { headers: mergePatchHeaders(version) });
   }
 
   /**
@@ -178,6 +273,55 @@ class ApiClient {
     return this.request('GET', `/caves/${caveId}/cells`);
   }
 
+  /**
+   * Get cave grid (zones > racks > rows > slots) with per-slot occupancy
+   */
+  async getCaveLayout(caveId) {
+    return this.request('GET', `/caves/${caveId}/layout`);
+  }
+
+  /**
+   * Get a ranked placement plan for incoming bottles: { wine_id } or { wine }, plus optional quantity
+   */
+  async planPlacement(caveId, request) {
+    return this.request('POST', `/caves/${caveId}/placement-plan`, request);
+  }
+
+  /**
+   * Apply a placement plan in one call: { wine_id | wine, placements: [{ cell_id, quantity }] }
+   */
+  async applyPlacement(caveId, request, { overflow } = {}) {
+    return this.request('POST', `/caves/${caveId}/placement-plan/apply${overflowQuery(overflow)}`, request);
+  }
+
+  /**
+   * Get cave model catalog (built-in and custom); pass a model id as `model` to createCave
+   */
+  async getCaveModels() {
+    return this.request('GET', '/cave-models');
+  }
+
+  /**
+   * Create custom cave model (admin only)
+   */
+  async createCaveModel(model) {
+    return this.request('POST', '/api/admin/cave-models', model);
+  }
+
+  /**
+   * Update custom cave model (admin only)
+   */
+  async updateCaveModel(id, model) {
+    return this.request('PUT', `/api/admin/cave-models/${id}`, model);
+  }
+
+  /**
+   * Delete custom cave model (admin only)
+   */
+  async deleteCaveModel(id) {
+    return this.request('DELETE', `/api/admin/cave-models/${id}`);
+  }
+
   /**
    * Create cell
    */
@@ -271,7 +415,8 @@ class ApiClient {
   }
 
   /**
-   * Record consumption
+   * Record consumption ({ wine_id, quantity } or { unit_id } for a single bottle).
+   * Pour from an opened bottle with { wine_id, glasses, glass_ml? } or { wine_id, volume_ml }
    */
   async recordConsumption(consumption) {
     return this.request('POST', '/consumption', consumption);
@@ -316,6 +461,13 @@ class ApiClient {
     return this.request('PUT', '/api/admin/settings', settings);
   }
 
+  /**
+   * Patch settings (JSON Merge Patch): only the given fields are changed
+   */
+  async patchSettings(changes) {
+    return this.request('PATCH', '/api/admin/settings', changes, { headers: mergePatchHeaders() });
+  }
+
   // ============ ACTIVITY LOG ============
 
   /**
@@ -375,7 +527,7 @@ class ApiClient {
   // ============ GEOCODING ============
 
   /**
-   * Search for location suggestions
+   * Search for location suggestions (built-in wine gazetteer first, then Nominatim)
    */
   async searchLocations(query) {
     return this.request('GET', `/api/geocoding/search?q=${encodeURIComponent(query)}`);
@@ -391,16 +543,227 @@ class ApiClient {
     return this.request('GET', `/tobacco/${id}`);
   }
 
-  async createTobacco(item) {
-    return this.request('POST', '/tobacco', item);
+  async createTobacco(item, { overflow = false } = {}) {
+    return this.request('POST', `/tobacco${overflowQuery(overflow)}`, item);
+  }
+
+  async updateTobacco(id, item, { version, overflow = false } = {}) {
+    return this.request('PUT', `/tobacco/${id}${overflowQuery(overflow)}`, item, { headers: ifMatch(version) });
+  }
+
+  async patchTobacco(id, changes, { version } = {}) {
+    return this.request('PATCH', `/tobacco/${id}`, changes, { headers: mergePatchHeaders(version) });
+  }
+
+  async deleteTobacco(id, { all = false } = {}) {
+    return this.request('DELETE', `/tobacco/${id}${all ? '?all=true' : ''}`);
+  }
+
+  // ============ PURCHASES ============
+
+  /**
+   * Get the purchase lots of a wine or tobacco with average and remaining-stock cost
+   * (itemType: 'wines' or 'tobacco')
+   */
+  async getPurchases(itemType, id) {
+    return this.request('GET', `/${itemType}/${id}/purchases`);
+  }
+
+  /**
+   * Record a purchase lot: { purchase_date, merchant_id, quantity, unit_price, currency, invoice_ref, notes }
+   */
+  async addPurchase(itemType, id, lot) {
+    return this.request('POST', `/${itemType}/${id}/purchases`, lot);
   }
 
-  async updateTobacco(id, item) {
-    return this.request('PUT', `/tobacco/${id}`, item);
+  async updatePurchase(id, lot) {
+    return this.request('PUT', `/purchases/${id}`, lot);
+  }
+
+  async deletePurchase(id) {
+    return this.request('DELETE', `/purchases/${id}`);
+  }
+
+  async getMerchants() {
+    return this.request('GET', '/merchants');
+  }
+
+  async getMerchant(id) {
+    return this.request('GET', `/merchants/${id}`);
+  }
+
+  async createMerchant(merchant) {
+    return this.request('POST', '/merchants', merchant);
+  }
+
+  async updateMerchant(id, merchant) {
+    return this.request('PUT', `/merchants/${id}`, merchant);
+  }
+
+  async deleteMerchant(id) {
+    return this.request('DELETE', `/merchants/${id}`);
+  }
+
+  /**
+   * Get the purchase history of a merchant (most recent first)
+   */
+  async getMerchantPurchases(id) {
+    return this.request('GET', `/merchants/${id}/purchases`);
+  }
+
+
+  // ============ PRODUCERS ============
+
+  async getProducers() {
+    return this.request('GET', '/producers');
+  }
+
+  /**
+   * Suggest existing producers similar to a name (normalized, fuzzy; best first)
+   */
+  async matchProducers(name, limit = 5) {
+    return this.request('GET', `/producers/match?name=${encodeURIComponent(name)}&limit=${limit}`);
+  }
+
+  async getProducer(id) {
+    return this.request('GET', `/producers/${id}`);
+  }
+
+  async createProducer(producer) {
+    return this.request('POST', '/producers', producer);
+  }
+
+  async updateProducer(id, producer) {
+    return this.request('PUT', `/producers/${id}`, producer);
+  }
+
+  async deleteProducer(id) {
+    return this.request('DELETE', `/producers/${id}`);
+  }
+
+  async getProducerWines(id) {
+    return this.request('GET', `/producers/${id}/wines`);
+  }
+
+  async getProducerConsumption(id) {
+    return this.request('GET', `/producers/${id}/consumption`);
+  }
+
+  /**
+   * Merge duplicate producers into producer id (admin); their wines are relinked
+   */
+  async mergeProducers(id, sourceIds) {
+    return this.request('POST', `/api/admin/producers/${id}/merge`, { source_ids: sourceIds });
+  }
+
+  // ============ GRAPES ============
+
+  async getGrapes() {
+    return this.request('GET', '/grapes');
+  }
+
+  /**
+   * Autocomplete grape varieties by name or synonym (Shiraz finds Syrah)
+   */
+  async matchGrapes(name, limit = 10) {
+    return this.request('GET', `/grapes/match?name=${encodeURIComponent(name)}&limit=${limit}`);
+  }
+
+  async getGrape(id) {
+    return this.request('GET', `/grapes/${id}`);
+  }
+
+  async createGrape(grape) {
+    return this.request('POST', '/grapes', grape);
+  }
+
+  async updateGrape(id, grape) {
+    return this.request('PUT', `/grapes/${id}`, grape);
+  }
+
+  async deleteGrape(id) {
+    return this.request('DELETE', `/grapes/${id}`);
+  }
+
+  async getGrapeWines(id) {
+    return this.request('GET', `/grapes/${id}/wines`);
+  }
+
+  /**
+   * Wines, bottles in stock and average rating per grape
+   */
+  async getGrapeStats() {
+    return this.request('GET', '/grapes/stats');
+  }
+
+  /**
+   * Blends (two grapes or more) ranked by average rating, optionally containing a grape
+   */
+  async getBlendStats(grape) {
+    return this.request('GET', grape ? `/grapes/stats/blends?grape=${encodeURIComponent(grape)}` : '/grapes/stats/blends');
+  }
+
+  // ============ GEOGRAPHY ============
+
+  /**
+   * Countries of the built-in gazetteer with their wine regions
+   */
+  async getCountries() {
+    return this.request('GET', '/geo/countries');
+  }
+
+  /**
+   * Autocomplete appellations (accent-insensitive), optionally within a country code or region id
+   */
+  async searchAppellations(query, { country, region, limit = 10 } = {}) {
+    const params = new URLSearchParams({ q: query, limit });
+    if (country) params.set('country', country);
+    if (region) params.set('region', region);
+    return this.request('GET', `/geo/appellations?${params}`);
+  }
+
+  async getAppellation(id) {
+    return this.request('GET', `/geo/appellations/${encodeURIComponent(id)}`);
+  }
+
+  /**
+   * Bottles in stock per country, for the map
+   */
+  async getCountryStats() {
+    return this.request('GET', '/geo/stats/countries');
+  }
+
+  /**
+   * Bottles in stock per wine region, optionally for one country code
+   */
+  async getRegionStats(country) {
+    return this.request('GET', country ? `/geo/stats/regions?country=${encodeURIComponent(country)}` : '/geo/stats/regions');
+  }
+
+  // ============ FORMATS & INVENTORY ============
+
+  /**
+   * Known bottle formats (id, name, volume_ml, kind)
+   */
+  async getBottleFormats() {
+    return this.request('GET', '/formats');
+  }
+
+  /**
+   * Stock in bottles, liters and value, per bottle type and format
+   */
+  async getInventory() {
+    return this.request('GET', '/inventory');
+  }
+
+  // ============ TRASH ============
+
+  async getTrash() {
+    return this.request('GET', '/trash');
   }
 
-  async deleteTobacco(id) {
-    return this.request('DELETE', `/tobacco/${id}`);
+  async restoreFromTrash(type, id) {
+    return this.request('POST', `/trash/${type}/${id}/restore`);
   }
 }
 